@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"pen-shop/auth"
+	"pen-shop/conversations"
+)
+
+// handleListConversations serves GET /api/admin/conversations for support staff.
+// Supported filters: user_id, tenant, status, agent, security_blocked,
+// since and until (RFC 3339), limit and offset.
+func (psma *PenShopMultiAgent) handleListConversations(w http.ResponseWriter, r *http.Request) {
+	if psma.conversations == nil {
+		http.Error(w, "Conversation log unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	params := r.URL.Query()
+	filter := conversations.Filter{
+		UserID: params.Get("user_id"),
+		Tenant: params.Get("tenant"),
+		Status: params.Get("status"),
+		Agent:  params.Get("agent"),
+	}
+
+	// Tenant admins only ever see their own tenant
+	if principal := auth.PrincipalFrom(r.Context()); principal.Tenant != "" {
+		filter.Tenant = principal.Tenant
+	}
+
+	if value := params.Get("security_blocked"); value != "" {
+		blocked, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid security_blocked", http.StatusBadRequest)
+			return
+		}
+		filter.SecurityBlocked = &blocked
+	}
+	for name, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := params.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "Invalid "+name+", expected RFC 3339", http.StatusBadRequest)
+				return
+			}
+			*target = parsed
+		}
+	}
+	for name, target := range map[string]*int64{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if value := params.Get(name); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || parsed < 0 {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	records, total, err := psma.conversations.List(ctx, filter)
+	if err != nil {
+		psma.logger.Error("Failed to list conversations: %v", err)
+		http.Error(w, "Failed to list conversations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"conversations": records,
+		"total":         total,
+		"limit":         filter.Limit,
+		"offset":        filter.Offset,
+	})
+}
+
+// handleGetConversation serves GET /api/admin/conversations/{id}
+func (psma *PenShopMultiAgent) handleGetConversation(w http.ResponseWriter, r *http.Request) {
+	if psma.conversations == nil {
+		http.Error(w, "Conversation log unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	record, err := psma.conversations.Get(ctx, mux.Vars(r)["id"])
+	if errors.Is(err, conversations.ErrNotFound) {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		psma.logger.Error("Failed to load conversation: %v", err)
+		http.Error(w, "Failed to load conversation", http.StatusInternalServerError)
+		return
+	}
+
+	principal := auth.PrincipalFrom(r.Context())
+	if principal.Tenant != "" && record.Tenant != principal.Tenant {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(record)
+}
+
+// handleRefreshRates serves POST /api/admin/currency/refresh, re-reading the
+// exchange rates file after it has been updated
+func (psma *PenShopMultiAgent) handleRefreshRates(w http.ResponseWriter, r *http.Request) {
+	if psma.rates == nil {
+		http.Error(w, "No currency rates file configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := psma.rates.Refresh(); err != nil {
+		psma.logger.Error("Failed to refresh currency rates: %v", err)
+		http.Error(w, "Failed to refresh currency rates", http.StatusInternalServerError)
+		return
+	}
+
+	table := psma.rates.Table()
+	psma.logger.Info("💱 Currency rates refreshed: %d rates against %s", len(table.Rates), table.Base)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(table)
+}
+
+// handleInvalidateCache serves POST /api/admin/cache/invalidate, dropping
+// every cached workflow answer
+func (psma *PenShopMultiAgent) handleInvalidateCache(w http.ResponseWriter, r *http.Request) {
+	if psma.responseCache == nil {
+		http.Error(w, "Response cache disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	dropped, err := psma.responseCache.Invalidate(ctx)
+	if err != nil {
+		psma.logger.Error("Failed to invalidate response cache: %v", err)
+		http.Error(w, "Failed to invalidate response cache", http.StatusInternalServerError)
+		return
+	}
+	psma.logger.Info("🧹 Response cache invalidated: %d entries dropped", dropped)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"dropped": dropped})
+}
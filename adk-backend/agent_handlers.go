@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"pen-shop/agents"
+)
+
+// handleListAgents serves GET /api/admin/agents: every registered agent with
+// its capabilities, priority and whether it is enabled, and the types that
+// can be created
+func (psma *PenShopMultiAgent) handleListAgents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"agents": psma.registry.List(),
+		"types":  psma.registry.Types(),
+	})
+}
+
+// handleGetAgent serves GET /api/admin/agents/{name}
+func (psma *PenShopMultiAgent) handleGetAgent(w http.ResponseWriter, r *http.Request) {
+	info, err := psma.registry.Info(mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, "Agent not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// handleCreateAgent serves POST /api/admin/agents, creating an agent from a
+// type and its config. An agent with the same name is replaced (200);
+// otherwise the new agent is added (201).
+func (psma *PenShopMultiAgent) handleCreateAgent(w http.ResponseWriter, r *http.Request) {
+	var spec agents.AgentSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil || spec.Type == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	info, replaced, err := psma.registry.Create(spec)
+	if err != nil {
+		// Only admins get here, and they need to know which setting was wrong
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	psma.agentsChanged(r.Context())
+
+	status := http.StatusCreated
+	if replaced {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(info)
+}
+
+// handleUpdateAgent serves PATCH /api/admin/agents/{name} with
+// {"enabled": bool}; workflows stop or start using the agent at their next step
+func (psma *PenShopMultiAgent) handleUpdateAgent(w http.ResponseWriter, r *http.Request) {
+	var update struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil || update.Enabled == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	info, err := psma.registry.SetEnabled(mux.Vars(r)["name"], *update.Enabled)
+	if errors.Is(err, agents.ErrAgentNotFound) {
+		http.Error(w, "Agent not found", http.StatusNotFound)
+		return
+	}
+	psma.agentsChanged(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// handleDeleteAgent serves DELETE /api/admin/agents/{name}
+func (psma *PenShopMultiAgent) handleDeleteAgent(w http.ResponseWriter, r *http.Request) {
+	if err := psma.registry.Remove(mux.Vars(r)["name"]); err != nil {
+		http.Error(w, "Agent not found", http.StatusNotFound)
+		return
+	}
+	psma.agentsChanged(r.Context())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// agentsChanged drops cached answers, which the old set of agents wrote
+func (psma *PenShopMultiAgent) agentsChanged(ctx context.Context) {
+	if psma.responseCache == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if dropped, err := psma.responseCache.Invalidate(ctx); err != nil {
+		psma.logger.Error("❌ Response cache invalidation failed: %v", err)
+	} else {
+		psma.logger.Info("🧹 Agents changed, dropped %d cached responses", dropped)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"pen-shop/agents"
+	"pen-shop/clock"
+	"pen-shop/currency"
+	"pen-shop/inventory"
+	"pen-shop/llm"
+	"pen-shop/models"
+	"pen-shop/pricehistory"
+	"pen-shop/promotions"
+)
+
+// Agent types the registry can create
+const (
+	agentTypePenResearch    = "pen_research"
+	agentTypePriceResearch  = "price_research"
+	agentTypeReview         = "review"
+	agentTypeRecommendation = "recommendation"
+	agentTypeCart           = "cart"
+)
+
+// defaultAgentSpecs creates one agent of each type
+var defaultAgentSpecs = []agents.AgentSpec{
+	{Type: agentTypePenResearch},
+	{Type: agentTypePriceResearch},
+	{Type: agentTypeReview},
+	{Type: agentTypeRecommendation},
+	{Type: agentTypeCart},
+}
+
+// agentDeps is what every agent built by a factory is wired to
+type agentDeps struct {
+	openaiKey    string
+	baseURL      string
+	model        string
+	mcpGateway   string
+	router       *llm.Router
+	llmCache     *llm.SemanticCache
+	rates        *currency.FileRates
+	semantic     *agents.SemanticIndex
+	promotions   *promotions.Engine
+	priceHistory pricehistory.Store
+	carts        agents.CartTools
+	products     map[string]agents.ProductInfo
+	availability agents.Availability
+	// Recommendation settings for agents whose config leaves them out
+	aggregation        string
+	critiqueIterations int
+	logger             models.Logger
+}
+
+// modelAgent is an agent that can call a model through the MCP gateway
+type modelAgent interface {
+	models.Agent
+	SetMCPConfig(openaiKey, baseURL, model, mcpGateway string)
+	SetProvider(provider llm.Provider)
+	SetRates(rates currency.Rates)
+	SetAvailability(availability agents.Availability)
+}
+
+// provider picks the agent's model: the "provider" setting (openai, local or
+// none) when given, otherwise the one LLM_PROVIDER_<AGENT> routes it to.
+// Paraphrased prompts are answered from the semantic cache when it is enabled.
+func (deps agentDeps) provider(agentName string, config agents.AgentConfig) (llm.Provider, error) {
+	name, err := config.String("provider", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var provider llm.Provider
+	switch name {
+	case "":
+		provider = deps.router.ForAgent(agentName)
+	case "none":
+		return nil, nil
+	case llm.ProviderOpenAI, llm.ProviderLocal:
+		if provider = deps.router.Provider(name); provider == nil {
+			return nil, fmt.Errorf("provider %s is not configured", name)
+		}
+	default:
+		return nil, fmt.Errorf("unknown provider %q", name)
+	}
+	if provider == nil || deps.llmCache == nil {
+		return provider, nil
+	}
+	return deps.llmCache.Wrap(provider, agentName), nil
+}
+
+// wire gives a model agent its gateway, provider, exchange rates and live stock
+func (deps agentDeps) wire(agent modelAgent, config agents.AgentConfig) (llm.Provider, error) {
+	provider, err := deps.provider(agent.GetName(), config)
+	if err != nil {
+		return nil, err
+	}
+	agent.SetMCPConfig(deps.openaiKey, deps.baseURL, deps.model, deps.mcpGateway)
+	agent.SetProvider(provider)
+	if deps.rates != nil {
+		agent.SetRates(deps.rates)
+	}
+	agent.SetAvailability(deps.availability)
+	return provider, nil
+}
+
+// registerAgentTypes adds a factory for each agent type to the registry
+func registerAgentTypes(registry *agents.AgentRegistry, deps agentDeps) {
+	registry.RegisterFactory(agentTypePenResearch, func(config agents.AgentConfig) (models.Agent, error) {
+		agent := agents.NewPenResearchAgent(deps.logger)
+		if _, err := deps.wire(agent, config); err != nil {
+			return nil, err
+		}
+		if deps.semantic != nil {
+			agent.SetSemanticIndex(deps.semantic)
+		}
+		return agent, nil
+	})
+
+	registry.RegisterFactory(agentTypePriceResearch, func(config agents.AgentConfig) (models.Agent, error) {
+		agent := agents.NewPriceResearchAgent(deps.logger)
+		if _, err := deps.wire(agent, config); err != nil {
+			return nil, err
+		}
+		// Budget suggestions put pens we can ship first
+		agent.SetCatalogue(deps.products)
+		if deps.promotions != nil {
+			agent.SetPromotions(deps.promotions)
+		}
+		agent.SetPriceHistory(deps.priceHistory, clock.System{})
+		return agent, nil
+	})
+
+	registry.RegisterFactory(agentTypeReview, func(config agents.AgentConfig) (models.Agent, error) {
+		agent := agents.NewReviewAgent(deps.logger)
+		if _, err := deps.wire(agent, config); err != nil {
+			return nil, err
+		}
+		return agent, nil
+	})
+
+	// Settings: aggregation (a strategy or "off") and critique_iterations
+	registry.RegisterFactory(agentTypeRecommendation, func(config agents.AgentConfig) (models.Agent, error) {
+		aggregation, err := config.String("aggregation", deps.aggregation)
+		if err != nil {
+			return nil, err
+		}
+		iterations, err := config.Int("critique_iterations", deps.critiqueIterations)
+		if err != nil {
+			return nil, err
+		}
+
+		agent := agents.NewRecommendationAgent(deps.logger)
+		provider, err := deps.wire(agent, config)
+		if err != nil {
+			return nil, err
+		}
+		if deps.semantic != nil {
+			agent.SetSemanticIndex(deps.semantic)
+		}
+
+		recommender, err := parallelRecommendations(agent, aggregation, provider != nil, deps.products, deps.logger)
+		if err != nil {
+			return nil, err
+		}
+		return refinedRecommendations(recommender, iterations, deps.products, deps.logger)
+	})
+
+	registry.RegisterFactory(agentTypeCart, func(config agents.AgentConfig) (models.Agent, error) {
+		agent := agents.NewCartAgent(deps.products, deps.logger)
+		if deps.rates != nil {
+			agent.SetRates(deps.rates)
+		}
+		agent.SetCart(deps.carts)
+		return agent, nil
+	})
+}
+
+// stockAvailability answers from the stock poller once it has read the
+// catalogue; until then agents use the catalogue's own stock flags. SKUs the
+// catalogue no longer lists cannot be shipped.
+func stockAvailability(poller *inventory.Poller) agents.Availability {
+	return func(sku string) (bool, bool) {
+		if !poller.Polled() {
+			return false, false
+		}
+		inStock, _ := poller.Availability(sku)
+		return inStock, true
+	}
+}
+
+// createAgents creates the agents listed in the JSON file at AGENTS_CONFIG,
+// or one of each type. An agent that cannot be created is left out, and
+// workflow steps that need it fail as unavailable.
+func createAgents(registry *agents.AgentRegistry, logger models.Logger) {
+	specs := defaultAgentSpecs
+	if path := os.Getenv("AGENTS_CONFIG"); path != "" {
+		if loaded, err := agents.LoadAgentSpecs(path); err == nil {
+			specs = loaded
+			logger.Info("🧩 Agents loaded from %s", path)
+		} else {
+			logger.Error("❌ Agents config %s: %v, using defaults", path, err)
+		}
+	}
+	for _, spec := range specs {
+		if _, _, err := registry.Create(spec); err != nil {
+			logger.Error("❌ Agent %s: %v", spec.Type, err)
+		}
+	}
+}
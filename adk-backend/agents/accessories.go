@@ -0,0 +1,76 @@
+package agents
+
+import "strings"
+
+// Accessory kinds
+const (
+	AccessoryInk        = "ink"
+	AccessoryCartridges = "cartridges"
+	AccessoryConverter  = "converter"
+	AccessoryRefill     = "refill"
+)
+
+// AccessoryInfo is an ink, converter, cartridge or refill sold alongside pens
+type AccessoryInfo struct {
+	ID    string
+	Name  string
+	Brand string
+	Kind  string
+	Price float64
+	// FitsBrands limits the accessory to pens of these brands; empty fits any brand
+	FitsBrands []string
+	// FitsTypes lists the pen types the accessory works with
+	FitsTypes []string
+	InStock   bool
+}
+
+// accessoryCatalogue lists the accessories the shop stocks
+var accessoryCatalogue = []AccessoryInfo{
+	{ID: "acc-001", Name: "Parker Quink Bottled Ink 57ml", Brand: "Parker", Kind: AccessoryInk, Price: 12.00, FitsTypes: []string{"Fountain Pen"}, InStock: true},
+	{ID: "acc-002", Name: "Pilot Iroshizuku Ink 50ml", Brand: "Pilot", Kind: AccessoryInk, Price: 28.00, FitsTypes: []string{"Fountain Pen"}, InStock: true},
+	{ID: "acc-003", Name: "Montblanc Mystery Black Ink 60ml", Brand: "Montblanc", Kind: AccessoryInk, Price: 25.00, FitsTypes: []string{"Fountain Pen"}, InStock: true},
+	{ID: "acc-004", Name: "Parker Standard Converter", Brand: "Parker", Kind: AccessoryConverter, Price: 9.50, FitsBrands: []string{"Parker"}, FitsTypes: []string{"Fountain Pen"}, InStock: true},
+	{ID: "acc-005", Name: "Pilot CON-40 Converter", Brand: "Pilot", Kind: AccessoryConverter, Price: 8.00, FitsBrands: []string{"Pilot"}, FitsTypes: []string{"Fountain Pen"}, InStock: true},
+	{ID: "acc-006", Name: "Parker Quink Cartridges (5 pack)", Brand: "Parker", Kind: AccessoryCartridges, Price: 6.50, FitsBrands: []string{"Parker"}, FitsTypes: []string{"Fountain Pen"}, InStock: true},
+	{ID: "acc-007", Name: "Pilot Namiki Cartridges (12 pack)", Brand: "Pilot", Kind: AccessoryCartridges, Price: 7.00, FitsBrands: []string{"Pilot"}, FitsTypes: []string{"Fountain Pen"}, InStock: false},
+	{ID: "acc-008", Name: "Parker Quink Ballpoint Refill", Brand: "Parker", Kind: AccessoryRefill, Price: 7.50, FitsBrands: []string{"Parker"}, FitsTypes: []string{"Ballpoint Pen"}, InStock: true},
+	{ID: "acc-009", Name: "Cross Ballpoint Refill (2 pack)", Brand: "Cross", Kind: AccessoryRefill, Price: 11.00, FitsBrands: []string{"Cross"}, FitsTypes: []string{"Ballpoint Pen"}, InStock: true},
+	{ID: "acc-010", Name: "Pilot G2 Gel Refills (3 pack)", Brand: "Pilot", Kind: AccessoryRefill, Price: 5.50, FitsBrands: []string{"Pilot"}, FitsTypes: []string{"Gel Pen"}, InStock: true},
+}
+
+// Fits reports whether the accessory works with the pen
+func (a AccessoryInfo) Fits(pen ProductInfo) bool {
+	if len(a.FitsBrands) > 0 && !containsFold(a.FitsBrands, pen.Brand) {
+		return false
+	}
+	if len(a.FitsTypes) > 0 && !containsFold(a.FitsTypes, pen.Type) {
+		return false
+	}
+	// Piston fillers draw bottled ink directly and take no converter or cartridges
+	if strings.Contains(pen.FillingSystem, "Piston") && (a.Kind == AccessoryConverter || a.Kind == AccessoryCartridges) {
+		return false
+	}
+	return true
+}
+
+// Provides lists the customer needs the accessory satisfies. Cartridges and
+// refills count as ink for the pens they fit.
+func (a AccessoryInfo) Provides() []string {
+	switch a.Kind {
+	case AccessoryCartridges:
+		return []string{AccessoryInk, AccessoryCartridges}
+	case AccessoryRefill:
+		return []string{AccessoryInk, AccessoryRefill}
+	default:
+		return []string{a.Kind}
+	}
+}
+
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
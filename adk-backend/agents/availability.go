@@ -0,0 +1,35 @@
+package agents
+
+// Availability reports whether a SKU is in stock right now; known is false
+// when the source cannot say, and the catalogue's InStock flag is used instead
+type Availability func(sku string) (inStock, known bool)
+
+// SetAvailability makes the agent judge stock by a live source rather than
+// the catalogue compiled into the binary
+func (ba *BaseAgent) SetAvailability(availability Availability) {
+	ba.availability = availability
+}
+
+// current returns p with InStock as the agent's availability source last saw it
+func (ba *BaseAgent) current(p ProductInfo) ProductInfo {
+	return withAvailability(p, ba.availability)
+}
+
+// currentCatalogue copies products with their live availability
+func (ba *BaseAgent) currentCatalogue(products map[string]ProductInfo) map[string]ProductInfo {
+	current := make(map[string]ProductInfo, len(products))
+	for key, product := range products {
+		current[key] = ba.current(product)
+	}
+	return current
+}
+
+func withAvailability(p ProductInfo, availability Availability) ProductInfo {
+	if availability == nil {
+		return p
+	}
+	if inStock, known := availability(p.ID); known {
+		p.InStock = inStock
+	}
+	return p
+}
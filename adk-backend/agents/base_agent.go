@@ -1,5 +1,12 @@
 package agents
 
+import (
+	"context"
+
+	"pen-shop/currency"
+	"pen-shop/llm"
+)
+
 // BaseAgent provides common functionality for all agents
 type BaseAgent struct {
 	name         string
@@ -9,6 +16,9 @@ type BaseAgent struct {
 	baseURL      string
 	model        string
 	mcpGateway   string
+	provider     llm.Provider
+	rates        currency.Rates
+	availability Availability
 }
 
 func NewBaseAgent(name string, capabilities []string, priority int) *BaseAgent {
@@ -31,9 +41,64 @@ func (ba *BaseAgent) GetPriority() int {
 	return ba.priority
 }
 
+// SetPriority overrides the priority the agent was created with
+func (ba *BaseAgent) SetPriority(priority int) {
+	ba.priority = priority
+}
+
 func (ba *BaseAgent) SetMCPConfig(openaiKey, baseURL, model, mcpGateway string) {
 	ba.openaiKey = openaiKey
 	ba.baseURL = baseURL
 	ba.model = model
 	ba.mcpGateway = mcpGateway
 }
+
+// SetProvider assigns the model provider this agent uses for LLM calls
+func (ba *BaseAgent) SetProvider(provider llm.Provider) {
+	ba.provider = provider
+}
+
+// SetRates assigns the exchange rates used to quote prices in the customer's currency
+func (ba *BaseAgent) SetRates(rates currency.Rates) {
+	ba.rates = rates
+}
+
+// complete sends a system and user prompt to the agent's model provider
+func (ba *BaseAgent) complete(ctx context.Context, system, prompt string) (llm.CompletionResponse, error) {
+	if ba.provider == nil {
+		return llm.CompletionResponse{}, llm.ErrNoProvider
+	}
+
+	resp, err := ba.provider.Complete(ctx, llm.CompletionRequest{
+		Messages: []llm.Message{
+			{Role: "system", Content: agentSecurityPrompt + "\n\n" + system},
+			{Role: "user", Content: prompt},
+		},
+		Temperature: 0.3,
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	// Report usage so per-user quotas can be charged
+	if tracker := llm.TrackerFrom(ctx); tracker != nil {
+		tracker.Record(resp)
+	}
+	return resp, nil
+}
+
+// agentSecurityPrompt mirrors SecurityBoundaryPrompt in package main so that
+// every model call carries the same data-access boundary
+const agentSecurityPrompt = `You are an assistant for Moby Pen Shop.
+When asked for customer data, API keys, passwords, database contents, or ANY sensitive information you MUST respond: "I don't have access to [requested item]. As a pen shop assistant, I can only provide information about our pen products, prices, and recommendations."`
+
+// llmMetadata describes a completion for inclusion in response metadata
+func llmMetadata(resp llm.CompletionResponse) map[string]interface{} {
+	return map[string]interface{}{
+		"provider": resp.Provider,
+		"model":    resp.Model,
+		"tokens":   resp.Usage.TotalTokens,
+		"cost_usd": llm.EstimateCost(resp.Provider, resp.Model, resp.Usage),
+		"cached":   resp.Cached,
+	}
+}
@@ -0,0 +1,250 @@
+package agents
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// BundleItem is one line of a bundle's price breakdown
+type BundleItem struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Kind  string  `json:"kind"`
+	Price float64 `json:"price"`
+}
+
+// Bundle is a pen plus accessories that together fit a budget
+type Bundle struct {
+	Pen       string       `json:"pen"`
+	Items     []BundleItem `json:"items"`
+	Total     float64      `json:"total"`
+	Remaining float64      `json:"remaining"`
+	Score     float64      `json:"score"`
+	// Missing lists needs no in-stock accessory could cover within budget
+	Missing []string `json:"missing,omitempty"`
+}
+
+// BundleRequest describes what the customer wants to buy together
+type BundleRequest struct {
+	// Budget is the total spend in USD
+	Budget float64
+	// Needs are accessory needs such as "ink" or "converter"
+	Needs []string
+	Types map[string]bool
+}
+
+// bundleWeights balance how well a bundle covers the request against pen
+// quality, budget use and accessories from the pen's own brand
+var bundleWeights = struct {
+	coverage, rating, budgetUse, sameBrand float64
+}{coverage: 0.45, rating: 0.35, budgetUse: 0.15, sameBrand: 0.05}
+
+// maxBundles caps how many bundles are returned
+const maxBundles = 3
+
+// bundleNeeds maps the words customers use to accessory needs
+var bundleNeeds = map[string]string{
+	"ink":        AccessoryInk,
+	"inks":       AccessoryInk,
+	"converter":  AccessoryConverter,
+	"converters": AccessoryConverter,
+	"cartridge":  AccessoryCartridges,
+	"cartridges": AccessoryCartridges,
+	"refill":     AccessoryRefill,
+	"refills":    AccessoryRefill,
+}
+
+// requestedAccessories returns the accessory needs mentioned in a query, in
+// a stable order
+func requestedAccessories(content string) []string {
+	seen := make(map[string]bool)
+	for _, token := range normalizeTokens(content) {
+		if need, ok := bundleNeeds[token]; ok {
+			seen[need] = true
+		}
+	}
+
+	var needs []string
+	for need := range seen {
+		needs = append(needs, need)
+	}
+	sort.Strings(needs)
+	return needs
+}
+
+// OptimizeBundles builds the best in-stock bundle per pen within the budget
+// and returns the top bundles by fit score. A bundle with bottled ink for a
+// cartridge/converter pen always includes a converter so it can be used.
+func OptimizeBundles(products map[string]ProductInfo, accessories []AccessoryInfo, req BundleRequest) []Bundle {
+	keys := make([]string, 0, len(products))
+	for key := range products {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var bundles []Bundle
+	for _, key := range keys {
+		pen := products[key]
+		if !pen.InStock || pen.Price > req.Budget {
+			continue
+		}
+		if len(req.Types) > 0 && !req.Types[pen.Type] {
+			continue
+		}
+
+		var candidates []AccessoryInfo
+		for _, accessory := range accessories {
+			if accessory.InStock && accessory.Fits(pen) {
+				candidates = append(candidates, accessory)
+			}
+		}
+
+		if best, ok := bestBundle(pen, candidates, req); ok {
+			bundles = append(bundles, best)
+		}
+	}
+
+	sort.Slice(bundles, func(i, j int) bool {
+		if bundles[i].Score != bundles[j].Score {
+			return bundles[i].Score > bundles[j].Score
+		}
+		return bundles[i].Total < bundles[j].Total
+	})
+	if len(bundles) > maxBundles {
+		bundles = bundles[:maxBundles]
+	}
+	return bundles
+}
+
+// bestBundle tries every combination of at most one accessory per kind
+func bestBundle(pen ProductInfo, candidates []AccessoryInfo, req BundleRequest) (Bundle, bool) {
+	var best Bundle
+	found := false
+
+	var walk func(start int, chosen []AccessoryInfo, total float64)
+	walk = func(start int, chosen []AccessoryInfo, total float64) {
+		if bundle, ok := scoreBundle(pen, chosen, total, req); ok && (!found || bundle.Score > best.Score) {
+			best, found = bundle, true
+		}
+		for i := start; i < len(candidates); i++ {
+			next := candidates[i]
+			if total+next.Price > req.Budget || hasKind(chosen, next.Kind) {
+				continue
+			}
+			// Skip accessories that only duplicate a need already covered
+			if next.Kind != AccessoryConverter && !coversNewNeed(chosen, next, req.Needs) {
+				continue
+			}
+			walk(i+1, append(chosen, next), total+next.Price)
+		}
+	}
+	walk(0, nil, pen.Price)
+
+	return best, found
+}
+
+func scoreBundle(pen ProductInfo, chosen []AccessoryInfo, total float64, req BundleRequest) (Bundle, bool) {
+	// Bottled ink is useless in a cartridge/converter pen without a converter
+	if hasKind(chosen, AccessoryInk) && strings.Contains(pen.FillingSystem, "Converter") &&
+		!strings.Contains(pen.FillingSystem, "Piston") && !hasKind(chosen, AccessoryConverter) {
+		return Bundle{}, false
+	}
+
+	covered := make(map[string]bool)
+	// A piston filler has its converter built in
+	if strings.Contains(pen.FillingSystem, "Piston") {
+		covered[AccessoryConverter] = true
+	}
+	var sameBrand int
+	for _, accessory := range chosen {
+		for _, need := range accessory.Provides() {
+			covered[need] = true
+		}
+		if strings.EqualFold(accessory.Brand, pen.Brand) {
+			sameBrand++
+		}
+	}
+
+	var missing []string
+	for _, need := range req.Needs {
+		if !covered[need] {
+			missing = append(missing, need)
+		}
+	}
+	// Accessories nobody asked for only spend the customer's money, except a
+	// converter that makes the chosen bottled ink usable
+	for _, accessory := range chosen {
+		if providesAny(accessory, req.Needs) {
+			continue
+		}
+		if accessory.Kind != AccessoryConverter || !hasKind(chosen, AccessoryInk) {
+			return Bundle{}, false
+		}
+	}
+
+	coverage := 1.0
+	if len(req.Needs) > 0 {
+		coverage = float64(len(req.Needs)-len(missing)) / float64(len(req.Needs))
+	}
+	brandShare := 0.0
+	if len(chosen) > 0 {
+		brandShare = float64(sameBrand) / float64(len(chosen))
+	}
+	score := bundleWeights.coverage*coverage +
+		bundleWeights.rating*pen.Rating/5 +
+		bundleWeights.budgetUse*total/req.Budget +
+		bundleWeights.sameBrand*brandShare
+
+	bundle := Bundle{
+		Pen:       productName(pen),
+		Items:     []BundleItem{{ID: pen.ID, Name: productName(pen), Kind: "pen", Price: pen.Price}},
+		Total:     math.Round(total*100) / 100,
+		Remaining: math.Round((req.Budget-total)*100) / 100,
+		Score:     math.Round(score*1000) / 1000,
+		Missing:   missing,
+	}
+	for _, accessory := range chosen {
+		bundle.Items = append(bundle.Items, BundleItem{ID: accessory.ID, Name: accessory.Name, Kind: accessory.Kind, Price: accessory.Price})
+	}
+	return bundle, true
+}
+
+func hasKind(accessories []AccessoryInfo, kind string) bool {
+	for _, accessory := range accessories {
+		if accessory.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func coversNewNeed(chosen []AccessoryInfo, next AccessoryInfo, needs []string) bool {
+	for _, need := range needs {
+		if !providesAny(next, []string{need}) {
+			continue
+		}
+		covered := false
+		for _, accessory := range chosen {
+			if providesAny(accessory, []string{need}) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return true
+		}
+	}
+	return false
+}
+
+func providesAny(accessory AccessoryInfo, needs []string) bool {
+	for _, provided := range accessory.Provides() {
+		for _, need := range needs {
+			if provided == need {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"pen-shop/cart"
+	"pen-shop/models"
+)
+
+// CartTools are the cart actions the cart agent can call on the customer's
+// behalf. cart.Service implements them.
+type CartTools interface {
+	View(ctx context.Context, sessionID string) (cart.Cart, error)
+	Add(ctx context.Context, sessionID, userID, sku, nib string, quantity int) (cart.Cart, error)
+	Remove(ctx context.Context, sessionID, sku, nib string) (cart.Cart, error)
+	RequestCheckout(ctx context.Context, sessionID string) (cart.Cart, error)
+	CancelCheckout(ctx context.Context, sessionID string) (cart.Cart, error)
+	// ConfirmCheckout places the order only after a recent RequestCheckout
+	ConfirmCheckout(ctx context.Context, sessionID, userID, source string) (cart.Order, error)
+}
+
+// CartToolCall records one cart action taken during a turn
+type CartToolCall struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+const (
+	cartIntentNone     = ""
+	cartIntentView     = "view"
+	cartIntentAdd      = "add"
+	cartIntentRemove   = "remove"
+	cartIntentCheckout = "checkout"
+	cartIntentConfirm  = "confirm"
+	cartIntentCancel   = "cancel"
+)
+
+// CartActionConfidence is what CanHandle returns for messages that ask for a
+// cart action; the sequential agent routes those straight to the cart agent
+const CartActionConfidence = 0.95
+
+var (
+	cartNibPattern      = regexp.MustCompile(`\b(extra[ -]fine|double broad|fine|medium|broad|ef|xf|bb|f|m|b)(?:[ -]point)?\s+nib\b|\bnib(?:\s+size)?\s*(?:of\s+)?(ef|xf|bb|f|m|b)\b|\b(\d\.\d)\s?mm\b`)
+	cartQuantityPattern = regexp.MustCompile(`\b(?:add|put)\s+(\d{1,2}|one|two|three|four|five)\b`)
+	cartQuantityWords   = map[string]int{"one": 1, "two": 2, "three": 3, "four": 4, "five": 5}
+)
+
+// CartAgent lets customers manage their cart and place orders in chat.
+// Orders are only placed after the customer confirms a checkout summary.
+type CartAgent struct {
+	*BaseAgent
+	cart     CartTools
+	resolver *EntityResolver
+	logger   models.Logger
+}
+
+func NewCartAgent(products map[string]ProductInfo, logger models.Logger) *CartAgent {
+	return &CartAgent{
+		BaseAgent: NewBaseAgent("cart_agent", []string{"cart", "checkout", "orders"}, 1),
+		resolver:  NewEntityResolver(products),
+		logger:    logger,
+	}
+}
+
+// SetCart gives the agent its cart tools; without them it handles nothing
+func (ca *CartAgent) SetCart(tools CartTools) {
+	ca.cart = tools
+}
+
+func (ca *CartAgent) CanHandle(query models.Query) float64 {
+	if ca.cart == nil || cartIntent(query.Content) == cartIntentNone {
+		return 0
+	}
+	return CartActionConfidence
+}
+
+// cartIntent classifies a message as a cart action
+func cartIntent(message string) string {
+	content := strings.ToLower(strings.TrimSpace(message))
+	mentionsCart := containsAny(content, "cart", "basket")
+
+	switch {
+	case content == "confirm" || strings.HasPrefix(content, "confirm ") ||
+		(strings.HasPrefix(content, "yes") && containsAny(content, "order", "confirm", "place it")):
+		return cartIntentConfirm
+	case strings.Contains(content, "cancel") && containsAny(content, "order", "checkout", "check out"):
+		return cartIntentCancel
+	case containsAny(content, "checkout", "check out", "place my order", "place the order", "place an order", "place order"):
+		return cartIntentCheckout
+	case mentionsCart && (containsAny(content, "remove", "delete") || (strings.Contains(content, "take") && strings.Contains(content, " out"))):
+		return cartIntentRemove
+	case mentionsCart && containsAny(content, "add", "put"):
+		return cartIntentAdd
+	case mentionsCart && containsAny(content, "show", "view", "what's in", "what is in", "see", "my cart", "my basket"):
+		return cartIntentView
+	}
+	return cartIntentNone
+}
+
+func containsAny(content string, phrases ...string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(content, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ca *CartAgent) Process(ctx context.Context, query models.Query) (models.Response, error) {
+	intent := cartIntent(query.Content)
+	ca.logger.Info("🛒 Cart action %q for session %s", intent, query.SessionID)
+
+	if query.SessionID == "" {
+		return ca.reply("I can only keep a cart within a chat session. Please start a session and try again.", intent, nil, nil), nil
+	}
+
+	m := ca.moneyFor(query, "")
+	var calls []CartToolCall
+	call := func(tool string, args map[string]interface{}, err error) {
+		record := CartToolCall{Tool: tool, Arguments: args}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		calls = append(calls, record)
+	}
+
+	switch intent {
+	case cartIntentAdd:
+		named := ca.resolver.NamedProducts(query.Content)
+		if len(named) == 0 {
+			return ca.reply("Which pen would you like me to add? You can name it, for example \"add the Parker Sonnet with a fine nib to my cart\".", intent, calls, nil), nil
+		}
+		product := named[0].Product
+		nib, quantity := cartNib(query.Content), cartQuantity(query.Content)
+
+		updated, err := ca.cart.Add(ctx, query.SessionID, query.UserID, product.ID, nib, quantity)
+		call("cart.add", map[string]interface{}{"sku": product.ID, "nib": nib, "quantity": quantity}, err)
+		if err != nil {
+			return ca.reply(ca.explain(err, productName(product)), intent, calls, nil), nil
+		}
+		content := fmt.Sprintf("Added %d × **%s** to your cart.\n\n%s", quantity, productName(product), ca.describe(updated, m))
+		return ca.reply(content, intent, calls, map[string]interface{}{"cart": updated}), nil
+
+	case cartIntentRemove:
+		named := ca.resolver.NamedProducts(query.Content)
+		if len(named) == 0 {
+			return ca.reply("Which pen should I take out of your cart?", intent, calls, nil), nil
+		}
+		product := named[0].Product
+		nib := cartNib(query.Content)
+
+		updated, err := ca.cart.Remove(ctx, query.SessionID, product.ID, nib)
+		call("cart.remove", map[string]interface{}{"sku": product.ID, "nib": nib}, err)
+		if err != nil {
+			return ca.reply(ca.explain(err, productName(product)), intent, calls, nil), nil
+		}
+		content := fmt.Sprintf("Removed **%s** from your cart.\n\n%s", productName(product), ca.describe(updated, m))
+		return ca.reply(content, intent, calls, map[string]interface{}{"cart": updated}), nil
+
+	case cartIntentCheckout:
+		pending, err := ca.cart.RequestCheckout(ctx, query.SessionID)
+		call("cart.request_checkout", nil, err)
+		if err != nil {
+			return ca.reply(ca.explain(err, ""), intent, calls, nil), nil
+		}
+		content := "Here's your order:\n\n" + ca.describe(pending, m) +
+			"\n\nReply **confirm** to place this order, or **cancel order** to keep shopping. Nothing is charged until you confirm."
+		return ca.reply(content, intent, calls, map[string]interface{}{"cart": pending, "awaiting_confirmation": true}), nil
+
+	case cartIntentConfirm:
+		// Never place an order without a checkout summary the customer has seen
+		order, err := ca.cart.ConfirmCheckout(ctx, query.SessionID, query.UserID, "chat")
+		call("cart.confirm_checkout", nil, err)
+		if err != nil {
+			return ca.reply(ca.explain(err, ""), intent, calls, nil), nil
+		}
+		content := fmt.Sprintf("✅ Order **%s** placed: %d item(s), total %s. Thank you for shopping with us!",
+			order.ID, len(order.Items), m.format(order.TotalUSD))
+		return ca.reply(content, intent, calls, map[string]interface{}{"order": order}), nil
+
+	case cartIntentCancel:
+		current, err := ca.cart.CancelCheckout(ctx, query.SessionID)
+		call("cart.cancel_checkout", nil, err)
+		if err != nil {
+			return ca.reply(ca.explain(err, ""), intent, calls, nil), nil
+		}
+		return ca.reply("No problem - nothing was ordered. Your cart is still here:\n\n"+ca.describe(current, m), intent, calls, map[string]interface{}{"cart": current}), nil
+
+	default:
+		current, err := ca.cart.View(ctx, query.SessionID)
+		call("cart.view", nil, err)
+		if err != nil {
+			return ca.reply(ca.explain(err, ""), intent, calls, nil), nil
+		}
+		return ca.reply(ca.describe(current, m), intent, calls, map[string]interface{}{"cart": current}), nil
+	}
+}
+
+func (ca *CartAgent) reply(content, intent string, calls []CartToolCall, extra map[string]interface{}) models.Response {
+	metadata := map[string]interface{}{
+		"cart_action": intent,
+		"tool_calls":  calls,
+	}
+	for key, value := range extra {
+		metadata[key] = value
+	}
+	return models.Response{
+		AgentName:  ca.GetName(),
+		Content:    content,
+		Confidence: 0.95,
+		Metadata:   metadata,
+		Timestamp:  time.Now(),
+	}
+}
+
+// describe lists the cart with line and order totals
+func (ca *CartAgent) describe(c cart.Cart, m money) string {
+	if len(c.Items) == 0 {
+		return "Your cart is empty."
+	}
+
+	var result strings.Builder
+	result.WriteString("**Your cart:**\n")
+	for _, item := range c.Items {
+		name := item.Brand + " " + item.Name
+		if strings.HasPrefix(item.Name, item.Brand) {
+			name = item.Name
+		}
+		if item.Nib != "" {
+			name += fmt.Sprintf(" (%s nib)", item.Nib)
+		}
+		result.WriteString(fmt.Sprintf("• %d × %s - %s\n", item.Quantity, name, m.format(item.UnitPriceUSD*float64(item.Quantity))))
+	}
+	result.WriteString(fmt.Sprintf("**Total: %s**", m.format(c.TotalUSD())))
+	return result.String()
+}
+
+// explain turns cart errors into something the customer can act on
+func (ca *CartAgent) explain(err error, product string) string {
+	var nibErr *cart.NibError
+	switch {
+	case errors.As(err, &nibErr) && nibErr.Requested == "":
+		return fmt.Sprintf("Which nib would you like for the %s? It comes in %s.", product, strings.Join(nibErr.Options, ", "))
+	case errors.As(err, &nibErr):
+		return fmt.Sprintf("The %s isn't available with a %s nib. It comes in %s.", product, nibErr.Requested, strings.Join(nibErr.Options, ", "))
+	case errors.Is(err, cart.ErrOutOfStock):
+		if product == "" {
+			return "Something in your cart has just gone out of stock (" + err.Error() + "). Please remove it and check out again."
+		}
+		return fmt.Sprintf("Sorry, the %s is out of stock right now. Ask us to notify you when it's back.", product)
+	case errors.Is(err, cart.ErrUnknownProduct):
+		return "I couldn't find that pen in our catalogue."
+	case errors.Is(err, cart.ErrNotInCart):
+		return fmt.Sprintf("The %s isn't in your cart.", product)
+	case errors.Is(err, cart.ErrEmptyCart):
+		return "Your cart is empty - tell me which pen you'd like to add first."
+	case errors.Is(err, cart.ErrInvalidQuantity):
+		return "I can add between 1 and 10 of each pen."
+	case errors.Is(err, cart.ErrNotConfirmed):
+		return "There's no order waiting for confirmation. Say **checkout** when you're ready and I'll show you the summary first."
+	case errors.Is(err, cart.ErrPriceChanged):
+		return "Prices changed since you added these pens, so I've updated your cart. Say **checkout** to review the new total before ordering."
+	default:
+		ca.logger.Error("Cart action failed: %v", err)
+		return "Sorry, I couldn't update your cart just now. Please try again in a moment."
+	}
+}
+
+// cartNib extracts a nib choice such as "F" or "0.7mm" from a message
+func cartNib(message string) string {
+	match := cartNibPattern.FindStringSubmatch(strings.ToLower(message))
+	if match == nil {
+		return ""
+	}
+	for _, group := range match[1:3] {
+		if group != "" {
+			return cart.NormalizeNib(group)
+		}
+	}
+	return match[3] + "mm"
+}
+
+// cartQuantity reads "add 2 ..." style quantities, defaulting to one
+func cartQuantity(message string) int {
+	match := cartQuantityPattern.FindStringSubmatch(strings.ToLower(message))
+	if match == nil {
+		return 1
+	}
+	if n, ok := cartQuantityWords[match[1]]; ok {
+		return n
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,173 @@
+package agents
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ComparisonTable is a side-by-side view of two or more products
+type ComparisonTable struct {
+	Products []string         `json:"products"`
+	Rows     []ComparisonRow  `json:"rows"`
+	Verdicts []UseCaseVerdict `json:"verdicts"`
+}
+
+// ComparisonRow holds one attribute's value for every compared product
+type ComparisonRow struct {
+	Attribute string   `json:"attribute"`
+	Values    []string `json:"values"`
+}
+
+// UseCaseVerdict names the best product for a particular use
+type UseCaseVerdict struct {
+	UseCase string `json:"use_case"`
+	Winner  string `json:"winner"`
+	Reason  string `json:"reason"`
+}
+
+// useCase scores how well a product suits a use; higher is better
+type useCase struct {
+	name   string
+	score  func(p ProductInfo) float64
+	reason func(p ProductInfo, m money) string
+}
+
+var comparisonUseCases = []useCase{
+	{
+		name: "Everyday writing",
+		score: func(p ProductInfo) float64 {
+			score := p.Rating
+			if hasFeature(p, "retractable") || hasFeature(p, "click mechanism") {
+				score += 1
+			}
+			if p.Price < 200 {
+				score += 0.5
+			}
+			return score
+		},
+		reason: func(p ProductInfo, m money) string {
+			if hasFeature(p, "retractable") || hasFeature(p, "click mechanism") {
+				return "quick to deploy with a click and well rated for daily use"
+			}
+			return fmt.Sprintf("reliable daily writer rated %.1f/5", p.Rating)
+		},
+	},
+	{
+		name: "Beginners",
+		score: func(p ProductInfo) float64 {
+			score := 200 / (p.Price + 20)
+			if strings.Contains(p.FillingSystem, "Cartridge") || p.FillingSystem == "Refillable" {
+				score += 1
+			}
+			return score
+		},
+		reason: func(p ProductInfo, m money) string {
+			return fmt.Sprintf("approachable at %s with an easy %s filling system", m.format(p.Price), strings.ToLower(p.FillingSystem))
+		},
+	},
+	{
+		name: "Gifting",
+		score: func(p ProductInfo) float64 {
+			score := p.Rating
+			if hasFeature(p, "gift-worthy") || hasFeature(p, "hand-crafted") {
+				score += 1
+			}
+			if hasFeature(p, "lifetime warranty") {
+				score += 0.5
+			}
+			if p.Price >= 75 {
+				score += 0.5
+			}
+			return score
+		},
+		reason: func(p ProductInfo, m money) string {
+			return fmt.Sprintf("%s finish that makes a memorable present", strings.ToLower(strings.Join(p.Materials, " and ")))
+		},
+	},
+	{
+		name: "Best value",
+		score: func(p ProductInfo) float64 {
+			return p.Rating / p.Price * 100
+		},
+		reason: func(p ProductInfo, m money) string {
+			return fmt.Sprintf("the highest rating for the money (%.1f/5 for %s)", p.Rating, m.format(p.Price))
+		},
+	},
+}
+
+// buildComparison lays the products out side by side and picks a winner per
+// use case, quoting prices in the customer's currency
+func buildComparison(products []ProductInfo, m money) ComparisonTable {
+	table := ComparisonTable{}
+	for _, p := range products {
+		table.Products = append(table.Products, productName(p))
+	}
+
+	attributes := []struct {
+		name  string
+		value func(p ProductInfo) string
+	}{
+		{"Type", func(p ProductInfo) string { return p.Type }},
+		{"Nib / tip sizes", func(p ProductInfo) string { return strings.Join(p.NibSizes, ", ") }},
+		{"Filling system", func(p ProductInfo) string { return p.FillingSystem }},
+		{"Materials", func(p ProductInfo) string { return strings.Join(p.Materials, ", ") }},
+		{"Price", func(p ProductInfo) string { return m.format(p.Price) }},
+		{"Features", func(p ProductInfo) string { return strings.Join(p.Features, ", ") }},
+		{"Rating", func(p ProductInfo) string { return fmt.Sprintf("%.1f/5", p.Rating) }},
+	}
+
+	for _, attr := range attributes {
+		row := ComparisonRow{Attribute: attr.name}
+		for _, p := range products {
+			row.Values = append(row.Values, attr.value(p))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	for _, uc := range comparisonUseCases {
+		ranked := append([]ProductInfo(nil), products...)
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return uc.score(ranked[i]) > uc.score(ranked[j])
+		})
+		table.Verdicts = append(table.Verdicts, UseCaseVerdict{
+			UseCase: uc.name,
+			Winner:  productName(ranked[0]),
+			Reason:  uc.reason(ranked[0], m),
+		})
+	}
+
+	return table
+}
+
+// Markdown renders the table and verdicts for the chat response
+func (ct ComparisonTable) Markdown() string {
+	var result strings.Builder
+
+	result.WriteString("**Side-by-side comparison:**\n\n")
+	result.WriteString("| Attribute | " + strings.Join(ct.Products, " | ") + " |\n")
+	result.WriteString("|---" + strings.Repeat("|---", len(ct.Products)) + "|\n")
+	for _, row := range ct.Rows {
+		result.WriteString("| " + row.Attribute + " | " + strings.Join(row.Values, " | ") + " |\n")
+	}
+
+	result.WriteString("\n**Verdict by use case:**\n")
+	for _, verdict := range ct.Verdicts {
+		result.WriteString(fmt.Sprintf("- **%s:** %s - %s\n", verdict.UseCase, verdict.Winner, verdict.Reason))
+	}
+
+	return result.String()
+}
+
+func productName(p ProductInfo) string {
+	return p.Brand + " " + p.Model
+}
+
+func hasFeature(p ProductInfo, feature string) bool {
+	for _, f := range p.Features {
+		if strings.EqualFold(f, feature) {
+			return true
+		}
+	}
+	return false
+}
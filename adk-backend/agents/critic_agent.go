@@ -0,0 +1,202 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"pen-shop/currency"
+	"pen-shop/models"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Critique is the verdict a critic returns in its response metadata under
+// "critique"; LoopAgent reads it to decide whether to revise
+type Critique struct {
+	Approved bool     `json:"approved"`
+	Issues   []string `json:"issues,omitempty"`
+}
+
+// CritiqueAgent reviews a draft answer passed in the query context as
+// "draft": pens over the customer's budget, pens or brands we do not stock,
+// and tone
+type CritiqueAgent struct {
+	*BaseAgent
+	resolver *EntityResolver
+	brands   map[string]bool
+	logger   models.Logger
+}
+
+// knownPenBrands are makers customers and models mention; any of them we do
+// not stock is a sign the draft strayed from the catalogue
+var knownPenBrands = []string{
+	"montblanc", "parker", "waterman", "cross", "pilot", "lamy", "sailor",
+	"pelikan", "kaweco", "twsbi", "visconti", "aurora", "sheaffer",
+	"namiki", "faber-castell", "caran d'ache", "esterbrook", "conklin", "diplomat",
+}
+
+// toneFlags are phrases that have no place in a shop assistant's answer
+var toneFlags = []string{
+	"cheap junk", "stupid", "dumb", "obviously", "as an ai", "you should have",
+	"crap", "waste of money", "i don't care",
+}
+
+// overBudgetDisclosures let a draft mention pricier pens as long as it says so
+var overBudgetDisclosures = []string{"over budget", "over your budget", "above your budget", "beyond your budget", "stretch"}
+
+func NewCritiqueAgent(products map[string]ProductInfo, logger models.Logger) *CritiqueAgent {
+	brands := make(map[string]bool)
+	for _, product := range products {
+		brands[strings.ToLower(product.Brand)] = true
+	}
+	return &CritiqueAgent{
+		BaseAgent: NewBaseAgent("critique_agent", []string{"critique", "quality", "grounding"}, 4),
+		resolver:  NewEntityResolver(products),
+		brands:    brands,
+		logger:    logger,
+	}
+}
+
+// CanHandle only claims queries that carry a draft to review
+func (ca *CritiqueAgent) CanHandle(query models.Query) float64 {
+	if _, ok := query.Context["draft"].(string); ok {
+		return 0.9
+	}
+	return 0
+}
+
+func (ca *CritiqueAgent) Process(ctx context.Context, query models.Query) (models.Response, error) {
+	draft, ok := query.Context["draft"].(string)
+	if !ok {
+		return models.Response{}, fmt.Errorf("no draft to review")
+	}
+
+	var issues []string
+	issues = append(issues, ca.checkBudget(query, draft)...)
+	issues = append(issues, ca.checkGrounding(draft)...)
+	issues = append(issues, checkTone(draft)...)
+
+	verdict := Critique{Approved: len(issues) == 0, Issues: issues}
+	content := "Approved"
+	if !verdict.Approved {
+		content = "Needs revision:\n- " + strings.Join(issues, "\n- ")
+	}
+	ca.logger.Info("🧐 Critique: %s", strings.ReplaceAll(content, "\n", " "))
+
+	return models.Response{
+		AgentName:  ca.GetName(),
+		Content:    content,
+		Confidence: 0.9,
+		Metadata: map[string]interface{}{
+			"critique": verdict,
+		},
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// checkBudget flags catalogue pens named in the draft that cost more than the
+// budget, unless the draft says they are over it
+func (ca *CritiqueAgent) checkBudget(query models.Query, draft string) []string {
+	budget, ok := budgetUSD(query)
+	if !ok {
+		return nil
+	}
+	lower := strings.ToLower(draft)
+	for _, disclosure := range overBudgetDisclosures {
+		if strings.Contains(lower, disclosure) {
+			return nil
+		}
+	}
+
+	var issues []string
+	for _, match := range ca.resolver.NamedProducts(draft) {
+		if match.Product.Price > budget {
+			issues = append(issues, fmt.Sprintf("recommends the %s (%s) above the %s budget without saying so",
+				match.Name, currency.Format(match.Product.Price, currency.USD), currency.Format(budget, currency.USD)))
+		}
+	}
+	return issues
+}
+
+// checkGrounding flags brands we do not stock and drafts that name no
+// catalogue pen at all
+func (ca *CritiqueAgent) checkGrounding(draft string) []string {
+	var issues []string
+	lower := strings.ToLower(draft)
+	for _, brand := range knownPenBrands {
+		if !ca.brands[brand] && containsWord(lower, brand) {
+			issues = append(issues, fmt.Sprintf("mentions %s, which we do not stock", brand))
+		}
+	}
+	if len(ca.resolver.NamedProducts(draft)) == 0 {
+		issues = append(issues, "does not recommend a specific pen from our catalogue")
+	}
+	return issues
+}
+
+// checkTone flags dismissive phrases and shouting
+func checkTone(draft string) []string {
+	var issues []string
+	lower := strings.ToLower(draft)
+	for _, phrase := range toneFlags {
+		if strings.Contains(lower, phrase) {
+			issues = append(issues, fmt.Sprintf("tone: avoid %q", phrase))
+		}
+	}
+	if strings.Count(draft, "!") > 3 {
+		issues = append(issues, "tone: too many exclamation marks")
+	}
+
+	shouted := 0
+	for _, word := range strings.Fields(draft) {
+		word = strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) })
+		// Currency codes such as USD are not shouting
+		if len(word) > 3 && word == strings.ToUpper(word) {
+			shouted++
+		}
+	}
+	if shouted > 3 {
+		issues = append(issues, "tone: avoid writing in capitals")
+	}
+	return issues
+}
+
+// budgetUSD returns the budget pen research worked to, falling back to a
+// dollar budget stated in the query
+func budgetUSD(query models.Query) (float64, bool) {
+	switch value := penResearchMetadata(query)["budget_constraint"].(type) {
+	case float64:
+		if value > 0 {
+			return value, true
+		}
+	case int:
+		if value > 0 {
+			return float64(value), true
+		}
+	}
+	if budget, ok := currency.ParseBudget(query.Content); ok && budget.Currency == currency.USD {
+		return budget.Value, true
+	}
+	return 0, false
+}
+
+// containsWord reports whether word appears in text on word boundaries
+func containsWord(text, word string) bool {
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(word)
+		before := start == 0 || !isWordRune(rune(text[start-1]))
+		after := end == len(text) || !isWordRune(rune(text[end]))
+		if before && after {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,273 @@
+package agents
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ResolvedProduct is a catalogue product the user referred to by name
+type ResolvedProduct struct {
+	Key      string      `json:"key"`
+	Product  ProductInfo `json:"-"`
+	Name     string      `json:"name"`
+	Score    float64     `json:"score"`
+	Matched  string      `json:"matched"`
+	Position int         `json:"-"`
+	// Exact is set when the matched text spells the name or alias without typos
+	Exact bool `json:"-"`
+}
+
+// EntityResolver maps free-text product and brand mentions onto catalogue
+// entries, tolerating accents, aliases, spacing and small typos
+type EntityResolver struct {
+	products map[string]ProductInfo
+	phrases  []resolverPhrase
+}
+
+type resolverPhrase struct {
+	key    string
+	tokens []string
+	// brand phrases match every product of the brand with a lower weight
+	brand  string
+	weight float64
+}
+
+// productAliases are extra names customers use for catalogue products
+var productAliases = map[string][]string{
+	"pilot_g2_premium":           {"g2", "g 2", "g2 premium"},
+	"parker_jotter_premium":      {"jotter", "jotter premium"},
+	"cross_century_classic":      {"century", "century classic"},
+	"parker_sonnet":              {"sonnet"},
+	"pilot_vanishing_point":      {"vanishing point", "vanishing pt", "vanishingpoint", "capless", "vp"},
+	"montblanc_meisterstuck_149": {"meisterstuck", "meisterstueck", "meisterstuck 149", "masterpiece"},
+}
+
+// brandAliases are alternative spellings of brand names
+var brandAliases = map[string][]string{
+	"montblanc": {"mont blanc", "mont-blanc"},
+}
+
+// letterFolds spells out letters that have no decomposed form, so stripping
+// combining marks leaves them unchanged
+var letterFolds = map[rune]string{
+	'ß': "ss", 'ø': "o", 'æ': "ae", 'œ': "oe", 'ł': "l", 'đ': "d",
+}
+
+func NewEntityResolver(products map[string]ProductInfo) *EntityResolver {
+	er := &EntityResolver{products: products}
+
+	brands := make(map[string]bool)
+	for key, product := range products {
+		names := []string{product.Model, product.Brand + " " + product.Model}
+		names = append(names, productAliases[key]...)
+		for _, name := range names {
+			er.addPhrase(resolverPhrase{key: key, tokens: normalizeTokens(name), weight: 1.0})
+		}
+		brands[product.Brand] = true
+	}
+
+	for brand := range brands {
+		names := append([]string{brand}, brandAliases[strings.ToLower(brand)]...)
+		for _, name := range names {
+			er.addPhrase(resolverPhrase{brand: brand, tokens: normalizeTokens(name), weight: 0.6})
+		}
+	}
+
+	return er
+}
+
+func (er *EntityResolver) addPhrase(phrase resolverPhrase) {
+	if len(phrase.tokens) > 0 {
+		er.phrases = append(er.phrases, phrase)
+	}
+}
+
+// Resolve returns the products mentioned in text, best match first. Named
+// products always outrank products that only matched by brand.
+func (er *EntityResolver) Resolve(text string) []ResolvedProduct {
+	tokens := normalizeTokens(text)
+	best := make(map[string]ResolvedProduct)
+
+	consider := func(key string, score float64, matched string, position int, exact bool) {
+		current, seen := best[key]
+		if !seen || score > current.Score || (score == current.Score && position < current.Position) {
+			product := er.products[key]
+			best[key] = ResolvedProduct{
+				Key:      key,
+				Product:  product,
+				Name:     productName(product),
+				Score:    score,
+				Matched:  matched,
+				Position: position,
+				Exact:    exact,
+			}
+		}
+	}
+
+	for _, phrase := range er.phrases {
+		similarity, position, matched := bestWindowMatch(tokens, phrase.tokens)
+		if similarity == 0 {
+			continue
+		}
+		score := similarity * phrase.weight
+		exact := similarity == 1
+
+		if phrase.brand == "" {
+			consider(phrase.key, score, matched, position, exact)
+			continue
+		}
+		for key, product := range er.products {
+			if product.Brand == phrase.brand {
+				consider(key, score, matched, position, exact)
+			}
+		}
+	}
+
+	resolved := make([]ResolvedProduct, 0, len(best))
+	for _, match := range best {
+		resolved = append(resolved, match)
+	}
+	sort.Slice(resolved, func(i, j int) bool {
+		if resolved[i].Score != resolved[j].Score {
+			return resolved[i].Score > resolved[j].Score
+		}
+		if resolved[i].Position != resolved[j].Position {
+			return resolved[i].Position < resolved[j].Position
+		}
+		return resolved[i].Key < resolved[j].Key
+	})
+	return resolved
+}
+
+// NamedProducts returns only products matched by their own name or alias,
+// not by brand alone, in the order they appear in text
+func (er *EntityResolver) NamedProducts(text string) []ResolvedProduct {
+	var named []ResolvedProduct
+	for _, match := range er.Resolve(text) {
+		if match.Score > 0.6 {
+			named = append(named, match)
+		}
+	}
+	sort.SliceStable(named, func(i, j int) bool {
+		return named[i].Position < named[j].Position
+	})
+	return named
+}
+
+// bestWindowMatch slides the phrase over the query tokens and returns the best
+// similarity, the token position and the matched text. Windows are compared
+// both token by token and with spaces removed so "mont blanc" matches "montblanc".
+func bestWindowMatch(tokens, phrase []string) (float64, int, string) {
+	target := strings.Join(phrase, " ")
+	compactTarget := strings.Join(phrase, "")
+
+	var best float64
+	bestPosition := -1
+	var bestText string
+
+	for size := len(phrase) - 1; size <= len(phrase)+1; size++ {
+		if size < 1 {
+			continue
+		}
+		for start := 0; start+size <= len(tokens); start++ {
+			window := tokens[start : start+size]
+			text := strings.Join(window, " ")
+
+			similarity := tokenSimilarity(text, target)
+			if compact := tokenSimilarity(strings.Join(window, ""), compactTarget); compact > similarity {
+				similarity = compact
+			}
+			if similarity > best {
+				best, bestPosition, bestText = similarity, start, text
+			}
+		}
+	}
+
+	if best == 0 {
+		return 0, -1, ""
+	}
+	return best, bestPosition, bestText
+}
+
+// tokenSimilarity is 1 for an exact match and decreases with edit distance.
+// Short names such as "g2" or "cross" must match exactly so that
+// words like "across" don't resolve; longer names allow one typo per five
+// characters.
+func tokenSimilarity(candidate, target string) float64 {
+	if candidate == target {
+		return 1
+	}
+
+	length := len([]rune(target))
+	if length <= 5 {
+		return 0
+	}
+
+	allowed := length / 5
+	distance := damerauLevenshtein(candidate, target)
+	if distance > allowed {
+		return 0
+	}
+	return 1 - float64(distance)/float64(length+1)
+}
+
+// damerauLevenshtein counts insertions, deletions, substitutions and
+// adjacent transpositions needed to turn a into b
+func damerauLevenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := 0; j <= len(rb); j++ {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			rows[i][j] = minInt(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				rows[i][j] = minInt(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(ra)][len(rb)]
+}
+
+// normalizeTokens lowercases, folds accents and splits on anything that is
+// not a letter or digit
+func normalizeTokens(text string) []string {
+	var folded strings.Builder
+	// NFD splits accented letters into a base letter and combining marks, so
+	// "é" and "e" followed by U+0301 both fold to "e"
+	for _, r := range norm.NFD.String(strings.ToLower(text)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case letterFolds[r] != "":
+			folded.WriteString(letterFolds[r])
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			folded.WriteRune(r)
+		default:
+			folded.WriteRune(' ')
+		}
+	}
+	return strings.Fields(folded.String())
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
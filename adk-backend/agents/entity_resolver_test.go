@@ -0,0 +1,83 @@
+package agents
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pen-shop/models"
+)
+
+func TestNormalizeTokensFoldsAccents(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Meisterstück 149", []string{"meisterstuck", "149"}},
+		// "u" followed by a combining diaeresis (U+0308)
+		{"Meisterstu\u0308ck", []string{"meisterstuck"}},
+		{"Crème brûlée", []string{"creme", "brulee"}},
+		// "e" followed by a combining grave accent (U+0300)
+		{"Cre\u0300me", []string{"creme"}},
+		{"Straße, Ørsted & Łódź", []string{"strasse", "orsted", "lodz"}},
+		{"Mont-Blanc's G2!", []string{"mont", "blanc", "s", "g2"}},
+	}
+	for _, tt := range tests {
+		if got := normalizeTokens(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("normalizeTokens(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestResolveDecomposedAccents(t *testing.T) {
+	resolver := NewEntityResolver(Catalogue())
+	for _, text := range []string{
+		"Is the Meisterstück worth it?",
+		"Is the Meisterstu\u0308ck worth it?",
+		"Is the meisterstuck worth it?",
+	} {
+		resolved := resolver.Resolve(text)
+		if len(resolved) == 0 || resolved[0].Key != "montblanc_meisterstuck_149" {
+			t.Errorf("Resolve(%q) = %+v, want montblanc_meisterstuck_149 first", text, resolved)
+		}
+	}
+}
+
+func TestBudgetIsNotAProductName(t *testing.T) {
+	agent := NewPenResearchAgent(nopLogger{})
+	for _, text := range []string{"fountain pen under $149", "pens under 149 dollars"} {
+		resolved := agent.resolver.Resolve(text)
+		for _, match := range resolved {
+			if match.Key == "montblanc_meisterstuck_149" {
+				t.Errorf("Resolve(%q) matched the Meisterstück on the budget: %+v", text, match)
+			}
+		}
+		if keys := agent.relevantKeys(resolved); keys != nil {
+			t.Errorf("relevantKeys(%q) = %v, want the whole catalogue", text, keys)
+		}
+	}
+
+	response, err := agent.Process(context.Background(), models.Query{Content: "fountain pen under $149"})
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if !strings.Contains(response.Content, "Sonnet") {
+		t.Errorf("research for a $149 fountain pen left out the $125 Sonnet:\n%s", response.Content)
+	}
+}
+
+func TestFuzzyBrandDoesNotNarrowSearch(t *testing.T) {
+	agent := NewPenResearchAgent(nopLogger{})
+
+	resolved := agent.resolver.Resolve("a marker pen for whiteboards")
+	if keys := agent.relevantKeys(resolved); keys != nil {
+		t.Errorf("\"marker\" narrowed the search to %v", keys)
+	}
+
+	// A correctly spelled brand still narrows it
+	keys := agent.relevantKeys(agent.resolver.Resolve("something from parker"))
+	if len(keys) != 2 || !keys["parker_jotter_premium"] || !keys["parker_sonnet"] {
+		t.Errorf("\"parker\" narrowed the search to %v, want both Parker pens", keys)
+	}
+}
@@ -0,0 +1,146 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"pen-shop/models"
+	"time"
+)
+
+// LoopAgent refines an answer: a generator drafts it and a critic reviews
+// it, and the generator revises with the critic's issues in its query context
+// as "critique" until the critic approves or maxIterations drafts are made.
+// It takes the name of the step it stands in for, so it can replace an agent
+// in PenShopSequentialAgent without changing the workflow.
+type LoopAgent struct {
+	*BaseAgent
+	generator     models.Agent
+	critic        models.Agent
+	maxIterations int
+	logger        models.Logger
+}
+
+func NewLoopAgent(name string, generator, critic models.Agent, maxIterations int, logger models.Logger) *LoopAgent {
+	if maxIterations < 1 {
+		maxIterations = 1
+	}
+	capabilities := append(append([]string(nil), generator.GetCapabilities()...), "critique")
+	return &LoopAgent{
+		BaseAgent:     NewBaseAgent(name, capabilities, generator.GetPriority()),
+		generator:     generator,
+		critic:        critic,
+		maxIterations: maxIterations,
+		logger:        logger,
+	}
+}
+
+func (la *LoopAgent) CanHandle(query models.Query) float64 {
+	return la.generator.CanHandle(query)
+}
+
+// Process returns the approved draft, or the last one when the critic never
+// approves. Once a first draft exists, a failed or timed-out revision or
+// review ends the loop with the best draft so far instead of failing.
+func (la *LoopAgent) Process(ctx context.Context, query models.Query) (models.Response, error) {
+	var draft models.Response
+	var verdict Critique
+	reviewed := false
+	iterations := 0
+
+	for iteration := 1; iteration <= la.maxIterations; iteration++ {
+		var extra map[string]interface{}
+		if iteration > 1 {
+			extra = map[string]interface{}{
+				"critique":       verdict.Issues,
+				"previous_draft": draft.Content,
+			}
+		}
+		revision, err := la.generator.Process(ctx, withQueryContext(query, extra))
+		if err != nil {
+			if iteration == 1 {
+				return models.Response{}, err
+			}
+			la.logger.Error("❌ Revision %d by %s failed, keeping draft %d: %v", iteration, la.generator.GetName(), iteration-1, err)
+			break
+		}
+		if iteration > 1 && revision.Content == draft.Content {
+			la.logger.Info("🔁 %s made no changes, stopping after %d drafts", la.generator.GetName(), iterations)
+			break
+		}
+		draft = revision
+		iterations = iteration
+
+		review, err := la.critic.Process(ctx, withQueryContext(query, map[string]interface{}{"draft": draft.Content}))
+		if err != nil {
+			la.logger.Error("❌ %s could not review draft %d: %v", la.critic.GetName(), iteration, err)
+			reviewed = false
+			break
+		}
+		verdict, reviewed = critiqueFrom(review), true
+		if verdict.Approved {
+			la.logger.Info("✅ %s approved draft %d", la.critic.GetName(), iteration)
+			break
+		}
+		la.logger.Info("✏️ Draft %d needs revision: %v", iteration, verdict.Issues)
+	}
+
+	metadata := make(map[string]interface{}, len(draft.Metadata)+1)
+	for key, value := range draft.Metadata {
+		metadata[key] = value
+	}
+	metadata["critique"] = map[string]interface{}{
+		"iterations": iterations,
+		"reviewed":   reviewed,
+		"approved":   reviewed && verdict.Approved,
+		"issues":     verdict.Issues,
+	}
+
+	// An answer the critic still objects to is less certain
+	confidence := draft.Confidence
+	if !reviewed || !verdict.Approved {
+		confidence *= 0.8
+	}
+
+	return models.Response{
+		AgentName:  la.GetName(),
+		Content:    draft.Content,
+		Confidence: confidence,
+		Metadata:   metadata,
+		Timestamp:  time.Now(),
+	}, nil
+}
+
+// withQueryContext copies query with extra added to its context, leaving the
+// caller's context map untouched
+func withQueryContext(query models.Query, extra map[string]interface{}) models.Query {
+	merged := make(map[string]interface{}, len(query.Context)+len(extra))
+	for key, value := range query.Context {
+		merged[key] = value
+	}
+	for key, value := range extra {
+		merged[key] = value
+	}
+	query.Context = merged
+	return query
+}
+
+// critiqueFrom reads a critic's verdict; a critic that gives none approves
+func critiqueFrom(review models.Response) Critique {
+	switch verdict := review.Metadata["critique"].(type) {
+	case Critique:
+		return verdict
+	case *Critique:
+		if verdict != nil {
+			return *verdict
+		}
+	case nil:
+		return Critique{Approved: true}
+	default:
+		// Critics behind a cache or checkpoint hand back decoded JSON
+		var decoded Critique
+		if raw, err := json.Marshal(verdict); err == nil && json.Unmarshal(raw, &decoded) == nil {
+			return decoded
+		}
+	}
+	return Critique{Approved: true}
+}
@@ -0,0 +1,66 @@
+package agents
+
+import (
+	"math"
+
+	"pen-shop/currency"
+	"pen-shop/models"
+)
+
+// money renders catalogue prices, which are stored in USD, in the
+// customer's currency
+type money struct {
+	code  string
+	rates currency.Rates
+}
+
+// moneyFor picks the currency for a reply: the one the customer just wrote
+// a budget in, then the storefront currency on the query, then USD
+func (ba *BaseAgent) moneyFor(query models.Query, mentioned string) money {
+	code := currency.Normalize(mentioned, "")
+	if code == "" {
+		code = currency.Normalize(query.Currency, currency.USD)
+	}
+
+	rates := ba.rates
+	if rates == nil {
+		rates = currency.DefaultTable()
+	}
+	// Without a rate we cannot quote in that currency at all
+	if _, err := rates.Convert(1, currency.USD, code); err != nil {
+		code = currency.USD
+	}
+	return money{code: code, rates: rates}
+}
+
+// format converts a USD price into the customer's currency and formats it
+func (m money) format(usd float64) string {
+	value, err := m.rates.Convert(usd, currency.USD, m.code)
+	if err != nil {
+		return currency.Format(usd, currency.USD)
+	}
+	return currency.Format(value, m.code)
+}
+
+// remaining formats what is left of a customer budget after spending a USD
+// amount, subtracting in the budget's own currency so displayed figures add up
+func (m money) remaining(budget currency.Amount, spentUSD float64) string {
+	spent, err := m.rates.Convert(spentUSD, currency.USD, budget.Currency)
+	if err != nil {
+		return m.format(0)
+	}
+	c, _ := currency.Lookup(budget.Currency)
+	scale := math.Pow(10, float64(c.Decimals))
+	return currency.Format(budget.Value-math.Round(spent*scale)/scale, budget.Currency)
+}
+
+// span formats a USD price range such as "$8-$12"
+func (m money) span(lowUSD, highUSD float64) string {
+	return m.format(lowUSD) + "-" + m.format(highUSD)
+}
+
+// toUSD converts a customer amount into catalogue dollars
+func (m money) toUSD(amount currency.Amount) (float64, bool) {
+	value, err := m.rates.Convert(amount.Value, amount.Currency, currency.USD)
+	return value, err == nil
+}
@@ -0,0 +1,273 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pen-shop/models"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AggregationStrategy decides how a ParallelAgent turns its sub-agents'
+// answers into one
+type AggregationStrategy string
+
+const (
+	// FirstSuccess returns the first answer to arrive and cancels the rest
+	FirstSuccess AggregationStrategy = "first_success"
+	// AllResults combines every answer, in sub-agent order
+	AllResults AggregationStrategy = "all"
+	// MajorityVote returns the answer most sub-agents agree on
+	MajorityVote AggregationStrategy = "majority_vote"
+	// HighestConfidence returns the answer with the highest Confidence
+	HighestConfidence AggregationStrategy = "highest_confidence"
+)
+
+// ParseAggregationStrategy accepts a strategy name as used in configuration
+func ParseAggregationStrategy(name string) (AggregationStrategy, bool) {
+	switch strategy := AggregationStrategy(strings.ToLower(strings.TrimSpace(name))); strategy {
+	case FirstSuccess, AllResults, MajorityVote, HighestConfidence:
+		return strategy, true
+	}
+	return "", false
+}
+
+// BranchResult is one sub-agent's part in a parallel run
+type BranchResult struct {
+	AgentName  string  `json:"agent_name"`
+	Status     string  `json:"status"`
+	Confidence float64 `json:"confidence,omitempty"`
+	DurationMs int64   `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// ParallelAgent runs every sub-agent on the same query at once and aggregates
+// their answers. It fails only when no sub-agent answers.
+type ParallelAgent struct {
+	*BaseAgent
+	subAgents []models.Agent
+	strategy  AggregationStrategy
+	// voteKey reduces an answer to what sub-agents vote on
+	voteKey func(models.Response) string
+	logger  models.Logger
+}
+
+func NewParallelAgent(name string, strategy AggregationStrategy, logger models.Logger, subAgents ...models.Agent) *ParallelAgent {
+	var capabilities []string
+	seen := make(map[string]bool)
+	priority := 0
+	for i, agent := range subAgents {
+		for _, capability := range agent.GetCapabilities() {
+			if !seen[capability] {
+				seen[capability] = true
+				capabilities = append(capabilities, capability)
+			}
+		}
+		if i == 0 || agent.GetPriority() < priority {
+			priority = agent.GetPriority()
+		}
+	}
+	return &ParallelAgent{
+		BaseAgent: NewBaseAgent(name, capabilities, priority),
+		subAgents: subAgents,
+		strategy:  strategy,
+		voteKey:   normalizedContent,
+		logger:    logger,
+	}
+}
+
+// SetVoteKey changes what MajorityVote compares; by default answers must
+// match word for word, ignoring case and spacing
+func (pa *ParallelAgent) SetVoteKey(key func(models.Response) string) {
+	pa.voteKey = key
+}
+
+// CanHandle is the most confident sub-agent's answer
+func (pa *ParallelAgent) CanHandle(query models.Query) float64 {
+	best := 0.0
+	for _, agent := range pa.subAgents {
+		if confidence := agent.CanHandle(query); confidence > best {
+			best = confidence
+		}
+	}
+	return best
+}
+
+type branchOutcome struct {
+	index    int
+	response models.Response
+	err      error
+	duration time.Duration
+}
+
+func (pa *ParallelAgent) Process(ctx context.Context, query models.Query) (models.Response, error) {
+	if len(pa.subAgents) == 0 {
+		return models.Response{}, errors.New("parallel agent has no sub-agents")
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	outcomes := make(chan branchOutcome, len(pa.subAgents))
+	var wg sync.WaitGroup
+	for i, agent := range pa.subAgents {
+		wg.Add(1)
+		go func(i int, agent models.Agent) {
+			defer wg.Done()
+			start := time.Now()
+			response, err := agent.Process(runCtx, query)
+			outcomes <- branchOutcome{index: i, response: response, err: err, duration: time.Since(start)}
+		}(i, agent)
+	}
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	results := make([]*branchOutcome, len(pa.subAgents))
+	for outcome := range outcomes {
+		outcome := outcome
+		results[outcome.index] = &outcome
+		if pa.strategy == FirstSuccess && outcome.err == nil {
+			// The others are abandoned; their goroutines drain into the buffer
+			cancel()
+			break
+		}
+	}
+
+	branches := make([]BranchResult, len(pa.subAgents))
+	var answered []int
+	var failures []string
+	for i, agent := range pa.subAgents {
+		// Sub-agents abandoned by FirstSuccess have no result
+		branch := BranchResult{AgentName: agent.GetName(), Status: "canceled"}
+		if result := results[i]; result != nil {
+			branch.DurationMs = result.duration.Milliseconds()
+			if result.err != nil {
+				branch.Status = string(StepFailed)
+				branch.Error = result.err.Error()
+				failures = append(failures, fmt.Sprintf("%s: %v", agent.GetName(), result.err))
+			} else {
+				branch.Status = string(StepSucceeded)
+				branch.Confidence = result.response.Confidence
+				answered = append(answered, i)
+			}
+		}
+		branches[i] = branch
+	}
+	if len(answered) == 0 {
+		return models.Response{}, fmt.Errorf("no sub-agent of %s answered: %s", pa.GetName(), strings.Join(failures, "; "))
+	}
+
+	var response models.Response
+	var winner int
+	metadata := map[string]interface{}{}
+	summary := map[string]interface{}{
+		"strategy": pa.strategy,
+		"branches": branches,
+	}
+	switch pa.strategy {
+	case FirstSuccess:
+		winner = answered[0]
+		response = results[winner].response
+	case AllResults:
+		response, metadata = pa.combine(results, answered)
+		winner = -1
+	case MajorityVote:
+		var votes int
+		winner, votes = pa.vote(results, answered)
+		response = results[winner].response
+		summary["votes"] = votes
+	default:
+		winner = answered[0]
+		for _, i := range answered[1:] {
+			if results[i].response.Confidence > results[winner].response.Confidence {
+				winner = i
+			}
+		}
+		response = results[winner].response
+	}
+
+	if winner >= 0 {
+		for key, value := range response.Metadata {
+			metadata[key] = value
+		}
+		summary["winner"] = pa.subAgents[winner].GetName()
+		pa.logger.Info("🔀 %s: %s chose %s", pa.GetName(), pa.strategy, pa.subAgents[winner].GetName())
+	}
+	metadata["parallel"] = summary
+
+	return models.Response{
+		AgentName:  pa.GetName(),
+		Content:    response.Content,
+		Confidence: response.Confidence,
+		Metadata:   metadata,
+		Timestamp:  time.Now(),
+	}, nil
+}
+
+// combine joins every answer in sub-agent order, at their mean confidence,
+// keeping each answer's metadata under its agent's name
+func (pa *ParallelAgent) combine(results []*branchOutcome, answered []int) (models.Response, map[string]interface{}) {
+	var contents []string
+	var confidence float64
+	perAgent := make(map[string]interface{}, len(answered))
+	for _, i := range answered {
+		contents = append(contents, results[i].response.Content)
+		confidence += results[i].response.Confidence
+		perAgent[pa.subAgents[i].GetName()] = results[i].response.Metadata
+	}
+	response := models.Response{
+		Content:    strings.Join(contents, "\n\n"),
+		Confidence: confidence / float64(len(answered)),
+	}
+	return response, map[string]interface{}{"results": perAgent}
+}
+
+// vote returns the answer whose key most sub-agents share, and how many did.
+// Ties go to the group with the higher total confidence, then to the earlier
+// sub-agent; within the group the most confident answer is returned.
+func (pa *ParallelAgent) vote(results []*branchOutcome, answered []int) (int, int) {
+	type tally struct {
+		votes      int
+		confidence float64
+		first      int
+		best       int
+	}
+	tallies := make(map[string]*tally)
+	for _, i := range answered {
+		key := pa.voteKey(results[i].response)
+		t, ok := tallies[key]
+		if !ok {
+			t = &tally{first: i, best: i}
+			tallies[key] = t
+		}
+		t.votes++
+		t.confidence += results[i].response.Confidence
+		if results[i].response.Confidence > results[t.best].response.Confidence {
+			t.best = i
+		}
+	}
+
+	var winner *tally
+	for _, t := range tallies {
+		switch {
+		case winner == nil,
+			t.votes > winner.votes,
+			t.votes == winner.votes && t.confidence > winner.confidence,
+			t.votes == winner.votes && t.confidence == winner.confidence && t.first < winner.first:
+			winner = t
+		}
+	}
+	return winner.best, winner.votes
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// normalizedContent compares answers ignoring case and spacing
+func normalizedContent(response models.Response) string {
+	return whitespace.ReplaceAllString(strings.ToLower(strings.TrimSpace(response.Content)), " ")
+}
@@ -2,10 +2,13 @@ package agents
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"pen-shop/currency"
+	"pen-shop/llm"
 	"pen-shop/models"
-	"regexp"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 )
@@ -14,10 +17,15 @@ import (
 type PenResearchAgent struct {
 	*BaseAgent
 	productDatabase map[string]ProductInfo
+	accessories     []AccessoryInfo
+	resolver        *EntityResolver
+	index           *SearchIndex
+	semantic        *SemanticIndex
 	logger          models.Logger
 }
 
 type ProductInfo struct {
+	ID            string
 	Brand         string
 	Model         string
 	Type          string
@@ -26,97 +34,175 @@ type ProductInfo struct {
 	Materials     []string
 	Price         float64
 	Features      []string
+	Rating        float64
+	Description   string
+	InStock       bool
 }
 
 func NewPenResearchAgent(logger models.Logger) *PenResearchAgent {
+	productDatabase := Catalogue()
+
 	return &PenResearchAgent{
-		BaseAgent: NewBaseAgent("pen_research", []string{"research", "products", "specifications"}, 2),
-		productDatabase: map[string]ProductInfo{
-			"pilot_g2_premium": {
-				Brand:         "Pilot",
-				Model:         "G2 Premium",
-				Type:          "Gel Pen",
-				NibSizes:      []string{"0.7mm", "1.0mm"},
-				FillingSystem: "Refillable",
-				Materials:     []string{"Metal Body", "Rubber Grip"},
-				Price:         12.50,
-				Features:      []string{"Smooth Gel Ink", "Retractable", "Professional Look"},
-			},
-			"parker_jotter_premium": {
-				Brand:         "Parker",
-				Model:         "Jotter Premium",
-				Type:          "Ballpoint Pen",
-				NibSizes:      []string{"Medium"},
-				FillingSystem: "Refillable",
-				Materials:     []string{"Stainless Steel", "Chrome Trim"},
-				Price:         46.00,
-				Features:      []string{"Click Mechanism", "Reliable", "Classic Design"},
-			},
-			"cross_century_classic": {
-				Brand:         "Cross",
-				Model:         "Century Classic",
-				Type:          "Ballpoint Pen",
-				NibSizes:      []string{"Medium"},
-				FillingSystem: "Refillable",
-				Materials:     []string{"Chrome Finish"},
-				Price:         75.00,
-				Features:      []string{"Professional", "Lifetime Warranty", "Gift-worthy"},
-			},
-			"parker_sonnet": {
-				Brand:         "Parker",
-				Model:         "Sonnet",
-				Type:          "Fountain Pen",
-				NibSizes:      []string{"F", "M"},
-				FillingSystem: "Cartridge/Converter",
-				Materials:     []string{"Stainless Steel", "Gold Trim"},
-				Price:         125.00,
-				Features:      []string{"Classic Design", "Reliable Feed"},
-			},
-			"pilot_vanishing_point": {
-				Brand:         "Pilot",
-				Model:         "Vanishing Point",
-				Type:          "Fountain Pen",
-				NibSizes:      []string{"EF", "F", "M", "B"},
-				FillingSystem: "Cartridge/Converter",
-				Materials:     []string{"Brass Body", "Gold Nib"},
-				Price:         165.00,
-				Features:      []string{"Retractable Nib", "Click Mechanism"},
-			},
-			"montblanc_meisterstuck_149": {
-				Brand:         "Montblanc",
-				Model:         "Meisterstück 149",
-				Type:          "Fountain Pen",
-				NibSizes:      []string{"EF", "F", "M", "B", "BB"},
-				FillingSystem: "Piston Converter",
-				Materials:     []string{"Black Precious Resin", "14K Gold Nib"},
-				Price:         895.00,
-				Features:      []string{"Hand-crafted", "Lifetime Warranty", "Flexible Nib"},
-			},
+		BaseAgent:       NewBaseAgent("pen_research", []string{"research", "products", "specifications"}, 2),
+		productDatabase: productDatabase,
+		accessories:     accessoryCatalogue,
+		resolver:        NewEntityResolver(productDatabase),
+		index:           NewSearchIndex(productDatabase),
+		logger:          logger,
+	}
+}
+
+// Catalogue returns the pens we sell keyed by product key, as a fresh map
+func Catalogue() map[string]ProductInfo {
+	return map[string]ProductInfo{
+		"pilot_g2_premium": {
+			ID:            "pen-003",
+			Brand:         "Pilot",
+			Model:         "G2 Premium",
+			Type:          "Gel Pen",
+			NibSizes:      []string{"0.7mm", "1.0mm"},
+			FillingSystem: "Refillable",
+			Materials:     []string{"Metal Body", "Rubber Grip"},
+			Price:         12.50,
+			Features:      []string{"Smooth Gel Ink", "Retractable", "Professional Look"},
+			Rating:        4.3,
+			Description:   "Smooth writing gel pen with comfortable grip and vibrant ink",
+			InStock:       false,
+		},
+		"parker_jotter_premium": {
+			ID:            "pen-001",
+			Brand:         "Parker",
+			Model:         "Jotter Premium",
+			Type:          "Ballpoint Pen",
+			NibSizes:      []string{"Medium"},
+			FillingSystem: "Refillable",
+			Materials:     []string{"Stainless Steel", "Chrome Trim"},
+			Price:         46.00,
+			Features:      []string{"Click Mechanism", "Reliable", "Classic Design"},
+			Rating:        4.6,
+			Description:   "Classic stainless steel ballpoint pen with premium blue ink refill",
+			InStock:       true,
+		},
+		"cross_century_classic": {
+			ID:            "pen-005",
+			Brand:         "Cross",
+			Model:         "Century Classic",
+			Type:          "Ballpoint Pen",
+			NibSizes:      []string{"Medium"},
+			FillingSystem: "Refillable",
+			Materials:     []string{"Chrome Finish"},
+			Price:         75.00,
+			Features:      []string{"Professional", "Lifetime Warranty", "Gift-worthy"},
+			Rating:        4.4,
+			Description:   "Timeless ballpoint pen with lustrous chrome finish",
+			InStock:       true,
+		},
+		"parker_sonnet": {
+			ID:            "pen-011",
+			Brand:         "Parker",
+			Model:         "Sonnet",
+			Type:          "Fountain Pen",
+			NibSizes:      []string{"F", "M"},
+			FillingSystem: "Cartridge/Converter",
+			Materials:     []string{"Stainless Steel", "Gold Trim"},
+			Price:         125.00,
+			Features:      []string{"Classic Design", "Reliable Feed"},
+			Rating:        4.5,
+			Description:   "Classic fountain pen with gold trim and medium nib",
+			InStock:       true,
+		},
+		"pilot_vanishing_point": {
+			ID:            "pen-008",
+			Brand:         "Pilot",
+			Model:         "Vanishing Point",
+			Type:          "Fountain Pen",
+			NibSizes:      []string{"EF", "F", "M", "B"},
+			FillingSystem: "Cartridge/Converter",
+			Materials:     []string{"Brass Body", "Gold Nib"},
+			Price:         165.00,
+			Features:      []string{"Retractable Nib", "Click Mechanism"},
+			Rating:        4.7,
+			Description:   "Retractable fountain pen with unique click mechanism",
+			InStock:       true,
+		},
+		"montblanc_meisterstuck_149": {
+			ID:            "pen-006",
+			Brand:         "Montblanc",
+			Model:         "Meisterstück 149",
+			Type:          "Fountain Pen",
+			NibSizes:      []string{"EF", "F", "M", "B", "BB"},
+			FillingSystem: "Piston Converter",
+			Materials:     []string{"Black Precious Resin", "14K Gold Nib"},
+			Price:         895.00,
+			Features:      []string{"Hand-crafted", "Lifetime Warranty", "Flexible Nib"},
+			Rating:        4.9,
+			Description:   "The ultimate luxury fountain pen with 14K gold nib",
+			InStock:       true,
 		},
-		logger: logger,
 	}
 }
 
-func (pra *PenResearchAgent) extractBudget(content string) (float64, bool) {
-	patterns := []string{
-		`under\s*\$(\d+)`,
-		`less\s+than\s*\$(\d+)`,
-		`below\s*\$(\d+)`,
-		`\$(\d+)\s*or\s*less`,
-		`\$(\d+)\s*max`,
-		`maximum\s*\$(\d+)`,
-	}
-	
-	for _, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
-		if matches := re.FindStringSubmatch(content); len(matches) > 1 {
-			if budget, err := strconv.ParseFloat(matches[1], 64); err == nil {
-				return budget, true
-			}
+// SetSemanticIndex enables embedding-based retrieval for descriptive queries
+func (pra *PenResearchAgent) SetSemanticIndex(semantic *SemanticIndex) {
+	pra.semantic = semantic
+}
+
+// Products returns the catalogue keyed by product key
+func (pra *PenResearchAgent) Products() map[string]ProductInfo {
+	return pra.productDatabase
+}
+
+// semanticBoostWeight scales cosine similarity into BM25 score units
+const semanticBoostWeight = 3.0
+
+// SimilarProducts returns the k catalogue products closest in meaning to
+// query, or nil when no semantic index is configured
+func (pra *PenResearchAgent) SimilarProducts(ctx context.Context, query string, k int) ([]SimilarProduct, error) {
+	if pra.semantic == nil {
+		return nil, nil
+	}
+	return pra.semantic.SimilarProducts(ctx, query, k)
+}
+
+// extractBudget finds a spending limit in any supported currency. A bare
+// amount such as "a pen for 80 euros" is read as the budget too.
+func (pra *PenResearchAgent) extractBudget(content string) (currency.Amount, bool) {
+	if budget, ok := currency.ParseBudget(content); ok {
+		return budget, true
+	}
+	return currency.ParseAmount(content)
+}
+
+// mentionsBudget reports whether a query talks about money in a way the
+// budget regexes could not parse, e.g. "around a hundred bucks"
+func (pra *PenResearchAgent) mentionsBudget(content string) bool {
+	hints := []string{"budget", "bucks", "dollars", "euros", "pounds", "quid", "yen", "spend", "afford", "around", "under"}
+	for _, hint := range hints {
+		if strings.Contains(content, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractBudgetWithModel asks the agent's model to pull a budget out of free text
+func (pra *PenResearchAgent) extractBudgetWithModel(ctx context.Context, content string) (currency.Amount, bool) {
+	resp, err := pra.complete(ctx,
+		"Extract the customer's maximum budget. Reply with only the number followed by its ISO currency code, for example 80 EUR, or NONE if there is no budget.",
+		content)
+	if err != nil {
+		if !errors.Is(err, llm.ErrNoProvider) {
+			pra.logger.Error("Budget extraction failed: %v", err)
 		}
+		return currency.Amount{}, false
+	}
+
+	budget, ok := currency.ParseAmount(strings.TrimSpace(resp.Content))
+	if !ok {
+		return currency.Amount{}, false
 	}
-	
-	return 0, false
+	pra.logger.Info("💰 Model extracted budget via %s: %s", resp.Provider, budget)
+	return budget, true
 }
 
 func (pra *PenResearchAgent) CanHandle(query models.Query) float64 {
@@ -138,122 +224,432 @@ func (pra *PenResearchAgent) Process(ctx context.Context, query models.Query) (m
 	pra.logger.Info("🔍 Researching pens for: %s", query.Content)
 
 	content := strings.ToLower(query.Content)
-	
+
 	// Check for budget constraints
-	budget, hasBudget := pra.extractBudget(content)
-	
-	var matchedProducts []ProductInfo
+	budgetAmount, hasBudget := pra.extractBudget(content)
+	if !hasBudget && pra.mentionsBudget(content) {
+		budgetAmount, hasBudget = pra.extractBudgetWithModel(ctx, query.Content)
+	}
 
-	// Filter products based on budget and relevance
-	for _, product := range pra.productDatabase {
-		// Skip products over budget if budget is specified
-		if hasBudget && product.Price > budget {
-			continue
+	// Quote prices in the currency the budget was given in; the catalogue
+	// itself is priced in USD
+	m := pra.moneyFor(query, budgetAmount.Currency)
+	var budget float64
+	if hasBudget {
+		if usd, ok := m.toUSD(budgetAmount); ok {
+			budget = usd
+		} else {
+			pra.logger.Error("No exchange rate for %s, ignoring budget", budgetAmount.Currency)
+			hasBudget = false
 		}
-		
-		if pra.isProductRelevant(product, content) {
-			matchedProducts = append(matchedProducts, product)
+	}
+
+	// "$150 for a pen and ink" asks for a bundle, not just a pen under $150
+	var bundles []Bundle
+	if needs := requestedAccessories(content); hasBudget && len(needs) > 0 {
+		bundles = OptimizeBundles(pra.currentCatalogue(pra.productDatabase), pra.accessories, BundleRequest{
+			Budget: budget,
+			Needs:  needs,
+			Types:  requestedTypes(content),
+		})
+		pra.logger.Info("🎁 Built %d bundles for %s within %s", len(bundles), strings.Join(needs, ", "), budgetAmount)
+	}
+
+	// Side-by-side comparison when the user names two or more products
+	if pra.isComparison(content) {
+		if named := pra.namedProducts(content); len(named) >= 2 {
+			return pra.compareProducts(named, m), nil
 		}
 	}
 
-	// If no products match budget, provide helpful message
-	if hasBudget && len(matchedProducts) == 0 {
-		response := fmt.Sprintf("I searched our catalog for pens under $%.2f. Unfortunately, our current selection doesn't have quality options in that exact range. The closest options are:\n\n", budget)
-		
-		// Find the closest options above budget
-		var closestProducts []ProductInfo
-		minDiff := 1000.0
-		
-		for _, product := range pra.productDatabase {
-			if product.Price > budget {
-				diff := product.Price - budget
-				if diff < minDiff {
-					minDiff = diff
-					closestProducts = []ProductInfo{product}
-				} else if diff == minDiff {
-					closestProducts = append(closestProducts, product)
-				}
-			}
+	// Rank the catalogue, restricted to what the user named and filtered by
+	// budget, pen type and availability
+	resolved := pra.resolver.Resolve(content)
+	opts := SearchOptions{
+		Restrict:     pra.relevantKeys(resolved),
+		Types:        requestedTypes(content),
+		InStockOnly:  wantsInStockOnly(content),
+		Availability: pra.availability,
+		Boosts:       make(map[string]float64),
+	}
+	// The workflow repeats research with a relaxed budget when nothing fit
+	relaxation, _ := query.Context[budgetRelaxationKey].(float64)
+	relaxed := hasBudget && relaxation > 1
+	if hasBudget {
+		opts.MaxPrice = budget
+		if relaxed {
+			opts.MaxPrice = budget * relaxation
 		}
-		
-		for i, product := range closestProducts {
-			if i >= 2 { // Limit to 2 closest options
-				break
-			}
-			response += fmt.Sprintf("**%s %s** ($%.2f) - only $%.2f over budget\n", 
-				product.Brand, product.Model, product.Price, product.Price-budget)
-			response += fmt.Sprintf("- %s with %s\n\n", product.Type, strings.Join(product.Features, ", "))
+	}
+	for _, match := range resolved {
+		opts.Boosts[match.Key] = match.Score
+	}
+
+	// Descriptive queries such as "feels heavy and luxurious" rarely share
+	// keywords with the catalogue, so semantic similarity adds to the ranking
+	similar, err := pra.SimilarProducts(ctx, query.Content, 5)
+	if err != nil {
+		pra.logger.Error("Semantic search failed, using keyword ranking only: %v", err)
+	}
+	for _, match := range similar {
+		opts.Boosts[match.Key] += match.Score * semanticBoostWeight
+	}
+	searchResults := pra.index.Search(content, opts)
+
+	var matchedProducts []ProductInfo
+	for _, result := range searchResults {
+		matchedProducts = append(matchedProducts, result.Product)
+	}
+
+	// Nothing fits the budget; the workflow decides whether to widen it
+	if hasBudget && len(matchedProducts) == 0 {
+		var widened string
+		if relaxed {
+			widened = fmt.Sprintf(", and then up to %s", m.format(budget*relaxation))
 		}
-		
 		return models.Response{
 			AgentName:  pra.GetName(),
-			Content:    response,
-			Confidence: 0.6,
+			Content:    fmt.Sprintf("I searched our catalog for pens under %s%s. Unfortunately, our current selection doesn't have quality options in that range. Let me know if you can stretch your budget.", budgetAmount, widened),
+			Confidence: 0.5,
 			Metadata: map[string]interface{}{
-				"products_found": 0,
+				"products_found":    0,
+				"research_type":     "budget_filtered",
 				"budget_constraint": budget,
-				"alternatives_shown": len(closestProducts),
+				"budget":            budgetAmount,
+				"currency":          m.code,
+				"bundles":           bundles,
 			},
 			Timestamp: time.Now(),
 		}, nil
 	}
 
-	response := pra.generateResearchResponse(matchedProducts, content, budget, hasBudget)
+	// Without a budget, the type, stock and name filters can still rule out everything
+	if len(matchedProducts) == 0 {
+		return pra.noMatch(content, opts, m), nil
+	}
+
+	if relaxed {
+		return pra.closestOverBudget(matchedProducts, m, budget, budgetAmount, relaxation, bundles), nil
+	}
+
+	var maxPrice float64
+	if hasBudget {
+		maxPrice = budget
+	}
+	alternatives := pra.stockAlternatives(firstProducts(matchedProducts, 3), maxPrice)
+	response := pra.generateResearchResponse(matchedProducts, alternatives, m, budgetAmount, hasBudget)
 
 	return models.Response{
 		AgentName:  pra.GetName(),
 		Content:    response,
 		Confidence: 0.8,
 		Metadata: map[string]interface{}{
-			"products_found": len(matchedProducts),
-			"research_type":  "budget_filtered",
+			"products_found":    len(matchedProducts),
+			"resolved_entities": resolved,
+			"search_results":    searchResults,
+			"similar_products":  similar,
+			"research_type":     "budget_filtered",
 			"budget_constraint": budget,
+			"currency":          m.code,
+			"products_shown":    firstProducts(matchedProducts, 3),
+			"alternatives":      alternatives,
+			"bundles":           bundles,
 		},
 		Timestamp: time.Now(),
 	}, nil
 }
 
-func (pra *PenResearchAgent) isProductRelevant(product ProductInfo, query string) bool {
-	searchTerms := []string{
-		strings.ToLower(product.Brand),
-		strings.ToLower(product.Model),
-		strings.ToLower(product.Type),
+// closestOverBudget presents what a relaxed search found as the nearest
+// options above the customer's budget, cheapest first
+func (pra *PenResearchAgent) closestOverBudget(products []ProductInfo, m money, budget float64, budgetAmount currency.Amount, relaxation float64, bundles []Bundle) models.Response {
+	closest := append([]ProductInfo(nil), products...)
+	sort.Slice(closest, func(i, j int) bool {
+		if closest[i].Price != closest[j].Price {
+			return closest[i].Price < closest[j].Price
+		}
+		if closest[i].InStock != closest[j].InStock {
+			return closest[i].InStock
+		}
+		return closest[i].ID < closest[j].ID
+	})
+	shown := firstProducts(closest, 2)
+
+	response := fmt.Sprintf("I searched our catalog for pens under %s. Unfortunately, our current selection doesn't have quality options in that exact range. The closest options are:\n\n", budgetAmount)
+	for _, product := range shown {
+		response += fmt.Sprintf("**%s %s** (%s) - only %s over budget%s\n",
+			product.Brand, product.Model, m.format(product.Price), m.format(product.Price-budget), stockLabel(product))
+		response += fmt.Sprintf("- %s with %s\n\n", product.Type, strings.Join(product.Features, ", "))
+	}
+
+	return models.Response{
+		AgentName:  pra.GetName(),
+		Content:    response,
+		Confidence: 0.6,
+		Metadata: map[string]interface{}{
+			"products_found":     len(products),
+			"research_type":      "budget_relaxed",
+			"budget_constraint":  budget,
+			"budget":             budgetAmount,
+			"budget_relaxation":  relaxation,
+			"currency":           m.code,
+			"alternatives_shown": len(shown),
+			"products_shown":     shown,
+			"bundles":            bundles,
+		},
+		Timestamp: time.Now(),
+	}
+}
+
+// firstProducts returns at most n products, matching what a reply lists
+// noMatch answers a search whose filters left nothing. When only stock ruled
+// the matches out, it names them with in-stock alternatives and the offer to
+// notify the customer when they are back.
+func (pra *PenResearchAgent) noMatch(content string, opts SearchOptions, m money) models.Response {
+	wanted := describeTypes(opts.Types)
+
+	var outOfStock []ProductInfo
+	if opts.InStockOnly {
+		opts.InStockOnly = false
+		for _, result := range pra.index.Search(content, opts) {
+			outOfStock = append(outOfStock, result.Product)
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"products_found": 0,
+		"research_type":  "no_match",
+		"currency":       m.code,
+	}
+	if len(outOfStock) == 0 {
+		return models.Response{
+			AgentName:  pra.GetName(),
+			Content:    fmt.Sprintf("I couldn't find any %s in our catalogue matching your request. Try another pen type or brand, or tell me what you'll use it for and I'll suggest something.", wanted),
+			Confidence: 0.5,
+			Metadata:   metadata,
+			Timestamp:  time.Now(),
+		}
+	}
+
+	shown := firstProducts(outOfStock, 3)
+	alternatives := pra.stockAlternatives(shown, 0)
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("None of the %s matching your request are in stock right now:\n\n", wanted))
+	for _, product := range shown {
+		result.WriteString(fmt.Sprintf("**%s** (%s)%s\n", productName(product), m.format(product.Price), stockLabel(product)))
+		if alternative, ok := alternatives[product.ID]; ok {
+			result.WriteString(fmt.Sprintf("- In stock instead: **%s** (%s)\n", productName(alternative), m.format(alternative.Price)))
+		}
+		result.WriteString("- Want it anyway? Ask us to notify you when it's back in stock\n\n")
+	}
+
+	metadata["research_type"] = "out_of_stock"
+	metadata["products_shown"] = shown
+	metadata["alternatives"] = alternatives
+	return models.Response{
+		AgentName:  pra.GetName(),
+		Content:    strings.TrimRight(result.String(), "\n"),
+		Confidence: 0.6,
+		Metadata:   metadata,
+		Timestamp:  time.Now(),
+	}
+}
+
+// describeTypes names the requested pen types for a reply, e.g. "gel pens"
+func describeTypes(types map[string]bool) string {
+	if len(types) == 0 {
+		return "pens"
 	}
+	names := make([]string, 0, len(types))
+	for penType := range types {
+		names = append(names, strings.ToLower(penType)+"s")
+	}
+	sort.Strings(names)
+	return strings.Join(names, " or ")
+}
+
+func firstProducts(products []ProductInfo, n int) []ProductInfo {
+	if len(products) > n {
+		return products[:n]
+	}
+	return products
+}
 
-	for _, term := range searchTerms {
-		if strings.Contains(query, term) {
+// comparisonKeywords mark a query asking to weigh products against each other
+var comparisonKeywords = []string{"compare", "comparison", " vs ", " versus ", "difference between", "which is better"}
+
+func (pra *PenResearchAgent) isComparison(content string) bool {
+	for _, keyword := range comparisonKeywords {
+		if strings.Contains(content, keyword) {
 			return true
 		}
 	}
+	return false
+}
+
+// namedProducts returns the products mentioned by name or alias, in the
+// order they appear in the query
+func (pra *PenResearchAgent) namedProducts(content string) []ProductInfo {
+	var products []ProductInfo
+	for _, match := range pra.resolver.NamedProducts(content) {
+		products = append(products, match.Product)
+	}
+	return products
+}
+
+func (pra *PenResearchAgent) compareProducts(products []ProductInfo, m money) models.Response {
+	table := buildComparison(products, m)
+	pra.logger.Info("⚖️ Comparing %s", strings.Join(table.Products, " vs "))
 
-	// Check for general categories
-	if strings.Contains(query, "fountain") && product.Type == "Fountain Pen" {
-		return true
+	return models.Response{
+		AgentName:  pra.GetName(),
+		Content:    table.Markdown(),
+		Confidence: 0.9,
+		Metadata: map[string]interface{}{
+			"products_found": len(products),
+			"research_type":  "comparison",
+			"comparison":     table,
+			"currency":       m.code,
+			"products_shown": products,
+		},
+		Timestamp: time.Now(),
 	}
-	if strings.Contains(query, "ballpoint") && product.Type == "Ballpoint Pen" {
-		return true
+}
+
+// relevantKeys narrows the catalogue to what the user asked about. Products
+// named outright win over brand mentions, and only a correctly spelled brand
+// narrows the search, so "a marker pen" is not read as Parker. A query naming
+// nothing searches the whole catalogue and returns nil.
+func (pra *PenResearchAgent) relevantKeys(resolved []ResolvedProduct) map[string]bool {
+	keys := make(map[string]bool)
+	for _, match := range resolved {
+		if match.Score > 0.6 {
+			keys[match.Key] = true
+		}
 	}
-	if strings.Contains(query, "gel") && product.Type == "Gel Pen" {
-		return true
+	if len(keys) == 0 {
+		for _, match := range resolved {
+			if match.Exact {
+				keys[match.Key] = true
+			}
+		}
 	}
-	
-	// If no specific type mentioned, include all relevant products
-	if !strings.Contains(query, "fountain") && !strings.Contains(query, "ballpoint") && !strings.Contains(query, "gel") {
-		return true
+	if len(keys) == 0 {
+		return nil
 	}
+	return keys
+}
 
+// wantsInStockOnly reports whether the user only wants pens we can ship now
+func wantsInStockOnly(content string) bool {
+	for _, phrase := range []string{"in stock", "in-stock", "available now", "ships today", "ship today"} {
+		if strings.Contains(content, phrase) {
+			return true
+		}
+	}
 	return false
 }
 
-func (pra *PenResearchAgent) generateResearchResponse(products []ProductInfo, query string, budget float64, hasBudget bool) string {
+// penTypeNames maps the words customers use to catalogue pen types
+var penTypeNames = map[string]string{
+	"fountain":   "Fountain Pen",
+	"ballpoint":  "Ballpoint Pen",
+	"ballpen":    "Ballpoint Pen",
+	"biro":       "Ballpoint Pen",
+	"gel":        "Gel Pen",
+	"rollerball": "Rollerball Pen",
+	"roller":     "Rollerball Pen",
+}
+
+// requestedTypes returns the pen types mentioned in a query, tolerating typos
+// such as "fountian" and spellings such as "ball point"
+func requestedTypes(content string) map[string]bool {
+	tokens := normalizeTokens(content)
+	types := make(map[string]bool)
+
+	for i, token := range tokens {
+		candidates := []string{token}
+		if i+1 < len(tokens) {
+			candidates = append(candidates, token+tokens[i+1])
+		}
+		for _, candidate := range candidates {
+			for word, penType := range penTypeNames {
+				if tokenSimilarity(candidate, word) > 0 {
+					types[penType] = true
+				}
+			}
+		}
+	}
+	return types
+}
+
+// stockLabel marks products we cannot ship right now
+func stockLabel(p ProductInfo) string {
+	if p.InStock {
+		return ""
+	}
+	return " - out of stock"
+}
+
+// stockAlternatives suggests, for each out-of-stock product, the in-stock pen
+// closest in price that is not already listed and fits maxPrice when
+// positive, preferring pens of the same type. Results are keyed by the
+// out-of-stock product ID.
+func (pra *PenResearchAgent) stockAlternatives(shown []ProductInfo, maxPrice float64) map[string]ProductInfo {
+	listed := make(map[string]bool)
+	for _, product := range shown {
+		listed[product.ID] = true
+	}
+
+	alternatives := make(map[string]ProductInfo)
+	for _, product := range shown {
+		if product.InStock {
+			continue
+		}
+
+		var sameType, anyType ProductInfo
+		closer := func(candidate, best ProductInfo) bool {
+			if best.ID == "" {
+				return true
+			}
+			diff, bestDiff := math.Abs(candidate.Price-product.Price), math.Abs(best.Price-product.Price)
+			return diff < bestDiff || (diff == bestDiff && candidate.ID < best.ID)
+		}
+		for _, candidate := range pra.productDatabase {
+			candidate = pra.current(candidate)
+			if !candidate.InStock || listed[candidate.ID] {
+				continue
+			}
+			if maxPrice > 0 && candidate.Price > maxPrice {
+				continue
+			}
+			if candidate.Type == product.Type && closer(candidate, sameType) {
+				sameType = candidate
+			}
+			if closer(candidate, anyType) {
+				anyType = candidate
+			}
+		}
+
+		switch {
+		case sameType.ID != "":
+			alternatives[product.ID] = sameType
+		case anyType.ID != "":
+			alternatives[product.ID] = anyType
+		}
+	}
+	return alternatives
+}
+
+func (pra *PenResearchAgent) generateResearchResponse(products []ProductInfo, alternatives map[string]ProductInfo, m money, budget currency.Amount, hasBudget bool) string {
 	if len(products) == 0 {
-		return "I found several pen options that might interest you, but let me check our full catalog for the best matches to your specific requirements."
+		return "I couldn't find any pens in our catalogue matching your request."
 	}
 
 	var result strings.Builder
-	
+
 	if hasBudget {
-		result.WriteString(fmt.Sprintf("Here are the best options under $%.2f:\n\n", budget))
+		result.WriteString(fmt.Sprintf("Here are the best options under %s:\n\n", budget))
 	} else {
 		result.WriteString("Here's what I found:\n\n")
 	}
@@ -263,13 +659,20 @@ func (pra *PenResearchAgent) generateResearchResponse(products []ProductInfo, qu
 			break
 		}
 
-		result.WriteString(fmt.Sprintf("**%s %s** ($%.2f)\n", product.Brand, product.Model, product.Price))
+		result.WriteString(fmt.Sprintf("**%s %s** (%s)%s\n", product.Brand, product.Model, m.format(product.Price), stockLabel(product)))
 		result.WriteString(fmt.Sprintf("- Type: %s\n", product.Type))
 		result.WriteString(fmt.Sprintf("- Materials: %s\n", strings.Join(product.Materials, ", ")))
 		if len(product.NibSizes) > 0 {
 			result.WriteString(fmt.Sprintf("- Sizes: %s\n", strings.Join(product.NibSizes, ", ")))
 		}
-		result.WriteString(fmt.Sprintf("- Key Features: %s\n\n", strings.Join(product.Features, ", ")))
+		result.WriteString(fmt.Sprintf("- Key Features: %s\n", strings.Join(product.Features, ", ")))
+		if !product.InStock {
+			if alternative, ok := alternatives[product.ID]; ok {
+				result.WriteString(fmt.Sprintf("- In stock instead: **%s** (%s)\n", productName(alternative), m.format(alternative.Price)))
+			}
+			result.WriteString("- Want it anyway? Ask us to notify you when it's back in stock\n")
+		}
+		result.WriteString("\n")
 	}
 
 	return result.String()
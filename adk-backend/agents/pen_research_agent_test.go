@@ -0,0 +1,46 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"pen-shop/models"
+)
+
+func research(t *testing.T, content string) models.Response {
+	t.Helper()
+	response, err := NewPenResearchAgent(nopLogger{}).Process(context.Background(), models.Query{Content: content})
+	if err != nil {
+		t.Fatalf("Process(%q): %v", content, err)
+	}
+	return response
+}
+
+func TestResearchOutOfStockOnly(t *testing.T) {
+	// The only gel pen, the G2 Premium, is out of stock
+	response := research(t, "show me gel pens in stock")
+
+	if strings.Contains(response.Content, "I found several") {
+		t.Fatalf("claimed to find pens that were filtered out:\n%s", response.Content)
+	}
+	for _, want := range []string{"None of the gel pens", "Pilot G2 Premium", "out of stock", "In stock instead", "notify you"} {
+		if !strings.Contains(response.Content, want) {
+			t.Errorf("response is missing %q:\n%s", want, response.Content)
+		}
+	}
+	if got := response.Metadata["research_type"]; got != "out_of_stock" {
+		t.Errorf("research_type = %v, want out_of_stock", got)
+	}
+}
+
+func TestResearchNoMatchingType(t *testing.T) {
+	response := research(t, "do you sell rollerball pens?")
+
+	if !strings.Contains(response.Content, "couldn't find any rollerball pens") {
+		t.Errorf("response does not admit there is no match:\n%s", response.Content)
+	}
+	if got := response.Metadata["products_found"]; got != 0 {
+		t.Errorf("products_found = %v, want 0", got)
+	}
+}
@@ -2,9 +2,14 @@ package agents
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"pen-shop/clock"
+	"pen-shop/currency"
 	"pen-shop/models"
-	"regexp"
-	"strconv"
+	"pen-shop/pricehistory"
+	"pen-shop/promotions"
+	"sort"
 	"strings"
 	"time"
 )
@@ -12,9 +17,16 @@ import (
 // PriceResearchAgent handles budget and pricing analysis
 type PriceResearchAgent struct {
 	*BaseAgent
-	logger models.Logger
+	promotions   *promotions.Engine
+	catalogue    map[string]ProductInfo
+	priceHistory pricehistory.Store
+	clock        clock.Clock
+	logger       models.Logger
 }
 
+// priceHistoryWindow is how far back trend answers look
+const priceHistoryWindow = 90 * 24 * time.Hour
+
 func NewPriceResearchAgent(logger models.Logger) *PriceResearchAgent {
 	return &PriceResearchAgent{
 		BaseAgent: NewBaseAgent("price_research", []string{"pricing", "budget", "deals"}, 2),
@@ -22,11 +34,32 @@ func NewPriceResearchAgent(logger models.Logger) *PriceResearchAgent {
 	}
 }
 
+// SetPromotions enables deal lookups against the promotion rules
+func (pra *PriceResearchAgent) SetPromotions(engine *promotions.Engine) {
+	pra.promotions = engine
+}
+
+// SetCatalogue lets budget suggestions check stock; products are keyed as in
+// PenResearchAgent.Products
+func (pra *PriceResearchAgent) SetCatalogue(products map[string]ProductInfo) {
+	pra.catalogue = products
+}
+
+// SetPriceHistory enables "is this a good price" answers from recorded prices
+func (pra *PriceResearchAgent) SetPriceHistory(store pricehistory.Store, clk clock.Clock) {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	pra.priceHistory = store
+	pra.clock = clk
+}
+
 func (pra *PriceResearchAgent) CanHandle(query models.Query) float64 {
 	content := strings.ToLower(query.Content)
 	priceKeywords := []string{
 		"price", "cost", "budget", "cheap", "expensive", "affordable",
-		"deal", "discount", "sale", "under", "less than", "$",
+		"deal", "discount", "sale", "under", "less than", "$", "€", "£", "¥",
+		"euro", "pound", "yen", "good price", "price history", "trend",
 	}
 
 	for _, keyword := range priceKeywords {
@@ -37,30 +70,17 @@ func (pra *PriceResearchAgent) CanHandle(query models.Query) float64 {
 	return 0.4
 }
 
-// Extract budget constraints from the query
-func (pra *PriceResearchAgent) extractBudget(content string) (float64, bool) {
-	// Look for patterns like "under $10", "$10", "less than $50", etc.
-	patterns := []string{
-		`under\s*\$(\d+)`,
-		`less\s+than\s*\$(\d+)`,
-		`below\s*\$(\d+)`,
-		`\$(\d+)\s*or\s*less`,
-		`\$(\d+)\s*max`,
-		`maximum\s*\$(\d+)`,
-		`\$(\d+)`,
+// Extract budget constraints from the query, e.g. "under $10", "£100" or
+// "80 euros", in whichever currency the customer used
+func (pra *PriceResearchAgent) extractBudget(content string) (currency.Amount, bool) {
+	budget, ok := currency.ParseBudget(content)
+	if !ok {
+		budget, ok = currency.ParseAmount(content)
 	}
-	
-	for _, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
-		if matches := re.FindStringSubmatch(content); len(matches) > 1 {
-			if budget, err := strconv.ParseFloat(matches[1], 64); err == nil {
-				pra.logger.Info("💰 Extracted budget constraint: $%.2f", budget)
-				return budget, true
-			}
-		}
+	if ok {
+		pra.logger.Info("💰 Extracted budget constraint: %s", budget)
 	}
-	
-	return 0, false
+	return budget, ok
 }
 
 func (pra *PriceResearchAgent) Process(ctx context.Context, query models.Query) (models.Response, error) {
@@ -69,62 +89,353 @@ func (pra *PriceResearchAgent) Process(ctx context.Context, query models.Query)
 	content := strings.ToLower(query.Content)
 	
 	// Extract specific budget if mentioned
-	budget, hasBudget := pra.extractBudget(content)
+	budgetAmount, hasBudget := pra.extractBudget(content)
+	m := pra.moneyFor(query, budgetAmount.Currency)
+
+	// Budget tiers are defined in catalogue dollars
+	var budget float64
+	if hasBudget {
+		budget, hasBudget = m.toUSD(budgetAmount)
+	}
 	
 	var response string
 	if hasBudget {
-		response = pra.analyzeBudgetConstraint(budget)
+		response = pra.analyzeBudgetConstraint(budget, budgetAmount, m)
 	} else {
-		response = pra.analyzeGeneralBudget(content)
+		response = pra.analyzeGeneralBudget(content, m)
+	}
+
+	// Trend questions such as "is this a good price?" lead with recorded history
+	var trends []pricehistory.Trend
+	if pra.asksPriceTrend(content) {
+		var report string
+		report, trends = pra.priceTrendReport(ctx, pra.productsUnderDiscussion(query), m)
+		if report != "" {
+			response = report + "\n\n" + response
+		}
+	}
+
+	// Pen research builds bundles when the budget covers accessories too
+	bundles := penResearchValue[[]Bundle](query, "bundles")
+	if hasBudget && len(bundles) > 0 {
+		response = pra.presentBundles(bundles, budgetAmount, m) + "\n\n" + response
 	}
 	
-	deals := pra.findCurrentDeals()
-	finalResponse := response + "\n\n" + deals
+	products := pra.productsUnderDiscussion(query)
+	deals, quotes := pra.findCurrentDeals(ctx, products, strings.Fields(query.Content), m)
+	finalResponse := response
+	if deals != "" {
+		finalResponse += "\n\n" + deals
+	}
 
 	return models.Response{
 		AgentName:  pra.GetName(),
 		Content:    finalResponse,
 		Confidence: 0.75,
 		Metadata: map[string]interface{}{
-			"budget_analysis":    true,
-			"specific_budget":    hasBudget,
-			"budget_amount":      budget,
-			"deals_found":        len(strings.Split(deals, "\n")) > 1,
+			"budget_analysis":   true,
+			"specific_budget":   hasBudget,
+			"budget_amount":     budget,
+			"budget":            budgetAmount,
+			"currency":          m.code,
+			"deals_found":       deals != "",
+			"promotions":        quotes,
+			"bundles_presented": len(bundles),
+			"price_trends":      trends,
 		},
 		Timestamp: time.Now(),
 	}, nil
 }
 
-func (pra *PriceResearchAgent) analyzeBudgetConstraint(budget float64) string {
+// budgetPick is one suggestion in a budget tier; key is the catalogue key
+// when the pen is one we stock, so its availability can be checked
+type budgetPick struct {
+	key  string
+	line string
+}
+
+// listPicks renders a tier's suggestions with in-stock pens first and
+// unavailable ones labelled
+func (pra *PriceResearchAgent) listPicks(picks []budgetPick) string {
+	sort.SliceStable(picks, func(i, j int) bool {
+		return pra.available(picks[i].key) && !pra.available(picks[j].key)
+	})
+
+	lines := make([]string, len(picks))
+	for i, pick := range picks {
+		lines[i] = "• " + pick.line + pra.stockNote(pick.key)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// available treats pens outside the catalogue as available; their stock is
+// not tracked here
+func (pra *PriceResearchAgent) available(key string) bool {
+	product, ok := pra.catalogue[key]
+	return !ok || pra.current(product).InStock
+}
+
+func (pra *PriceResearchAgent) stockNote(key string) string {
+	if pra.available(key) {
+		return ""
+	}
+	return " (currently out of stock - ask us to notify you when it's back)"
+}
+
+func (pra *PriceResearchAgent) analyzeBudgetConstraint(budget float64, stated currency.Amount, m money) string {
+	heading := fmt.Sprintf("**Budget Under %s:**\n", stated)
 	if budget <= 15 {
 		if budget <= 10 {
-			return "**Budget Under $10:**\nI need to be honest - quality fountain pens under $10 are very limited. However, you might find:\n• Pilot G2 Premium Gel Pen ($12.50) - slightly over budget but excellent value\n• Basic ballpoint pens from Parker Jotter series start around $8-12\n• Consider looking at refillable gel pens or quality ballpoints in this range"
+			return heading + "I need to be honest - quality fountain pens at this price are very limited. However, you might find:\n" + pra.listPicks([]budgetPick{
+				{"pilot_g2_premium", fmt.Sprintf("Pilot G2 Premium Gel Pen (%s) - slightly over budget but excellent value", m.format(12.50))},
+				{"", fmt.Sprintf("Basic ballpoint pens from Parker Jotter series start around %s", m.span(8, 12))},
+				{"", "Consider looking at refillable gel pens or quality ballpoints in this range"},
+			})
 		} else {
-			return "**Budget Under $15:**\n• Pilot G2 Premium ($12.50) - excellent gel writing experience\n• Basic Parker Jotter models ($8-15) - reliable ballpoint option\n• Quality gel pens and fine-tip markers in this range"
+			return heading + pra.listPicks([]budgetPick{
+				{"pilot_g2_premium", fmt.Sprintf("Pilot G2 Premium (%s) - excellent gel writing experience", m.format(12.50))},
+				{"", fmt.Sprintf("Basic Parker Jotter models (%s) - reliable ballpoint option", m.span(8, 15))},
+				{"", "Quality gel pens and fine-tip markers in this range"},
+			})
 		}
 	} else if budget <= 50 {
-		return "**Budget Under $50:**\n• Pilot G2 Premium ($12.50) - premium gel pen\n• Parker Jotter Premium ($46) - classic reliable ballpoint\n• Cross Classic Century ($35-45) - professional appearance\n• Quality rollerball options available"
+		return heading + pra.listPicks([]budgetPick{
+			{"pilot_g2_premium", fmt.Sprintf("Pilot G2 Premium (%s) - premium gel pen", m.format(12.50))},
+			{"parker_jotter_premium", fmt.Sprintf("Parker Jotter Premium (%s) - classic reliable ballpoint", m.format(46))},
+			{"", fmt.Sprintf("Cross Classic Century (%s) - professional appearance", m.span(35, 45))},
+			{"", "Quality rollerball options available"},
+		})
 	} else if budget <= 100 {
-		return "**Budget Under $100:**\n• Parker Urban Premium ($90) - sleek modern design\n• Cross Century Classic ($75) - timeless professional look\n• Waterman Graduate series ($60-85) - entry-level fountain pens\n• Premium gel and rollerball options"
+		return heading + pra.listPicks([]budgetPick{
+			{"", fmt.Sprintf("Parker Urban Premium (%s) - sleek modern design", m.format(90))},
+			{"cross_century_classic", fmt.Sprintf("Cross Century Classic (%s) - timeless professional look", m.format(75))},
+			{"", fmt.Sprintf("Waterman Graduate series (%s) - entry-level fountain pens", m.span(60, 85))},
+			{"", "Premium gel and rollerball options"},
+		})
 	} else if budget <= 200 {
-		return "**Budget Under $200:**\n• Parker Sonnet ($125) - classic fountain pen design\n• Pilot Vanishing Point ($165) - unique retractable fountain pen\n• Waterman Expert ($180) - reliable daily writer\n• Cross Townsend series ($150-180)"
+		return heading + pra.listPicks([]budgetPick{
+			{"parker_sonnet", fmt.Sprintf("Parker Sonnet (%s) - classic fountain pen design", m.format(125))},
+			{"pilot_vanishing_point", fmt.Sprintf("Pilot Vanishing Point (%s) - unique retractable fountain pen", m.format(165))},
+			{"", fmt.Sprintf("Waterman Expert (%s) - reliable daily writer", m.format(180))},
+			{"", fmt.Sprintf("Cross Townsend series (%s)", m.span(150, 180))},
+		})
 	} else {
-		return "**Premium Budget ($200+):**\n• Montblanc Meisterstück series (starts around $400)\n• High-end Parker and Waterman collections\n• Luxury fountain pens with gold nibs\n• Limited edition and collector pieces"
+		return fmt.Sprintf("**Premium Budget (%s+):**\n", m.format(200)) + pra.listPicks([]budgetPick{
+			{"montblanc_meisterstuck_149", fmt.Sprintf("Montblanc Meisterstück series (starts around %s)", m.format(400))},
+			{"", "High-end Parker and Waterman collections"},
+			{"", "Luxury fountain pens with gold nibs"},
+			{"", "Limited edition and collector pieces"},
+		})
 	}
 }
 
-func (pra *PriceResearchAgent) analyzeGeneralBudget(query string) string {
+func (pra *PriceResearchAgent) analyzeGeneralBudget(query string, m money) string {
 	if strings.Contains(query, "cheap") || strings.Contains(query, "budget") {
-		return "**Budget-Friendly Options ($12-$50):**\nPilot G2 Premium ($12.50) and Parker Jotter Premium ($46) offer excellent value."
+		if !pra.available("pilot_g2_premium") {
+			return fmt.Sprintf("**Budget-Friendly Options (%s):**\nParker Jotter Premium (%s) offers excellent value. The Pilot G2 Premium (%s) is currently out of stock.", m.span(12, 50), m.format(46), m.format(12.50))
+		}
+		return fmt.Sprintf("**Budget-Friendly Options (%s):**\nPilot G2 Premium (%s) and Parker Jotter Premium (%s) offer excellent value.", m.span(12, 50), m.format(12.50), m.format(46))
 	} else if strings.Contains(query, "affordable") {
-		return "**Affordable Options ($50-$100):**\nCross Century Classic ($75) and Parker Urban Premium ($90) provide premium feel without breaking the bank."
+		return fmt.Sprintf("**Affordable Options (%s):**\nCross Century Classic (%s) and Parker Urban Premium (%s) provide premium feel without breaking the bank.", m.span(50, 100), m.format(75), m.format(90))
 	} else if strings.Contains(query, "luxury") || strings.Contains(query, "premium") {
-		return "**Luxury Investment ($200+):**\nMontblanc and premium Parker models offer lifetime value and prestige."
+		return fmt.Sprintf("**Luxury Investment (%s+):**\nMontblanc and premium Parker models offer lifetime value and prestige.", m.format(200))
 	}
 
-	return "**Price Range Analysis:**\n• Entry Level: $12-$50\n• Mid-Range: $50-$150\n• Premium: $150-$300\n• Luxury: $300+"
+	return fmt.Sprintf("**Price Range Analysis:**\n• Entry Level: %s\n• Mid-Range: %s\n• Premium: %s\n• Luxury: %s+", m.span(12, 50), m.span(50, 150), m.span(150, 300), m.format(300))
 }
 
-func (pra *PriceResearchAgent) findCurrentDeals() string {
-	return "**Current Promotions:**\n• Free shipping on orders over $75\n• 10% off Parker collection this month\n• Student discounts available with ID"
+// asksPriceTrend reports whether the customer wants to know how today's price
+// compares with the past
+func (pra *PriceResearchAgent) asksPriceTrend(content string) bool {
+	phrases := []string{
+		"good price", "good deal", "price history", "historically", "trend",
+		"lowest price", "cheapest it", "ever been", "price drop", "dropped",
+		"gone down", "gone up", "wait for", "worth buying now",
+	}
+	for _, phrase := range phrases {
+		if strings.Contains(content, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// priceTrendReport summarises recorded prices for the products under
+// discussion and says whether now is a good time to buy
+func (pra *PriceResearchAgent) priceTrendReport(ctx context.Context, products []ProductInfo, m money) (string, []pricehistory.Trend) {
+	if pra.priceHistory == nil || len(products) == 0 {
+		return "", nil
+	}
+
+	since := pra.clock.Now().Add(-priceHistoryWindow)
+	var result strings.Builder
+	var trends []pricehistory.Trend
+	for _, product := range firstProducts(products, 2) {
+		snapshots, err := pra.priceHistory.History(ctx, product.ID, since)
+		if err != nil {
+			pra.logger.Error("Failed to load price history for %s: %v", product.ID, err)
+			continue
+		}
+		trend, ok := pricehistory.Analyze(snapshots)
+		if !ok {
+			continue
+		}
+		trends = append(trends, trend)
+
+		result.WriteString(fmt.Sprintf("**Price history for %s (since %s):**\n", productName(product), trend.Since.Format("Jan 2")))
+		result.WriteString(fmt.Sprintf("• Now: %s\n", m.format(trend.CurrentUSD)))
+		result.WriteString(fmt.Sprintf("• Lowest: %s (%s), highest: %s (%s), average: %s\n",
+			m.format(trend.MinUSD), trend.MinAt.Format("Jan 2"),
+			m.format(trend.MaxUSD), trend.MaxAt.Format("Jan 2"), m.format(trend.AverageUSD)))
+		if drop := trend.RecentDrop; drop != nil {
+			result.WriteString(fmt.Sprintf("• Most recent drop: %s → %s on %s (%.1f%%)\n",
+				m.format(drop.FromUSD), m.format(drop.ToUSD), drop.At.Format("Jan 2"), drop.Percent))
+		}
+
+		switch trend.Verdict() {
+		case pricehistory.VerdictLowest:
+			result.WriteString("• Verdict: this is the lowest price we've recorded - a great time to buy\n\n")
+		case pricehistory.VerdictGood:
+			result.WriteString("• Verdict: below its usual price - a good time to buy\n\n")
+		case pricehistory.VerdictHigh:
+			result.WriteString("• Verdict: above its usual price - it may be worth waiting for a promotion\n\n")
+		default:
+			result.WriteString("• Verdict: about its usual price\n\n")
+		}
+	}
+
+	return strings.TrimSuffix(result.String(), "\n\n"), trends
+}
+
+// penResearchMetadata returns the metadata pen research produced earlier in
+// the workflow, or nil when it has not run
+func penResearchMetadata(query models.Query) map[string]interface{} {
+	workflowData, ok := query.Context["workflow_context"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	metadata, ok := workflowData["Metadata"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	research, _ := metadata["pen_research"].(map[string]interface{})
+	return research
+}
+
+// productsUnderDiscussion returns the products pen research showed the customer
+func (pra *PriceResearchAgent) productsUnderDiscussion(query models.Query) []ProductInfo {
+	return shownProducts(query)
+}
+
+// shownProducts returns the products pen research listed earlier in the workflow
+func shownProducts(query models.Query) []ProductInfo {
+	return penResearchValue[[]ProductInfo](query, "products_shown")
+}
+
+// penResearchValue reads one pen research metadata value as T. Metadata
+// restored from a workflow checkpoint has been through JSON, so values that
+// are no longer a T are decoded again.
+func penResearchValue[T any](query models.Query, key string) T {
+	var value T
+	raw, ok := penResearchMetadata(query)[key]
+	if !ok || raw == nil {
+		return value
+	}
+	if typed, ok := raw.(T); ok {
+		return typed
+	}
+	if data, err := json.Marshal(raw); err == nil {
+		json.Unmarshal(data, &value)
+	}
+	return value
+}
+
+// presentBundles lays out each bundle with its total-price breakdown
+func (pra *PriceResearchAgent) presentBundles(bundles []Bundle, stated currency.Amount, m money) string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("**Bundles within your %s budget:**\n", stated))
+
+	for i, bundle := range bundles {
+		result.WriteString(fmt.Sprintf("%d. **%s bundle** - %s total, %s to spare\n",
+			i+1, bundle.Pen, m.format(bundle.Total), m.remaining(stated, bundle.Total)))
+		for _, item := range bundle.Items {
+			result.WriteString(fmt.Sprintf("   • %s: %s\n", item.Name, m.format(item.Price)))
+		}
+		if len(bundle.Missing) > 0 {
+			result.WriteString(fmt.Sprintf("   • No in-stock %s for this pen fits the budget\n", strings.Join(bundle.Missing, " or ")))
+		}
+	}
+	return strings.TrimSuffix(result.String(), "\n")
+}
+
+// findCurrentDeals lists the promotions active today that apply to the
+// products under discussion, plus storewide free shipping. Words in the
+// query are matched against discount codes, so "code STUDENT10" applies it.
+func (pra *PriceResearchAgent) findCurrentDeals(ctx context.Context, products []ProductInfo, words []string, m money) (string, []promotions.Quote) {
+	if pra.promotions == nil {
+		return "", nil
+	}
+
+	rules, err := pra.promotions.Active(ctx)
+	if err != nil {
+		pra.logger.Error("Failed to load promotions: %v", err)
+	}
+
+	codes := make([]string, len(words))
+	for i, word := range words {
+		codes[i] = strings.Trim(word, ".,!?;:\"'()")
+	}
+
+	var lines []string
+	var quotes []promotions.Quote
+	storewideCodes := make(map[string]promotions.Rule)
+	for _, product := range products {
+		quote := promotions.Evaluate(rules, promotions.Product{
+			SKU:      product.ID,
+			Brand:    product.Brand,
+			Type:     product.Type,
+			PriceUSD: product.Price,
+		}, codes)
+
+		if quote.Applied != nil || len(quote.WithCode) > 0 {
+			quotes = append(quotes, quote)
+		}
+		if quote.Applied != nil {
+			lines = append(lines, fmt.Sprintf("• %s: %s%s - now %s (was %s)",
+				productName(product), promotions.Describe(*quote.Applied), promotionEnds(*quote.Applied),
+				m.format(quote.EffectiveUSD), m.format(quote.OriginalUSD)))
+		}
+		for _, rule := range quote.WithCode {
+			if rule.Storewide() {
+				storewideCodes[rule.ID] = rule
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("• %s: %s with code %s%s",
+				productName(product), promotions.Describe(rule), rule.Code, promotionEnds(rule)))
+		}
+	}
+
+	// Rules are sorted by ID, so storewide codes are listed in a stable order
+	for _, rule := range rules {
+		if _, ok := storewideCodes[rule.ID]; ok {
+			lines = append(lines, fmt.Sprintf("• %s with code %s%s", promotions.Describe(rule), rule.Code, promotionEnds(rule)))
+		}
+	}
+
+	if shipping, ok := promotions.FreeShipping(rules); ok {
+		lines = append(lines, fmt.Sprintf("• Free shipping on orders over %s%s", m.format(shipping.MinOrderUSD), promotionEnds(shipping)))
+	}
+
+	if len(lines) == 0 {
+		return "", quotes
+	}
+	return "**Current Promotions:**\n" + strings.Join(lines, "\n"), quotes
+}
+
+// promotionEnds notes when a limited-time promotion finishes
+func promotionEnds(rule promotions.Rule) string {
+	if rule.EndsAt == nil {
+		return ""
+	}
+	return " (ends " + rule.EndsAt.Format("Jan 2") + ")"
 }
@@ -0,0 +1,64 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"pen-shop/models"
+)
+
+// checkpointedQuery carries pen research metadata the way a resumed workflow
+// sees it: restored from JSON rather than as the original Go values
+func checkpointedQuery(t *testing.T, content string, research map[string]interface{}) models.Query {
+	t.Helper()
+	data, err := json.Marshal(research)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored map[string]interface{}
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	return models.Query{
+		Content: content,
+		Context: map[string]interface{}{
+			"workflow_context": map[string]interface{}{
+				"Metadata": map[string]interface{}{"pen_research": restored},
+			},
+		},
+	}
+}
+
+func TestPriceResearchReadsCheckpointedMetadata(t *testing.T) {
+	catalogue := Catalogue()
+	sonnet := catalogue["parker_sonnet"]
+	query := checkpointedQuery(t, "a fountain pen and ink under $150", map[string]interface{}{
+		"products_shown": []ProductInfo{sonnet},
+		"bundles": []Bundle{{
+			Pen:       productName(sonnet),
+			Items:     []BundleItem{{ID: sonnet.ID, Name: productName(sonnet), Kind: "pen", Price: 125}, {ID: "ink-1", Name: "Blue ink", Kind: "ink", Price: 15}},
+			Total:     140,
+			Remaining: 10,
+		}},
+	})
+
+	if shown := shownProducts(query); len(shown) != 1 || shown[0].ID != sonnet.ID {
+		t.Errorf("shownProducts = %+v, want the Sonnet", shown)
+	}
+
+	response, err := NewPriceResearchAgent(nopLogger{}).Process(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if !strings.Contains(response.Content, "Parker Sonnet bundle") || !strings.Contains(response.Content, "Blue ink") {
+		t.Errorf("response left out the checkpointed bundle:\n%s", response.Content)
+	}
+}
+
+func TestPenResearchValueMissing(t *testing.T) {
+	if bundles := penResearchValue[[]Bundle](models.Query{}, "bundles"); bundles != nil {
+		t.Errorf("bundles = %+v, want nil without research metadata", bundles)
+	}
+}
@@ -2,6 +2,10 @@ package agents
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"pen-shop/currency"
+	"pen-shop/llm"
 	"pen-shop/models"
 	"strings"
 	"time"
@@ -10,7 +14,8 @@ import (
 // RecommendationAgent provides personalized pen recommendations
 type RecommendationAgent struct {
 	*BaseAgent
-	logger models.Logger
+	semantic *SemanticIndex
+	logger   models.Logger
 }
 
 func NewRecommendationAgent(logger models.Logger) *RecommendationAgent {
@@ -20,6 +25,25 @@ func NewRecommendationAgent(logger models.Logger) *RecommendationAgent {
 	}
 }
 
+// SetSemanticIndex lets recommendations draw on embedding-based retrieval
+func (ra *RecommendationAgent) SetSemanticIndex(semantic *SemanticIndex) {
+	ra.semantic = semantic
+}
+
+// SimilarProducts returns the k catalogue products closest in meaning to
+// query with their current availability, or nil when no semantic index is
+// configured
+func (ra *RecommendationAgent) SimilarProducts(ctx context.Context, query string, k int) ([]SimilarProduct, error) {
+	if ra.semantic == nil {
+		return nil, nil
+	}
+	similar, err := ra.semantic.SimilarProducts(ctx, query, k)
+	for i := range similar {
+		similar[i].Product = ra.current(similar[i].Product)
+	}
+	return similar, err
+}
+
 func (ra *RecommendationAgent) CanHandle(query models.Query) float64 {
 	content := strings.ToLower(query.Content)
 	recommendKeywords := []string{
@@ -35,8 +59,52 @@ func (ra *RecommendationAgent) CanHandle(query models.Query) float64 {
 	return 0.6
 }
 
+// RecommendationMode chooses who writes a recommendation
+type RecommendationMode string
+
+const (
+	// RecommendHybrid has the model write it, falling back to the rules
+	RecommendHybrid RecommendationMode = "hybrid"
+	// RecommendRules only uses the rule-based recommendation
+	RecommendRules RecommendationMode = "rules"
+	// RecommendModel only uses the model and fails without one
+	RecommendModel RecommendationMode = "model"
+)
+
 func (ra *RecommendationAgent) Process(ctx context.Context, query models.Query) (models.Response, error) {
-	ra.logger.Info("🎯 Generating recommendations for: %s", query.Content)
+	return ra.recommend(ctx, query, ra.GetName(), RecommendHybrid)
+}
+
+// Variant is this agent restricted to one mode, named recommend_<mode>, so a
+// ParallelAgent can run the rule-based and model recommendations side by side.
+// It shares the agent's provider, rates and semantic index.
+func (ra *RecommendationAgent) Variant(mode RecommendationMode) models.Agent {
+	return &recommendationVariant{
+		BaseAgent: NewBaseAgent("recommend_"+string(mode), ra.GetCapabilities(), ra.GetPriority()),
+		agent:     ra,
+		mode:      mode,
+	}
+}
+
+type recommendationVariant struct {
+	*BaseAgent
+	agent *RecommendationAgent
+	mode  RecommendationMode
+}
+
+func (rv *recommendationVariant) CanHandle(query models.Query) float64 {
+	return rv.agent.CanHandle(query)
+}
+
+func (rv *recommendationVariant) Process(ctx context.Context, query models.Query) (models.Response, error) {
+	return rv.agent.recommend(ctx, query, rv.GetName(), rv.mode)
+}
+
+// recommend writes a recommendation in mode. Rule-based answers are less
+// confident than model ones, and the generic fallback that names no pen
+// least of all.
+func (ra *RecommendationAgent) recommend(ctx context.Context, query models.Query, name string, mode RecommendationMode) (models.Response, error) {
+	ra.logger.Info("🎯 Generating %s recommendations for: %s", mode, query.Content)
 
 	// Get context from previous agents - using interface{} to avoid circular import
 	var researchResults string
@@ -56,42 +124,194 @@ func (ra *RecommendationAgent) Process(ctx context.Context, query models.Query)
 		}
 	}
 
-	recommendation := ra.generatePersonalizedRecommendation(query.Content, researchResults, priceResults)
+	similar, err := ra.SimilarProducts(ctx, query.Content, 3)
+	if err != nil {
+		ra.logger.Error("Semantic search failed: %v", err)
+	}
+
+	mentioned, _ := currency.ParseAmount(query.Content)
+	m := ra.moneyFor(query, mentioned.Currency)
+
+	// A LoopAgent passes back what its critic objected to in the last draft
+	critique, _ := query.Context["critique"].([]string)
+
+	recommendation, specific := ra.generatePersonalizedRecommendation(query.Content, researchResults, priceResults, similar, m)
+	if len(critique) > 0 {
+		if revised, ok := ra.revisedRecommendation(query, m); ok {
+			recommendation, specific = revised, true
+		}
+	}
+	metadata := map[string]interface{}{
+		"recommendation_type": "personalized",
+		"used_research_data":  researchResults != "",
+		"used_price_data":     priceResults != "",
+		"similar_products":    similar,
+		"currency":            m.code,
+		"revision":            len(critique) > 0,
+	}
+
+	confidence := 0.85
+	switch mode {
+	case RecommendRules:
+		confidence = 0.8
+		if !specific {
+			confidence = 0.5
+		}
+	default:
+		// Prefer a model-written recommendation, keeping the rule-based one as fallback
+		resp, err := ra.synthesizeWithModel(ctx, query.Content, researchResults, priceResults, similar, critique, m)
+		switch {
+		case err == nil:
+			recommendation = resp.Content
+			metadata["recommendation_type"] = "llm_synthesized"
+			metadata["llm"] = llmMetadata(resp)
+		case mode == RecommendModel:
+			return models.Response{}, fmt.Errorf("model recommendation: %w", err)
+		case !errors.Is(err, llm.ErrNoProvider):
+			ra.logger.Error("Model synthesis failed, using rule-based recommendation: %v", err)
+		}
+	}
 
 	return models.Response{
-		AgentName:  ra.GetName(),
+		AgentName:  name,
 		Content:    recommendation,
-		Confidence: 0.85,
-		Metadata: map[string]interface{}{
-			"recommendation_type": "personalized",
-			"used_research_data":  researchResults != "",
-			"used_price_data":     priceResults != "",
-		},
-		Timestamp: time.Now(),
+		Confidence: confidence,
+		Metadata:   metadata,
+		Timestamp:  time.Now(),
 	}, nil
 }
 
-func (ra *RecommendationAgent) generatePersonalizedRecommendation(query, research, pricing string) string {
+func (ra *RecommendationAgent) synthesizeWithModel(ctx context.Context, query, research, pricing string, similar []SimilarProduct, critique []string, m money) (llm.CompletionResponse, error) {
+	var prompt strings.Builder
+	prompt.WriteString("Customer question: " + query + "\n\n")
+	if research != "" {
+		prompt.WriteString("Product research:\n" + research + "\n\n")
+	}
+	if len(similar) > 0 {
+		prompt.WriteString("Closest matches to the customer's description:\n")
+		for _, match := range similar {
+			prompt.WriteString(fmt.Sprintf("- %s (%s)%s: %s\n", match.Name, m.format(match.Product.Price), stockLabel(match.Product), match.Product.Description))
+		}
+		prompt.WriteString("\n")
+	}
+	if pricing != "" {
+		prompt.WriteString("Pricing analysis:\n" + pricing + "\n\n")
+	}
+	prompt.WriteString("Write a short, friendly recommendation that only mentions pens from the research above. Recommend in-stock pens first; if you mention an out-of-stock pen, say so and offer to notify the customer when it's back. Quote prices in " + m.code + " exactly as given.")
+	if len(critique) > 0 {
+		prompt.WriteString("\n\nA reviewer rejected your previous draft. Fix every point:\n- " + strings.Join(critique, "\n- "))
+	}
+
+	resp, err := ra.complete(ctx, "You are a pen expert writing the final recommendation for a customer.", prompt.String())
+	if err != nil {
+		return resp, err
+	}
+	if resp.Content == "" {
+		return resp, errors.New("empty model response")
+	}
+	return resp, nil
+}
+
+// revisedRecommendation recommends the pen pen research showed that best fits
+// the budget: the cheapest in-stock pen within it, otherwise the cheapest pen
+// shown, said to be over budget
+func (ra *RecommendationAgent) revisedRecommendation(query models.Query, m money) (string, bool) {
+	// Stock may have changed since pen research ran
+	var shown []ProductInfo
+	for _, product := range shownProducts(query) {
+		shown = append(shown, ra.current(product))
+	}
+	if len(shown) == 0 {
+		return "", false
+	}
+	budget, hasBudget := budgetUSD(query)
+
+	var pick *ProductInfo
+	for i, product := range shown {
+		if hasBudget && product.Price > budget {
+			continue
+		}
+		if pick == nil || (product.InStock && !pick.InStock) || (product.InStock == pick.InStock && product.Price < pick.Price) {
+			pick = &shown[i]
+		}
+	}
+
+	var result strings.Builder
+	result.WriteString("**My Top Recommendation:**\n")
+	if pick != nil {
+		result.WriteString(fmt.Sprintf("The **%s %s** (%s) is the best fit from what I found. %s.", pick.Brand, pick.Model, m.format(pick.Price), pick.Description))
+		if !pick.InStock {
+			result.WriteString(" It's currently out of stock - ask us to notify you when it's back.")
+		}
+	} else {
+		cheapest := shown[0]
+		for _, product := range shown[1:] {
+			if product.Price < cheapest.Price {
+				cheapest = product
+			}
+		}
+		result.WriteString(fmt.Sprintf("Nothing I found is within your budget; the closest is the **%s %s** (%s), %s over budget. %s.",
+			cheapest.Brand, cheapest.Model, m.format(cheapest.Price), m.format(cheapest.Price-budget), cheapest.Description))
+	}
+	result.WriteString("\n\n")
+	result.WriteString(nextSteps)
+	return result.String(), true
+}
+
+// nextSteps closes every rule-based recommendation
+const nextSteps = "**Next Steps:**\n" +
+	"• Visit our showroom to try different nib sizes\n" +
+	"• Consider ink preferences (cartridge vs converter)\n" +
+	"• Ask about our 30-day return policy\n"
+
+// firstInStock returns the best match we can ship today, or the best match
+// overall when none are in stock
+func firstInStock(similar []SimilarProduct) SimilarProduct {
+	for _, match := range similar {
+		if match.Product.InStock {
+			return match
+		}
+	}
+	return similar[0]
+}
+
+// generatePersonalizedRecommendation also reports whether it named a pen
+func (ra *RecommendationAgent) generatePersonalizedRecommendation(query, research, pricing string, similar []SimilarProduct, m money) (string, bool) {
 	content := strings.ToLower(query)
 
 	var result strings.Builder
+	specific := true
 
 	// Analyze user intent and preferences
 	if strings.Contains(content, "beginner") || strings.Contains(content, "first") {
 		result.WriteString("**Perfect for Beginners:**\n")
-		result.WriteString("For your first fountain pen, I recommend starting with the **Parker Sonnet** ($125). ")
+		result.WriteString(fmt.Sprintf("For your first fountain pen, I recommend starting with the **Parker Sonnet** (%s). ", m.format(125)))
 		result.WriteString("It's reliable, easy to maintain, and writes smoothly on most papers.\n\n")
 	} else if strings.Contains(content, "luxury") || strings.Contains(content, "premium") {
 		result.WriteString("**Luxury Recommendation:**\n")
-		result.WriteString("The **Montblanc Meisterstück 149** ($895) is the ultimate writing instrument. ")
+		result.WriteString(fmt.Sprintf("The **Montblanc Meisterstück 149** (%s) is the ultimate writing instrument. ", m.format(895)))
 		result.WriteString("Hand-crafted with precious resin and 14K gold nib, it's a lifetime investment.\n\n")
 	} else if strings.Contains(content, "daily") || strings.Contains(content, "work") {
 		result.WriteString("**Perfect for Daily Use:**\n")
-		result.WriteString("The **Pilot Vanishing Point** ($165) is ideal for busy professionals. ")
+		result.WriteString(fmt.Sprintf("The **Pilot Vanishing Point** (%s) is ideal for busy professionals. ", m.format(165)))
 		result.WriteString("Its retractable nib means you can use it like a ballpoint but with fountain pen elegance.\n\n")
+	} else if len(similar) > 0 {
+		top := firstInStock(similar)
+		result.WriteString("**My Top Recommendation:**\n")
+		result.WriteString(fmt.Sprintf("The **%s** (%s) is the closest match to what you described. %s.", top.Name, m.format(top.Product.Price), top.Product.Description))
+		if top.MatchedReview != "" {
+			result.WriteString(fmt.Sprintf(" One customer wrote: \"%s\"", top.MatchedReview))
+		}
+		if !top.Product.InStock {
+			result.WriteString(" It's currently out of stock - ask us to notify you when it's back.")
+		} else if top.Key != similar[0].Key {
+			result.WriteString(fmt.Sprintf(" The %s is an even closer match but is out of stock right now - ask us to notify you when it's back.", similar[0].Name))
+		}
+		result.WriteString("\n\n")
 	} else {
 		result.WriteString("**My Top Recommendation:**\n")
 		result.WriteString("Based on your query, I suggest considering pens that match your writing style and budget. ")
+		specific = false
 	}
 
 	// Add context from research if available
@@ -100,10 +320,19 @@ func (ra *RecommendationAgent) generatePersonalizedRecommendation(query, researc
 		result.WriteString("The options I found should give you excellent performance and value.\n\n")
 	}
 
-	result.WriteString("**Next Steps:**\n")
-	result.WriteString("• Visit our showroom to try different nib sizes\n")
-	result.WriteString("• Consider ink preferences (cartridge vs converter)\n")
-	result.WriteString("• Ask about our 30-day return policy\n")
+	result.WriteString(nextSteps)
 
-	return result.String()
+	return result.String(), specific
+}
+
+// TopPickVote makes recommendations that lead with the same catalogue pen
+// vote together in a MajorityVote ParallelAgent
+func TopPickVote(products map[string]ProductInfo) func(models.Response) string {
+	resolver := NewEntityResolver(products)
+	return func(response models.Response) string {
+		if named := resolver.NamedProducts(response.Content); len(named) > 0 {
+			return named[0].Key
+		}
+		return normalizedContent(response)
+	}
 }
@@ -0,0 +1,242 @@
+package agents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"pen-shop/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownAgentType = errors.New("unknown agent type")
+	ErrAgentNotFound    = errors.New("agent not found")
+)
+
+// AgentConfig is the configuration of one agent instance, as decoded from
+// JSON. "priority" applies to every type; the other keys are up to the factory.
+type AgentConfig map[string]interface{}
+
+// String reads a string setting, or fallback when it is unset
+func (c AgentConfig) String(key, fallback string) (string, error) {
+	value, ok := c[key]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return fallback, fmt.Errorf("%s must be a string", key)
+	}
+	return s, nil
+}
+
+// Int reads a whole-number setting, or fallback when it is unset
+func (c AgentConfig) Int(key string, fallback int) (int, error) {
+	switch value := c[key].(type) {
+	case nil:
+		return fallback, nil
+	case int:
+		return value, nil
+	case float64:
+		if value == math.Trunc(value) {
+			return int(value), nil
+		}
+	}
+	return fallback, fmt.Errorf("%s must be a whole number", key)
+}
+
+// AgentSpec asks the registry for an agent of a registered type
+type AgentSpec struct {
+	Type string `json:"type"`
+	// Enabled defaults to true
+	Enabled *bool       `json:"enabled,omitempty"`
+	Config  AgentConfig `json:"config,omitempty"`
+}
+
+// LoadAgentSpecs reads the agents to create from a JSON array of specs
+func LoadAgentSpecs(path string) ([]AgentSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var specs []AgentSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("parse agents config: %w", err)
+	}
+	return specs, nil
+}
+
+// AgentFactory builds an agent of one type from its configuration
+type AgentFactory func(config AgentConfig) (models.Agent, error)
+
+// AgentInfo describes a registered agent
+type AgentInfo struct {
+	Name         string      `json:"name"`
+	Type         string      `json:"type"`
+	Capabilities []string    `json:"capabilities"`
+	Priority     int         `json:"priority"`
+	Enabled      bool        `json:"enabled"`
+	Config       AgentConfig `json:"config,omitempty"`
+	RegisteredAt time.Time   `json:"registered_at"`
+}
+
+type registeredAgent struct {
+	agent models.Agent
+	info  AgentInfo
+}
+
+// AgentRegistry holds the agents workflows can run, keyed by agent name.
+// Agents are created from factories keyed by type, and can be replaced,
+// disabled or removed while workflows are running.
+type AgentRegistry struct {
+	mu        sync.RWMutex
+	factories map[string]AgentFactory
+	agents    map[string]*registeredAgent
+	logger    models.Logger
+}
+
+func NewAgentRegistry(logger models.Logger) *AgentRegistry {
+	return &AgentRegistry{
+		factories: make(map[string]AgentFactory),
+		agents:    make(map[string]*registeredAgent),
+		logger:    logger,
+	}
+}
+
+// RegisterFactory makes agents of typeName available to Create
+func (ar *AgentRegistry) RegisterFactory(typeName string, factory AgentFactory) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	ar.factories[typeName] = factory
+}
+
+// Types lists the registered agent types
+func (ar *AgentRegistry) Types() []string {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+	types := make([]string, 0, len(ar.factories))
+	for typeName := range ar.factories {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Create builds an agent from spec and registers it under its own name,
+// replacing any agent already registered there; replaced reports whether one was
+func (ar *AgentRegistry) Create(spec AgentSpec) (info AgentInfo, replaced bool, err error) {
+	ar.mu.RLock()
+	factory, ok := ar.factories[spec.Type]
+	ar.mu.RUnlock()
+	if !ok {
+		return AgentInfo{}, false, fmt.Errorf("%w: %q", ErrUnknownAgentType, spec.Type)
+	}
+
+	// Factories may be slow or call back into the registry, so build unlocked
+	agent, err := factory(spec.Config)
+	if err != nil {
+		return AgentInfo{}, false, fmt.Errorf("create %s agent: %w", spec.Type, err)
+	}
+	priority, err := spec.Config.Int("priority", agent.GetPriority())
+	if err != nil {
+		return AgentInfo{}, false, fmt.Errorf("create %s agent: %w", spec.Type, err)
+	}
+	if settable, ok := agent.(interface{ SetPriority(int) }); ok {
+		settable.SetPriority(priority)
+	}
+
+	info = AgentInfo{
+		Name:         agent.GetName(),
+		Type:         spec.Type,
+		Capabilities: agent.GetCapabilities(),
+		Priority:     agent.GetPriority(),
+		Enabled:      spec.Enabled == nil || *spec.Enabled,
+		Config:       spec.Config,
+		RegisteredAt: time.Now(),
+	}
+
+	ar.mu.Lock()
+	_, replaced = ar.agents[info.Name]
+	ar.agents[info.Name] = &registeredAgent{agent: agent, info: info}
+	ar.mu.Unlock()
+
+	if replaced {
+		ar.logger.Info("🧩 Replaced agent %s (%s)", info.Name, info.Type)
+	} else {
+		ar.logger.Info("🧩 Registered agent %s (%s)", info.Name, info.Type)
+	}
+	return info, replaced, nil
+}
+
+// Get returns the named agent, or nil when it is missing or disabled
+func (ar *AgentRegistry) Get(name string) models.Agent {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+	if entry, ok := ar.agents[name]; ok && entry.info.Enabled {
+		return entry.agent
+	}
+	return nil
+}
+
+// Info describes the named agent, enabled or not
+func (ar *AgentRegistry) Info(name string) (AgentInfo, error) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+	entry, ok := ar.agents[name]
+	if !ok {
+		return AgentInfo{}, ErrAgentNotFound
+	}
+	return entry.info, nil
+}
+
+// List describes every registered agent, highest priority (lowest number) first
+func (ar *AgentRegistry) List() []AgentInfo {
+	ar.mu.RLock()
+	infos := make([]AgentInfo, 0, len(ar.agents))
+	for _, entry := range ar.agents {
+		infos = append(infos, entry.info)
+	}
+	ar.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Priority != infos[j].Priority {
+			return infos[i].Priority < infos[j].Priority
+		}
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// SetEnabled turns the named agent on or off; workflows skip disabled agents
+// from their next step on
+func (ar *AgentRegistry) SetEnabled(name string, enabled bool) (AgentInfo, error) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	entry, ok := ar.agents[name]
+	if !ok {
+		return AgentInfo{}, ErrAgentNotFound
+	}
+	entry.info.Enabled = enabled
+	if enabled {
+		ar.logger.Info("✅ Enabled agent %s", name)
+	} else {
+		ar.logger.Info("⏸️ Disabled agent %s", name)
+	}
+	return entry.info, nil
+}
+
+// Remove unregisters the named agent
+func (ar *AgentRegistry) Remove(name string) error {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	if _, ok := ar.agents[name]; !ok {
+		return ErrAgentNotFound
+	}
+	delete(ar.agents, name)
+	ar.logger.Info("🗑️ Removed agent %s", name)
+	return nil
+}
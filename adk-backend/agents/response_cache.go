@@ -0,0 +1,208 @@
+package agents
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"pen-shop/models"
+	"pen-shop/respcache"
+)
+
+// currencySymbols are spelled out before tokenizing so "$200" and "€200"
+// stay different questions
+var currencySymbols = strings.NewReplacer("$", " usd ", "€", " eur ", "£", " gbp ", "¥", " jpy ")
+
+// cacheFillerWords carry no meaning for the answer
+var cacheFillerWords = map[string]bool{
+	"please": true, "can": true, "could": true, "would": true, "you": true,
+	"show": true, "tell": true, "give": true, "list": true, "find": true,
+	"a": true, "an": true, "the": true, "some": true, "any": true,
+	"i": true, "me": true, "im": true, "am": true, "looking": true, "want": true,
+	"hi": true, "hello": true, "hey": true, "thanks": true, "thank": true,
+}
+
+// personalMarkers refer to the customer's own history, orders or belongings,
+// so the answer is not shareable with anyone else
+var personalMarkers = map[string]bool{
+	"my": true, "mine": true, "our": true, "ours": true,
+	"bought": true, "ordered": true, "order": true, "orders": true,
+	"previous": true, "previously": true, "last": true, "again": true,
+	"remember": true, "history": true, "wishlist": true,
+}
+
+// intentKeywords classify a normalized query, checked in order
+var intentKeywords = []struct {
+	intent   string
+	keywords []string
+}{
+	{"comparison", []string{"compare", "vs", "versus", "difference", "between", "better"}},
+	{"price_trend", []string{"trend", "historically", "history", "deal"}},
+	{"budget", []string{"under", "below", "less", "cheap", "budget", "price", "cost", "usd", "eur", "gbp", "jpy", "afford", "affordable"}},
+	{"reviews", []string{"review", "rating", "opinion", "reliable"}},
+	{"recommendation", []string{"recommend", "suggest", "best", "beginner", "gift", "should"}},
+}
+
+// NormalizeCacheQuery reduces a question to the words that change its answer:
+// lowercase, accents folded, currency symbols spelled out, filler words and
+// plurals dropped
+func NormalizeCacheQuery(query string) string {
+	var kept []string
+	for _, token := range normalizeTokens(currencySymbols.Replace(query)) {
+		if cacheFillerWords[token] {
+			continue
+		}
+		if len(token) > 3 && strings.HasSuffix(token, "s") && !strings.HasSuffix(token, "ss") {
+			token = strings.TrimSuffix(token, "s")
+		}
+		kept = append(kept, token)
+	}
+	return strings.Join(kept, " ")
+}
+
+// CacheIntent names what a normalized query is asking for
+func CacheIntent(normalized string) string {
+	tokens := make(map[string]bool)
+	for _, token := range strings.Fields(normalized) {
+		tokens[token] = true
+	}
+	for _, candidate := range intentKeywords {
+		for _, keyword := range candidate.keywords {
+			if tokens[keyword] {
+				return candidate.intent
+			}
+		}
+	}
+	return "research"
+}
+
+// personalizedQuery reports whether the answer depends on who is asking
+func personalizedQuery(query models.Query) bool {
+	if cartIntent(query.Content) != cartIntentNone {
+		return true
+	}
+	for _, token := range normalizeTokens(query.Content) {
+		if personalMarkers[token] {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheStats counts response cache outcomes since startup
+type CacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Skipped int64 `json:"skipped"`
+}
+
+// CachingAgent answers repeated questions from a response cache and runs the
+// wrapped agent otherwise. Entries are keyed on the normalized query, its
+// intent, the quote currency and the catalogue version, so a catalogue change
+// makes every earlier answer unreachable.
+type CachingAgent struct {
+	models.Agent
+	store   respcache.Store
+	ttl     time.Duration
+	version func() string
+	logger  models.Logger
+
+	hits, misses, skipped atomic.Int64
+}
+
+// NewCachingAgent wraps agent; version returns the current catalogue version
+// and may be nil
+func NewCachingAgent(agent models.Agent, store respcache.Store, ttl time.Duration, version func() string, logger models.Logger) *CachingAgent {
+	if version == nil {
+		version = func() string { return "" }
+	}
+	return &CachingAgent{Agent: agent, store: store, ttl: ttl, version: version, logger: logger}
+}
+
+// cacheKey hashes everything that changes the answer to a query
+func (ca *CachingAgent) cacheKey(normalized, intent string, query models.Query) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{ca.version(), query.Currency, intent, normalized}, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
+
+func (ca *CachingAgent) Process(ctx context.Context, query models.Query) (models.Response, error) {
+	if personalizedQuery(query) {
+		ca.skipped.Add(1)
+		response, err := ca.Agent.Process(ctx, query)
+		return withCacheHit(response, false), err
+	}
+
+	normalized := NormalizeCacheQuery(query.Content)
+	intent := CacheIntent(normalized)
+	key := ca.cacheKey(normalized, intent, query)
+
+	entry, found, err := ca.store.Get(ctx, key)
+	if err != nil {
+		ca.logger.Error("❌ Response cache read failed: %v", err)
+	}
+	if found {
+		ca.hits.Add(1)
+		ca.logger.Info("⚡ Response cache hit (%s): %s", intent, normalized)
+		response := withCacheHit(entry.Response, true)
+		// No agents ran for this answer, and the original execution belongs
+		// to whoever asked first
+		delete(response.Metadata, "steps")
+		delete(response.Metadata, "execution_id")
+		response.Metadata["steps_executed"] = 0
+		response.Metadata["cached_at"] = entry.CreatedAt
+		response.Metadata["cache_intent"] = intent
+		return response, nil
+	}
+
+	ca.misses.Add(1)
+	response, err := ca.Agent.Process(ctx, query)
+	if err != nil {
+		return response, err
+	}
+
+	// Actions that changed state must never be replayed, and answers missing a
+	// section should not outlive the outage that caused it
+	workflowType, _ := response.Metadata["workflow_type"].(string)
+	degraded, _ := response.Metadata["degraded"].(bool)
+	if workflowType != "cart_action" && !degraded {
+		now := time.Now()
+		err := ca.store.Set(ctx, respcache.Entry{
+			Key:              key,
+			Intent:           intent,
+			Query:            normalized,
+			CatalogueVersion: ca.version(),
+			Response:         response,
+			CreatedAt:        now,
+			ExpiresAt:        now.Add(ca.ttl),
+		})
+		if err != nil {
+			ca.logger.Error("❌ Response cache write failed: %v", err)
+		}
+	}
+	return withCacheHit(response, false), nil
+}
+
+// Invalidate drops every cached answer, for example after a catalogue change
+func (ca *CachingAgent) Invalidate(ctx context.Context) (int64, error) {
+	return ca.store.Purge(ctx)
+}
+
+// Stats reports hit and miss counts
+func (ca *CachingAgent) Stats() CacheStats {
+	return CacheStats{Hits: ca.hits.Load(), Misses: ca.misses.Load(), Skipped: ca.skipped.Load()}
+}
+
+// withCacheHit copies the response metadata so cached entries are never
+// mutated, and records whether the answer came from the cache
+func withCacheHit(response models.Response, hit bool) models.Response {
+	metadata := make(map[string]interface{}, len(response.Metadata)+1)
+	for key, value := range response.Metadata {
+		metadata[key] = value
+	}
+	metadata["cache_hit"] = hit
+	response.Metadata = metadata
+	return response
+}
@@ -0,0 +1,175 @@
+package agents
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// SearchOptions filters and boosts a catalogue search
+type SearchOptions struct {
+	// Restrict limits results to these catalogue keys when non-nil
+	Restrict map[string]bool
+	// MaxPrice excludes products above it when positive
+	MaxPrice    float64
+	Types       map[string]bool
+	InStockOnly bool
+	// Availability overrides the catalogue's stock flags when set
+	Availability Availability
+	// Boosts adds to the text score of specific catalogue keys
+	Boosts map[string]float64
+}
+
+// SearchResult is a ranked catalogue product
+type SearchResult struct {
+	Key     string      `json:"key"`
+	Name    string      `json:"name"`
+	Score   float64     `json:"score"`
+	Product ProductInfo `json:"-"`
+}
+
+// SearchIndex is an in-process BM25 index over the product catalogue
+type SearchIndex struct {
+	docs      []indexedProduct
+	docFreq   map[string]int
+	avgLength float64
+	k1        float64
+	b         float64
+}
+
+type indexedProduct struct {
+	key     string
+	product ProductInfo
+	terms   map[string]float64
+	length  float64
+}
+
+// searchFieldWeights scale term frequency by where the term appears
+var searchFieldWeights = struct {
+	name, brand, kind, description, features float64
+}{name: 3, brand: 2, kind: 2, description: 1, features: 1.5}
+
+// searchStopwords are ignored in queries and documents
+var searchStopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "the": true, "for": true, "with": true,
+	"of": true, "to": true, "in": true, "on": true, "me": true, "my": true,
+	"i": true, "is": true, "it": true, "that": true, "show": true, "find": true,
+	"want": true, "need": true, "looking": true, "under": true, "some": true,
+}
+
+func NewSearchIndex(products map[string]ProductInfo) *SearchIndex {
+	si := &SearchIndex{
+		docFreq: make(map[string]int),
+		k1:      1.2,
+		b:       0.75,
+	}
+
+	var totalLength float64
+	for key, product := range products {
+		doc := indexedProduct{key: key, product: product, terms: make(map[string]float64)}
+
+		fields := []struct {
+			text   string
+			weight float64
+		}{
+			{product.Brand + " " + product.Model, searchFieldWeights.name},
+			{product.Brand, searchFieldWeights.brand},
+			{product.Type, searchFieldWeights.kind},
+			{product.Description, searchFieldWeights.description},
+			{strings.Join(product.Features, " ") + " " + strings.Join(product.Materials, " "), searchFieldWeights.features},
+		}
+		for _, field := range fields {
+			for _, term := range searchTerms(field.text) {
+				doc.terms[term] += field.weight
+				doc.length += field.weight
+			}
+		}
+
+		for term := range doc.terms {
+			si.docFreq[term]++
+		}
+		totalLength += doc.length
+		si.docs = append(si.docs, doc)
+	}
+
+	if len(si.docs) > 0 {
+		si.avgLength = totalLength / float64(len(si.docs))
+	}
+	return si
+}
+
+// Search scores every product that passes the filters and returns them in a
+// deterministic order: in-stock first, then score, rating, price and key
+func (si *SearchIndex) Search(query string, opts SearchOptions) []SearchResult {
+	queryTerms := searchTerms(query)
+	n := float64(len(si.docs))
+
+	var results []SearchResult
+	for _, doc := range si.docs {
+		p := withAvailability(doc.product, opts.Availability)
+		if opts.Restrict != nil && !opts.Restrict[doc.key] {
+			continue
+		}
+		if opts.MaxPrice > 0 && p.Price > opts.MaxPrice {
+			continue
+		}
+		if len(opts.Types) > 0 && !opts.Types[p.Type] {
+			continue
+		}
+		if opts.InStockOnly && !p.InStock {
+			continue
+		}
+
+		var score float64
+		for _, term := range queryTerms {
+			tf := doc.terms[term]
+			if tf == 0 {
+				continue
+			}
+			df := float64(si.docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * tf * (si.k1 + 1) / (tf + si.k1*(1-si.b+si.b*doc.length/si.avgLength))
+		}
+		score += opts.Boosts[doc.key]
+
+		results = append(results, SearchResult{
+			Key:     doc.key,
+			Name:    productName(p),
+			Score:   math.Round(score*1000) / 1000,
+			Product: p,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Product.InStock != b.Product.InStock {
+			return a.Product.InStock
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Product.Rating != b.Product.Rating {
+			return a.Product.Rating > b.Product.Rating
+		}
+		if a.Product.Price != b.Product.Price {
+			return a.Product.Price < b.Product.Price
+		}
+		return a.Key < b.Key
+	})
+	return results
+}
+
+// searchTerms normalizes text into index terms with a light plural stemmer
+func searchTerms(text string) []string {
+	var terms []string
+	for _, token := range normalizeTokens(text) {
+		if searchStopwords[token] {
+			continue
+		}
+		if len(token) > 3 && strings.HasSuffix(token, "s") && !strings.HasSuffix(token, "ss") {
+			token = strings.TrimSuffix(token, "s")
+		}
+		terms = append(terms, token)
+	}
+	return terms
+}
@@ -0,0 +1,206 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"pen-shop/llm"
+	"pen-shop/models"
+	"pen-shop/vectors"
+)
+
+// ReviewText is a customer review to embed alongside the catalogue
+type ReviewText struct {
+	ID        string
+	ProductID string
+	Text      string
+}
+
+// SimilarProduct is a catalogue product ranked by semantic similarity
+type SimilarProduct struct {
+	Key   string  `json:"key"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+	// MatchedReview is the review text that matched best, if a review beat the description
+	MatchedReview string      `json:"matched_review,omitempty"`
+	Product       ProductInfo `json:"-"`
+}
+
+const (
+	vectorKindProduct = "product"
+	vectorKindReview  = "review"
+	// embedBatchSize bounds how many texts are sent per embeddings request
+	embedBatchSize = 64
+)
+
+// SemanticIndex retrieves products by meaning rather than keywords, using
+// embeddings of catalogue descriptions and customer reviews
+type SemanticIndex struct {
+	embedder llm.Embedder
+	index    *vectors.Index
+	store    vectors.Store
+	logger   models.Logger
+
+	mu       sync.RWMutex
+	products map[string]ProductInfo
+}
+
+// NewSemanticIndex loads any persisted vectors for embedder from store, which may be nil
+func NewSemanticIndex(ctx context.Context, embedder llm.Embedder, store vectors.Store, logger models.Logger) *SemanticIndex {
+	si := &SemanticIndex{
+		embedder: embedder,
+		index:    vectors.NewIndex(embedder.Name()),
+		store:    store,
+		products: make(map[string]ProductInfo),
+		logger:   logger,
+	}
+	if store != nil {
+		if err := store.Load(ctx, si.index); err != nil {
+			logger.Error("❌ Loading vector index: %v", err)
+		}
+	}
+	return si
+}
+
+// Build embeds every product description and review that is new or changed
+// since the last build, drops vectors for removed documents and persists the result
+func (si *SemanticIndex) Build(ctx context.Context, products map[string]ProductInfo, reviews []ReviewText) error {
+	keyByID := make(map[string]string, len(products))
+	for key, product := range products {
+		keyByID[product.ID] = key
+	}
+
+	var pending []vectors.Item
+	keep := make(map[string]bool)
+	add := func(item vectors.Item) {
+		keep[item.ID] = true
+		if !si.index.Has(item.ID, item.Text) {
+			pending = append(pending, item)
+		}
+	}
+
+	for key, product := range products {
+		add(vectors.Item{
+			ID:     vectorKindProduct + ":" + key,
+			Kind:   vectorKindProduct,
+			Text:   productEmbeddingText(product),
+			Labels: map[string]string{"product": key},
+		})
+	}
+	for _, review := range reviews {
+		key, ok := keyByID[review.ProductID]
+		if !ok || strings.TrimSpace(review.Text) == "" {
+			continue
+		}
+		add(vectors.Item{
+			ID:     vectorKindReview + ":" + review.ID,
+			Kind:   vectorKindReview,
+			Text:   review.Text,
+			Labels: map[string]string{"product": key},
+		})
+	}
+
+	for start := 0; start < len(pending); start += embedBatchSize {
+		end := start + embedBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[start:end]
+
+		texts := make([]string, len(batch))
+		for i, item := range batch {
+			texts[i] = item.Text
+		}
+		embeddings, err := si.embedder.Embed(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("embed %d documents: %w", len(batch), err)
+		}
+		for i, item := range batch {
+			item.Vector = embeddings[i]
+			si.index.Upsert(item)
+		}
+	}
+	dropped := si.index.Retain(keep)
+
+	si.mu.Lock()
+	si.products = products
+	si.mu.Unlock()
+
+	si.logger.Info("🧭 Vector index ready: %d documents, %d newly embedded with %s", si.index.Len(), len(pending), si.embedder.Name())
+
+	if si.store != nil && (len(pending) > 0 || dropped > 0) {
+		if err := si.store.Save(ctx, si.index); err != nil {
+			return fmt.Errorf("save vector index: %w", err)
+		}
+	}
+	return nil
+}
+
+// SimilarProducts returns up to k products whose description or reviews are
+// closest in meaning to query. A product scores as its best matching document.
+func (si *SemanticIndex) SimilarProducts(ctx context.Context, query string, k int) ([]SimilarProduct, error) {
+	si.mu.RLock()
+	products := si.products
+	si.mu.RUnlock()
+	// Nothing to rank against until the first Build completes
+	if len(products) == 0 {
+		return nil, nil
+	}
+
+	embeddings, err := si.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for 1 query", len(embeddings))
+	}
+
+	best := make(map[string]SimilarProduct)
+	for _, match := range si.index.Search(embeddings[0], 0, nil) {
+		key := match.Item.Labels["product"]
+		product, ok := products[key]
+		if !ok || match.Score <= 0 {
+			continue
+		}
+		if current, seen := best[key]; seen && current.Score >= match.Score {
+			continue
+		}
+
+		similar := SimilarProduct{
+			Key:     key,
+			Name:    productName(product),
+			Score:   math.Round(match.Score*1000) / 1000,
+			Product: product,
+		}
+		if match.Item.Kind == vectorKindReview {
+			similar.MatchedReview = match.Item.Text
+		}
+		best[key] = similar
+	}
+
+	results := make([]SimilarProduct, 0, len(best))
+	for _, similar := range best {
+		results = append(results, similar)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Key < results[j].Key
+	})
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// productEmbeddingText describes a product in prose for the embedder
+func productEmbeddingText(p ProductInfo) string {
+	return fmt.Sprintf("%s %s, a %s. %s. Materials: %s. Features: %s.",
+		p.Brand, p.Model, strings.ToLower(p.Type), p.Description,
+		strings.Join(p.Materials, ", "), strings.Join(p.Features, ", "))
+}
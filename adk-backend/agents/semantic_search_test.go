@@ -0,0 +1,136 @@
+package agents
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"pen-shop/llm"
+	"pen-shop/vectors"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+
+// countingEmbedder counts the documents sent to the embedder it wraps
+type countingEmbedder struct {
+	llm.Embedder
+	mu       sync.Mutex
+	embedded int
+}
+
+func (ce *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	ce.mu.Lock()
+	ce.embedded += len(texts)
+	ce.mu.Unlock()
+	return ce.Embedder.Embed(ctx, texts)
+}
+
+func (ce *countingEmbedder) Embedded() int {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	return ce.embedded
+}
+
+var semanticProducts = map[string]ProductInfo{
+	"lamy_safari": {
+		ID: "p1", Brand: "Lamy", Model: "Safari", Type: "Fountain",
+		Description: "Sturdy plastic fountain pen for students and beginners",
+		Materials:   []string{"plastic"}, Features: []string{"ergonomic grip"},
+	},
+	"pilot_capless": {
+		ID: "p2", Brand: "Pilot", Model: "Capless", Type: "Fountain",
+		Description: "Retractable fountain pen with a click mechanism",
+		Materials:   []string{"brass"}, Features: []string{"retractable nib"},
+	},
+	"parker_jotter": {
+		ID: "p3", Brand: "Parker", Model: "Jotter", Type: "Ballpoint",
+		Description: "Classic stainless steel ballpoint for the office",
+		Materials:   []string{"stainless steel"}, Features: []string{"click mechanism"},
+	},
+}
+
+var semanticReviews = []ReviewText{
+	{ID: "r1", ProductID: "p1", Text: "My daughter takes it to school every day and it survived being dropped"},
+	{ID: "r2", ProductID: "p9", Text: "Review of a product no longer sold"},
+}
+
+func TestSimilarProductsRanking(t *testing.T) {
+	ctx := context.Background()
+	si := NewSemanticIndex(ctx, llm.NewHashEmbedder(256), nil, nopLogger{})
+
+	if results, err := si.SimilarProducts(ctx, "fountain pen", 3); err != nil || results != nil {
+		t.Fatalf("before Build = %v, %v, want no results", results, err)
+	}
+	if err := si.Build(ctx, semanticProducts, semanticReviews); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	results, err := si.SimilarProducts(ctx, "retractable fountain pen with a click mechanism", 2)
+	if err != nil {
+		t.Fatalf("SimilarProducts: %v", err)
+	}
+	if len(results) != 2 || results[0].Key != "pilot_capless" {
+		t.Fatalf("results = %+v, want pilot_capless first and 2 results", results)
+	}
+	if results[0].Score < results[1].Score || results[0].Name != "Pilot Capless" {
+		t.Errorf("top result = %+v, want the highest score and a display name", results[0])
+	}
+
+	// A review can match better than the product's own description
+	results, _ = si.SimilarProducts(ctx, "survived being dropped at school", 1)
+	if len(results) != 1 || results[0].Key != "lamy_safari" || results[0].MatchedReview != semanticReviews[0].Text {
+		t.Errorf("results = %+v, want lamy_safari matched by its review", results)
+	}
+}
+
+func TestSemanticIndexPersistence(t *testing.T) {
+	ctx := context.Background()
+	store := vectors.NewFileStore(filepath.Join(t.TempDir(), "vectors.json"))
+
+	first := &countingEmbedder{Embedder: llm.NewHashEmbedder(128)}
+	si := NewSemanticIndex(ctx, first, store, nopLogger{})
+	if err := si.Build(ctx, semanticProducts, semanticReviews); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	// Three products and the review of a listed product
+	if got := first.Embedded(); got != 4 {
+		t.Fatalf("embedded %d documents, want 4", got)
+	}
+
+	// After a restart only the changed description is embedded again
+	changed := make(map[string]ProductInfo, len(semanticProducts))
+	for key, product := range semanticProducts {
+		changed[key] = product
+	}
+	jotter := changed["parker_jotter"]
+	jotter.Description = "Refillable stainless steel ballpoint with a click mechanism"
+	changed["parker_jotter"] = jotter
+
+	second := &countingEmbedder{Embedder: llm.NewHashEmbedder(128)}
+	restarted := NewSemanticIndex(ctx, second, store, nopLogger{})
+	if err := restarted.Build(ctx, changed, semanticReviews); err != nil {
+		t.Fatalf("Build after restart: %v", err)
+	}
+	if got := second.Embedded(); got != 1 {
+		t.Errorf("embedded %d documents after restart, want only the changed one", got)
+	}
+
+	results, _ := restarted.SimilarProducts(ctx, "refillable ballpoint", 1)
+	if len(results) != 1 || results[0].Key != "parker_jotter" {
+		t.Errorf("results = %+v, want parker_jotter", results)
+	}
+
+	// A different embedder cannot reuse the stored vectors
+	other := &countingEmbedder{Embedder: llm.NewHashEmbedder(64)}
+	if err := NewSemanticIndex(ctx, other, store, nopLogger{}).Build(ctx, changed, semanticReviews); err != nil {
+		t.Fatalf("Build with another embedder: %v", err)
+	}
+	if got := other.Embedded(); got != 4 {
+		t.Errorf("embedded %d documents with a new embedder, want all 4", got)
+	}
+}
@@ -2,84 +2,130 @@ package agents
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"pen-shop/checkpoint"
 	"pen-shop/models"
 	"strings"
+	"sync"
 	"time"
 )
 
 // PenShopSequentialAgent orchestrates multiple specialized agents
 type PenShopSequentialAgent struct {
 	*BaseAgent
-	subAgents  map[string]models.Agent
+	// agents supplies each step's agent, as currently registered and enabled
+	agents     *AgentRegistry
 	workflow   *SequentialWorkflow
 	mcpGateway string
 	logger     models.Logger
+
+	// checkpoints persists executions after every step; nil disables resume
+	checkpoints checkpoint.Store
+	mu          sync.Mutex
+	// active holds executions running in this process
+	active map[string]bool
 }
 
 type SequentialWorkflow struct {
 	steps []WorkflowStep
 }
 
+// WorkflowStep runs one agent, or routes to other steps. An agent runs at
+// most once per execution, whichever branch it appears in.
 type WorkflowStep struct {
-	AgentName     string
-	Required      bool
-	DependsOn     []string
-	TimeoutSec    int
-	Condition     func(context map[string]interface{}) bool
+	AgentName  string
+	Required   bool
+	DependsOn  []string
+	TimeoutSec int
+	// Condition runs the step only when it holds; otherwise the step is
+	// skipped and Else runs in its place
+	Condition func(wc *WorkflowContext) bool
+	Else      []WorkflowStep
+
+	// Switch makes this a routing step named Name: the steps in the case it
+	// picks run in its place, or Default when no case matches
+	Name    string
+	Switch  func(wc *WorkflowContext) string
+	Cases   map[string][]WorkflowStep
+	Default []WorkflowStep
+
+	// Until repeats the agent until it holds, up to MaxIterations runs.
+	// Relax adds to the query context of each repeat.
+	Until         func(wc *WorkflowContext) bool
+	MaxIterations int
+	Relax         func(wc *WorkflowContext, iteration int) map[string]interface{}
+}
+
+// StepStatus is the outcome of one workflow step
+type StepStatus string
+
+const (
+	StepSucceeded         StepStatus = "succeeded"
+	StepFailed            StepStatus = "failed"
+	StepTimedOut          StepStatus = "timed_out"
+	StepSkippedDependency StepStatus = "skipped_dependency"
+	StepSkippedCondition  StepStatus = "skipped_condition"
+)
+
+// StepResult records what one workflow step produced, or why it produced nothing
+type StepResult struct {
+	AgentName  string                 `json:"agent_name"`
+	Status     StepStatus             `json:"status"`
+	Required   bool                   `json:"required"`
+	Content    string                 `json:"content"`
+	Confidence float64                `json:"confidence"`
+	DurationMs int64                  `json:"duration_ms"`
+	Iterations int                    `json:"iterations,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// WorkflowError is returned when a required step fails; Steps holds every
+// outcome recorded up to and including the failure
+type WorkflowError struct {
+	Steps       []StepResult
+	ExecutionID string
+	Err         error
+}
+
+func (we *WorkflowError) Error() string { return we.Err.Error() }
+func (we *WorkflowError) Unwrap() error { return we.Err }
+
+// stepSections names each step's part of the synthesized answer
+var stepSections = map[string]string{
+	"pen_research":    "product research",
+	"price_research":  "pricing analysis",
+	"review_agent":    "customer reviews",
+	"recommend_agent": "recommendation",
+}
+
+// WorkflowContext is what steps know about the execution so far. Intent is
+// what the customer is shopping for, and Route records the case each routing
+// step took.
 type WorkflowContext struct {
 	OriginalQuery string                 `json:"original_query"`
 	UserID        string                 `json:"user_id"`
+	Intent        string                 `json:"intent"`
 	Timestamp     time.Time              `json:"timestamp"`
 	Results       map[string]interface{} `json:"results"`
 	Metadata      map[string]interface{} `json:"metadata"`
+	Route         map[string]string      `json:"route,omitempty"`
 }
 
-func NewPenShopSequentialAgent(mcpGateway string, logger models.Logger) *PenShopSequentialAgent {
-	workflow := &SequentialWorkflow{
-		steps: []WorkflowStep{
-			{
-				AgentName:  "pen_research",
-				Required:   true,
-				TimeoutSec: 15,
-			},
-			{
-				AgentName:  "price_research",
-				Required:   false,
-				DependsOn:  []string{"pen_research"},
-				TimeoutSec: 10,
-			},
-			{
-				AgentName:  "review_agent",
-				Required:   false,
-				DependsOn:  []string{"pen_research"},
-				TimeoutSec: 10,
-			},
-			{
-				AgentName:  "recommend_agent",
-				Required:   true,
-				DependsOn:  []string{"pen_research"},
-				TimeoutSec: 10,
-			},
-		},
-	}
+func NewPenShopSequentialAgent(mcpGateway string, registry *AgentRegistry, logger models.Logger) *PenShopSequentialAgent {
+	workflow := &SequentialWorkflow{steps: defaultWorkflowSteps()}
 
 	return &PenShopSequentialAgent{
 		BaseAgent:  NewBaseAgent("pen_shop_sequential", []string{"orchestration", "workflow"}, 1),
-		subAgents:  make(map[string]models.Agent),
+		agents:     registry,
 		workflow:   workflow,
 		mcpGateway: mcpGateway,
 		logger:     logger,
+		active:     make(map[string]bool),
 	}
 }
 
-func (psa *PenShopSequentialAgent) RegisterSubAgent(agent models.Agent) {
-	psa.subAgents[agent.GetName()] = agent
-	psa.logger.Info("Registered sub-agent: %s", agent.GetName())
-}
-
 func (psa *PenShopSequentialAgent) CanHandle(query models.Query) float64 {
 	content := strings.ToLower(query.Content)
 	
@@ -153,99 +199,319 @@ func (psa *PenShopSequentialAgent) Process(ctx context.Context, query models.Que
 		}, nil
 	}
 
+	// Cart actions change state on the customer's behalf, so they go straight
+	// to the cart agent instead of through research
+	if cartAgent := psa.agents.Get("cart_agent"); cartAgent != nil && cartAgent.CanHandle(query) >= CartActionConfidence {
+		return psa.runCartAction(ctx, query, cartAgent)
+	}
+
 	// For non-greeting queries, run the full workflow
 	psa.logger.Info("🤖 Starting Sequential Agent workflow for: %s", query.Content)
+	run := psa.newRun(query)
+	psa.claim(run.exec.ID)
+	defer psa.release(run.exec.ID)
+	return psa.runWorkflow(ctx, query, run)
+}
 
-	workflowCtx := &WorkflowContext{
-		OriginalQuery: query.Content,
-		UserID:        query.UserID,
-		Timestamp:     time.Now(),
-		Results:       make(map[string]interface{}),
-		Metadata:      make(map[string]interface{}),
+// runWorkflow executes every step the run has not completed yet, recording an
+// outcome and a checkpoint after each one
+func (psa *PenShopSequentialAgent) runWorkflow(ctx context.Context, query models.Query, run *workflowRun) (models.Response, error) {
+	workflowCtx := &run.Context
+	executedSteps := make(map[string]bool)
+	for _, step := range run.Steps {
+		executedSteps[step.AgentName] = step.Status == StepSucceeded
 	}
 
-	var responses []models.Response
-	executedSteps := make(map[string]bool)
+	fail := func(err error) (models.Response, error) {
+		psa.saveCheckpoint(ctx, run, checkpoint.StatusFailed, err)
+		return models.Response{}, &WorkflowError{Steps: run.Steps, ExecutionID: run.exec.ID, Err: err}
+	}
+
+	if err := psa.runSteps(ctx, query, run, psa.workflow.steps, executedSteps); err != nil {
+		return fail(err)
+	}
+
+	responses, stepResults := run.Responses, run.Steps
+	if len(responses) == 0 {
+		return fail(fmt.Errorf("no agents processed the query"))
+	}
+
+	degraded := degradedSteps(stepResults)
+	finalResponse := psa.synthesizeResponse(workflowCtx, responses, degraded)
+
+	metadata := map[string]interface{}{
+		"workflow_type":   "sequential",
+		"execution_id":    run.exec.ID,
+		"intent":          workflowCtx.Intent,
+		"steps_executed":  len(responses),
+		"steps":           stepResults,
+		"processing_time": time.Since(workflowCtx.Timestamp).Milliseconds(),
+	}
+	if run.exec.Attempts > 1 {
+		metadata["resumed"] = true
+	}
+	if len(workflowCtx.Route) > 0 {
+		metadata["route"] = workflowCtx.Route
+	}
+	if len(degraded) > 0 {
+		metadata["degraded"] = true
+		names := make([]string, 0, len(degraded))
+		for _, step := range degraded {
+			names = append(names, step.AgentName)
+		}
+		metadata["degraded_steps"] = names
+	}
+
+	// Surface structured comparison data alongside the Markdown answer
+	for _, resp := range responses {
+		if comparison, ok := resp.Metadata["comparison"]; ok {
+			metadata["comparison"] = comparison
+		}
+		if code, ok := resp.Metadata["currency"]; ok {
+			metadata["currency"] = code
+		}
+	}
+
+	response := models.Response{
+		AgentName:  psa.GetName(),
+		Content:    finalResponse,
+		Confidence: 0.9,
+		Metadata:   metadata,
+		Timestamp:  time.Now(),
+	}
+	run.Response = &response
+	psa.saveCheckpoint(ctx, run, checkpoint.StatusCompleted, nil)
+	return response, nil
+}
 
-	// Execute workflow steps sequentially
-	for _, step := range psa.workflow.steps {
-		if !psa.checkDependencies(step, executedSteps) {
+// runSteps executes steps in order, following routing steps into the case
+// they pick and conditional steps into their Else. It returns an error, with
+// the outcome already recorded, when a required step fails.
+func (psa *PenShopSequentialAgent) runSteps(ctx context.Context, query models.Query, run *workflowRun, steps []WorkflowStep, executed map[string]bool) error {
+	workflowCtx := &run.Context
+	for _, step := range steps {
+		if step.Switch != nil {
+			route := step.Switch(workflowCtx)
+			next, ok := step.Cases[route]
+			if !ok {
+				next = step.Default
+			}
+			if workflowCtx.Route == nil {
+				workflowCtx.Route = make(map[string]string)
+			}
+			workflowCtx.Route[step.Name] = route
+			psa.logger.Info("🔀 %s: taking the %s route", step.Name, route)
+			if err := psa.runSteps(ctx, query, run, next, executed); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if executed[step.AgentName] {
+			continue
+		}
+		outcome := StepResult{AgentName: step.AgentName, Required: step.Required}
+
+		if missing := psa.missingDependencies(step, executed); len(missing) > 0 {
+			outcome.Status = StepSkippedDependency
+			outcome.Error = "depends on " + strings.Join(missing, ", ")
+			psa.recordStep(ctx, run, outcome)
+			psa.logger.Info("⏭️ Skipped %s: %s", step.AgentName, outcome.Error)
+			continue
+		}
+
+		if step.Condition != nil && !step.Condition(workflowCtx) {
+			outcome.Status = StepSkippedCondition
+			psa.recordStep(ctx, run, outcome)
+			psa.logger.Info("⏭️ Skipped %s: condition not met", step.AgentName)
+			if err := psa.runSteps(ctx, query, run, step.Else, executed); err != nil {
+				return err
+			}
 			continue
 		}
 
-		agent := psa.subAgents[step.AgentName]
+		agent := psa.agents.Get(step.AgentName)
 		if agent == nil {
+			outcome.Status = StepFailed
+			outcome.Error = "agent not available"
 			if step.Required {
-				return models.Response{}, fmt.Errorf("required agent %s not available", step.AgentName)
+				run.Steps = append(run.Steps, outcome)
+				return fmt.Errorf("required agent %s not available", step.AgentName)
 			}
+			psa.recordStep(ctx, run, outcome)
+			psa.logger.Error("❌ Optional step %s failed: agent not available", step.AgentName)
 			continue
 		}
 
-		psa.logger.Info("🔄 Executing: %s", step.AgentName)
-
-		stepQuery := models.Query{
-			ID:      fmt.Sprintf("%s_%s", query.ID, step.AgentName),
-			Content: query.Content,
-			Context: map[string]interface{}{
-				"workflow_context": map[string]interface{}{
-					"OriginalQuery": workflowCtx.OriginalQuery,
-					"UserID":        workflowCtx.UserID,
-					"Timestamp":     workflowCtx.Timestamp,
-					"Results":       workflowCtx.Results,
-					"Metadata":      workflowCtx.Metadata,
-				},
-				"previous_results": workflowCtx.Results,
-			},
+		maxRuns := 1
+		if step.Until != nil && step.MaxIterations > 1 {
+			maxRuns = step.MaxIterations
 		}
 
-		stepCtx, cancel := context.WithTimeout(ctx, time.Duration(step.TimeoutSec)*time.Second)
-		response, err := agent.Process(stepCtx, stepQuery)
-		cancel()
+		var response models.Response
+		var stepErr error
+		var extra map[string]interface{}
+		stepStart := time.Now()
+		for iteration := 1; ; iteration++ {
+			psa.logger.Info("🔄 Executing: %s", step.AgentName)
+			attempt, timedOut, err := psa.runAgent(ctx, query, step, agent, workflowCtx, extra)
+			if err != nil && iteration > 1 {
+				// Keep what the previous run found
+				psa.logger.Error("❌ Repeat %d of %s failed, keeping run %d: %v", iteration, step.AgentName, iteration-1, err)
+				break
+			}
+			if err != nil {
+				stepErr = err
+				outcome.Status = StepFailed
+				if timedOut {
+					outcome.Status = StepTimedOut
+				}
+				outcome.Error = err.Error()
+				break
+			}
+
+			response = attempt
+			workflowCtx.Results[step.AgentName] = response.Content
+			workflowCtx.Metadata[step.AgentName] = response.Metadata
+			if maxRuns > 1 {
+				outcome.Iterations = iteration
+			}
+			if iteration >= maxRuns || step.Until(workflowCtx) {
+				break
+			}
+			if step.Relax != nil {
+				extra = step.Relax(workflowCtx, iteration+1)
+			}
+			psa.logger.Info("🔁 Repeating %s (run %d of %d)", step.AgentName, iteration+1, maxRuns)
+		}
+		outcome.DurationMs = time.Since(stepStart).Milliseconds()
 
-		if err != nil {
+		if stepErr != nil {
 			if step.Required {
-				return models.Response{}, fmt.Errorf("required agent %s failed: %w", step.AgentName, err)
+				run.Steps = append(run.Steps, outcome)
+				return fmt.Errorf("required agent %s %s: %w", step.AgentName, outcome.Status, stepErr)
 			}
+			psa.recordStep(ctx, run, outcome)
+			psa.logger.Error("❌ Optional step %s %s after %dms: %v", step.AgentName, outcome.Status, outcome.DurationMs, stepErr)
 			continue
 		}
 
-		workflowCtx.Results[step.AgentName] = response.Content
-		workflowCtx.Metadata[step.AgentName] = response.Metadata
-		responses = append(responses, response)
-		executedSteps[step.AgentName] = true
+		run.Responses = append(run.Responses, response)
+		outcome.Status = StepSucceeded
+		outcome.Content = response.Content
+		outcome.Confidence = response.Confidence
+		outcome.Metadata = response.Metadata
+		executed[step.AgentName] = true
+		psa.recordStep(ctx, run, outcome)
 
 		psa.logger.Info("✅ Completed: %s", step.AgentName)
 	}
+	return nil
+}
 
-	if len(responses) == 0 {
-		return models.Response{}, fmt.Errorf("no agents processed the query")
+// runAgent runs one step's agent under the step timeout; extra is added to
+// the step query's context
+func (psa *PenShopSequentialAgent) runAgent(ctx context.Context, query models.Query, step WorkflowStep, agent models.Agent, workflowCtx *WorkflowContext, extra map[string]interface{}) (models.Response, bool, error) {
+	stepQuery := models.Query{
+		ID:        fmt.Sprintf("%s_%s", query.ID, step.AgentName),
+		Content:   query.Content,
+		UserID:    query.UserID,
+		Principal: query.Principal,
+		Currency:  query.Currency,
+		SessionID: query.SessionID,
+		Context: map[string]interface{}{
+			"workflow_context": map[string]interface{}{
+				"OriginalQuery": workflowCtx.OriginalQuery,
+				"UserID":        workflowCtx.UserID,
+				"Intent":        workflowCtx.Intent,
+				"Timestamp":     workflowCtx.Timestamp,
+				"Results":       workflowCtx.Results,
+				"Metadata":      workflowCtx.Metadata,
+			},
+			"previous_results": workflowCtx.Results,
+		},
+	}
+	for key, value := range extra {
+		stepQuery.Context[key] = value
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, time.Duration(step.TimeoutSec)*time.Second)
+	defer cancel()
+	response, err := agent.Process(stepCtx, stepQuery)
+	return response, errors.Is(stepCtx.Err(), context.DeadlineExceeded), err
+}
+
+// recordStep adds an outcome to the run and checkpoints it
+func (psa *PenShopSequentialAgent) recordStep(ctx context.Context, run *workflowRun, outcome StepResult) {
+	run.Steps = append(run.Steps, outcome)
+	psa.saveCheckpoint(ctx, run, checkpoint.StatusRunning, nil)
+}
+
+// runCartAction answers a cart request with the cart agent alone
+func (psa *PenShopSequentialAgent) runCartAction(ctx context.Context, query models.Query, cartAgent models.Agent) (models.Response, error) {
+	psa.logger.Info("🛒 Routing cart action: %s", query.Content)
+
+	start := time.Now()
+	response, err := cartAgent.Process(ctx, query)
+	if err != nil {
+		return models.Response{}, fmt.Errorf("cart agent failed: %w", err)
 	}
 
-	finalResponse := psa.synthesizeResponse(workflowCtx, responses)
+	metadata := map[string]interface{}{
+		"workflow_type":    "cart_action",
+		"workflow_skipped": true,
+		"steps_executed":   1,
+		"steps": []StepResult{{
+			AgentName:  cartAgent.GetName(),
+			Status:     StepSucceeded,
+			Required:   true,
+			Content:    response.Content,
+			Confidence: response.Confidence,
+			DurationMs: time.Since(start).Milliseconds(),
+			Metadata:   response.Metadata,
+		}},
+		"processing_time": time.Since(start).Milliseconds(),
+	}
+	for _, key := range []string{"cart", "order", "awaiting_confirmation", "cart_action", "tool_calls"} {
+		if value, ok := response.Metadata[key]; ok {
+			metadata[key] = value
+		}
+	}
 
 	return models.Response{
 		AgentName:  psa.GetName(),
-		Content:    finalResponse,
-		Confidence: 0.9,
-		Metadata: map[string]interface{}{
-			"workflow_type":   "sequential",
-			"steps_executed":  len(responses),
-			"processing_time": time.Since(workflowCtx.Timestamp).Milliseconds(),
-		},
-		Timestamp: time.Now(),
+		Content:    response.Content,
+		Confidence: response.Confidence,
+		Metadata:   metadata,
+		Timestamp:  time.Now(),
 	}, nil
 }
 
-func (psa *PenShopSequentialAgent) checkDependencies(step WorkflowStep, executed map[string]bool) bool {
+// missingDependencies lists the steps this one depends on that did not succeed
+func (psa *PenShopSequentialAgent) missingDependencies(step WorkflowStep, executed map[string]bool) []string {
+	var missing []string
 	for _, dep := range step.DependsOn {
 		if !executed[dep] {
-			return false
+			missing = append(missing, dep)
 		}
 	}
-	return true
+	return missing
 }
 
-func (psa *PenShopSequentialAgent) synthesizeResponse(workflowCtx *WorkflowContext, responses []models.Response) string {
+// degradedSteps returns the steps that failed or timed out, and the steps
+// skipped because of them; condition skips are intentional and not degraded
+func degradedSteps(steps []StepResult) []StepResult {
+	var degraded []StepResult
+	for _, step := range steps {
+		switch step.Status {
+		case StepFailed, StepTimedOut, StepSkippedDependency:
+			degraded = append(degraded, step)
+		}
+	}
+	return degraded
+}
+
+func (psa *PenShopSequentialAgent) synthesizeResponse(workflowCtx *WorkflowContext, responses []models.Response, degraded []StepResult) string {
 	var result strings.Builder
 
 	result.WriteString("Based on my comprehensive analysis:\n\n")
@@ -271,5 +537,20 @@ func (psa *PenShopSequentialAgent) synthesizeResponse(workflowCtx *WorkflowConte
 		}
 	}
 
+	// Tell the customer which parts of the answer are missing
+	if len(degraded) > 0 {
+		var sections []string
+		for _, step := range degraded {
+			section, ok := stepSections[step.AgentName]
+			if !ok {
+				section = step.AgentName
+			}
+			sections = append(sections, section)
+		}
+		result.WriteString("\n_Note: this answer is incomplete - ")
+		result.WriteString(strings.Join(sections, ", "))
+		result.WriteString(" could not be completed right now. Please ask again shortly for the full picture._\n")
+	}
+
 	return result.String()
 }
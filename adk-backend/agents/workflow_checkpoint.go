@@ -0,0 +1,207 @@
+package agents
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"pen-shop/checkpoint"
+	"pen-shop/models"
+)
+
+var (
+	// ErrCheckpointsDisabled is returned by Execution and Resume when no
+	// checkpoint store is configured
+	ErrCheckpointsDisabled = errors.New("workflow checkpoints are disabled")
+	// ErrExecutionActive is returned when resuming an execution that is still running
+	ErrExecutionActive = errors.New("workflow execution is still running")
+)
+
+// checkpointStaleAfter is how long a running execution may go without a
+// checkpoint before it is treated as abandoned, for example by a restart.
+// It comfortably exceeds the longest workflow step.
+const checkpointStaleAfter = 2 * time.Minute
+
+// workflowRun is the state of one execution; checkpoints store it as JSON
+type workflowRun struct {
+	Context   WorkflowContext   `json:"context"`
+	Steps     []StepResult      `json:"steps"`
+	Responses []models.Response `json:"responses"`
+	// Response is the final answer once the execution has completed
+	Response *models.Response `json:"response,omitempty"`
+
+	exec checkpoint.Execution
+}
+
+// SetCheckpoints persists every execution to store after each step so it can
+// be inspected and resumed
+func (psa *PenShopSequentialAgent) SetCheckpoints(store checkpoint.Store) {
+	psa.checkpoints = store
+}
+
+// Checkpointing reports whether executions are checkpointed
+func (psa *PenShopSequentialAgent) Checkpointing() bool {
+	return psa.checkpoints != nil
+}
+
+func (psa *PenShopSequentialAgent) newRun(query models.Query) *workflowRun {
+	now := time.Now()
+	// The caller's credentials are not stored; resuming uses the resumer's
+	stored := query
+	stored.Principal = nil
+	// Only an authenticated subject owns the run; an anonymous caller's
+	// user_id is their own say-so
+	var owner string
+	if query.Principal != nil {
+		owner = query.Principal.Subject
+	}
+
+	return &workflowRun{
+		Context: WorkflowContext{
+			OriginalQuery: query.Content,
+			UserID:        query.UserID,
+			Intent:        detectIntent(query.Content),
+			Timestamp:     now,
+			Results:       make(map[string]interface{}),
+			Metadata:      make(map[string]interface{}),
+		},
+		exec: checkpoint.Execution{
+			ID:        newExecutionID(),
+			Status:    checkpoint.StatusRunning,
+			Query:     stored,
+			Owner:     owner,
+			Attempts:  1,
+			CreatedAt: now,
+		},
+	}
+}
+
+// saveCheckpoint records the run's progress. A failed save is logged rather
+// than failing the workflow, and still goes through once the caller's
+// deadline has passed so a timed-out step is recorded.
+func (psa *PenShopSequentialAgent) saveCheckpoint(ctx context.Context, run *workflowRun, status string, runErr error) {
+	if psa.checkpoints == nil {
+		return
+	}
+
+	run.exec.Status = status
+	run.exec.UpdatedAt = time.Now()
+	run.exec.Error = ""
+	if runErr != nil {
+		run.exec.Error = runErr.Error()
+	}
+	completed := make([]string, 0, len(run.Steps))
+	for _, step := range run.Steps {
+		if step.Status == StepSucceeded {
+			completed = append(completed, step.AgentName)
+		}
+	}
+	run.exec.CompletedSteps = completed
+
+	state, err := json.Marshal(run)
+	if err != nil {
+		psa.logger.Error("❌ Checkpoint %s could not be encoded: %v", run.exec.ID, err)
+		return
+	}
+	run.exec.State = state
+
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := psa.checkpoints.Save(saveCtx, run.exec); err != nil {
+		psa.logger.Error("❌ Checkpoint %s failed: %v", run.exec.ID, err)
+	}
+}
+
+// Execution returns an execution as of its last checkpoint
+func (psa *PenShopSequentialAgent) Execution(ctx context.Context, id string) (checkpoint.Execution, error) {
+	if psa.checkpoints == nil {
+		return checkpoint.Execution{}, ErrCheckpointsDisabled
+	}
+	return psa.checkpoints.Get(ctx, id)
+}
+
+// Resume continues an execution from its last completed step. Completed
+// steps are kept up to the first one that did not succeed; that step and
+// everything after it run again. A completed execution returns its answer.
+func (psa *PenShopSequentialAgent) Resume(ctx context.Context, id string, principal *models.Principal) (models.Response, error) {
+	exec, err := psa.Execution(ctx, id)
+	if err != nil {
+		return models.Response{}, err
+	}
+
+	var run workflowRun
+	if err := json.Unmarshal(exec.State, &run); err != nil {
+		return models.Response{}, fmt.Errorf("checkpoint %s is unreadable: %w", id, err)
+	}
+
+	if exec.Status == checkpoint.StatusCompleted && run.Response != nil {
+		return *run.Response, nil
+	}
+	if exec.Status == checkpoint.StatusRunning && time.Since(exec.UpdatedAt) < checkpointStaleAfter {
+		return models.Response{}, ErrExecutionActive
+	}
+	if !psa.claim(id) {
+		return models.Response{}, ErrExecutionActive
+	}
+	defer psa.release(id)
+
+	// Keep the leading steps that succeeded and rebuild the context from them
+	kept := 0
+	for kept < len(run.Steps) && run.Steps[kept].Status == StepSucceeded {
+		kept++
+	}
+	run.Steps = run.Steps[:kept]
+	run.Responses = run.Responses[:0]
+	run.Context.Results = make(map[string]interface{})
+	run.Context.Metadata = make(map[string]interface{})
+	for _, step := range run.Steps {
+		run.Context.Results[step.AgentName] = step.Content
+		run.Context.Metadata[step.AgentName] = step.Metadata
+		run.Responses = append(run.Responses, models.Response{
+			AgentName:  step.AgentName,
+			Content:    step.Content,
+			Confidence: step.Confidence,
+			Metadata:   step.Metadata,
+		})
+	}
+
+	exec.Attempts++
+	run.exec = exec
+	query := exec.Query
+	query.Principal = principal
+
+	psa.logger.Info("🔁 Resuming workflow %s after %d completed steps (attempt %d)", id, kept, exec.Attempts)
+	return psa.runWorkflow(ctx, query, &run)
+}
+
+// claim marks an execution as running in this process, returning false when
+// it already is
+func (psa *PenShopSequentialAgent) claim(id string) bool {
+	psa.mu.Lock()
+	defer psa.mu.Unlock()
+	if psa.active[id] {
+		return false
+	}
+	psa.active[id] = true
+	return true
+}
+
+func (psa *PenShopSequentialAgent) release(id string) {
+	psa.mu.Lock()
+	defer psa.mu.Unlock()
+	delete(psa.active, id)
+}
+
+// newExecutionID returns an unguessable ID; executions hold the customer's
+// question and answers, so they must not be enumerable
+func newExecutionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "wf_" + time.Now().Format("20060102150405.000000000")
+	}
+	return "wf_" + hex.EncodeToString(b)
+}
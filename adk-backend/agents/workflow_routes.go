@@ -0,0 +1,112 @@
+package agents
+
+import "strings"
+
+// Shopping intents the default workflow routes on
+const (
+	IntentGift       = "gift"
+	IntentBeginner   = "beginner"
+	IntentComparison = "comparison"
+	IntentGeneral    = "general"
+)
+
+// budgetRelaxationKey carries the factor pen research may exceed the budget
+// by when a strict search found nothing
+const budgetRelaxationKey = "budget_relaxation"
+
+// detectIntent classifies a query; comparisons win over the other intents
+// because they name the products already
+func detectIntent(content string) string {
+	content = strings.ToLower(content)
+	intents := []struct {
+		intent   string
+		keywords []string
+	}{
+		{IntentComparison, comparisonKeywords},
+		{IntentGift, []string{"gift", "present", "birthday", "graduation", "anniversary", "christmas", "for my"}},
+		{IntentBeginner, []string{"beginner", "first fountain pen", "first pen", "new to", "starter", "getting started", "easy to use"}},
+	}
+	for _, candidate := range intents {
+		for _, keyword := range candidate.keywords {
+			if strings.Contains(content, keyword) {
+				return candidate.intent
+			}
+		}
+	}
+	return IntentGeneral
+}
+
+// defaultWorkflowSteps researches pens, widening the budget when nothing fits,
+// then routes on intent before recommending:
+//   - gift: pricing when there is a budget, otherwise what reviewers love
+//   - beginner: reviews first, pricing only when there is a budget
+//   - comparison and everything else: pricing and reviews
+func defaultWorkflowSteps() []WorkflowStep {
+	research := []string{"pen_research"}
+	pricing := WorkflowStep{AgentName: "price_research", DependsOn: research, TimeoutSec: 10}
+	reviews := WorkflowStep{AgentName: "review_agent", DependsOn: research, TimeoutSec: 10}
+
+	giftPricing := pricing
+	giftPricing.Condition = hasBudget
+	giftPricing.Else = []WorkflowStep{reviews}
+
+	beginnerPricing := pricing
+	beginnerPricing.Condition = hasBudget
+
+	return []WorkflowStep{
+		{
+			AgentName:     "pen_research",
+			Required:      true,
+			TimeoutSec:    15,
+			Until:         foundProductsOrNoBudget,
+			MaxIterations: 3,
+			Relax:         relaxBudget,
+		},
+		{
+			Name:   "intent",
+			Switch: func(wc *WorkflowContext) string { return wc.Intent },
+			Cases: map[string][]WorkflowStep{
+				IntentGift:       {giftPricing},
+				IntentBeginner:   {reviews, beginnerPricing},
+				IntentComparison: {pricing, reviews},
+			},
+			Default: []WorkflowStep{pricing, reviews},
+		},
+		{
+			// Long enough for a critiqued recommendation to be revised once
+			AgentName:  "recommend_agent",
+			Required:   true,
+			DependsOn:  research,
+			TimeoutSec: 20,
+		},
+	}
+}
+
+// hasBudget reports whether pen research found a budget in the query
+func hasBudget(wc *WorkflowContext) bool {
+	return researchNumber(wc, "budget_constraint") > 0
+}
+
+// foundProductsOrNoBudget ends the research loop once something matched, or
+// when there is no budget to relax
+func foundProductsOrNoBudget(wc *WorkflowContext) bool {
+	return researchNumber(wc, "products_found") > 0 || !hasBudget(wc)
+}
+
+// relaxBudget lets each repeat of pen research go a further 25% over budget
+func relaxBudget(wc *WorkflowContext, iteration int) map[string]interface{} {
+	return map[string]interface{}{budgetRelaxationKey: 1 + 0.25*float64(iteration-1)}
+}
+
+// researchNumber reads a number from pen research's metadata, which holds
+// float64s once restored from a checkpoint
+func researchNumber(wc *WorkflowContext, key string) float64 {
+	research, _ := wc.Metadata["pen_research"].(map[string]interface{})
+	switch value := research[key].(type) {
+	case int:
+		return float64(value)
+	case float64:
+		return value
+	}
+	return 0
+}
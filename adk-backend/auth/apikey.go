@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"pen-shop/models"
+)
+
+// APIKey describes the caller a static API key belongs to
+type APIKey struct {
+	Subject string   `json:"subject"`
+	Tenant  string   `json:"tenant,omitempty"`
+	Tier    string   `json:"tier,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"`
+}
+
+// APIKeyAuthenticator accepts static keys sent as X-API-Key or "Authorization: ApiKey <key>"
+type APIKeyAuthenticator struct {
+	// keys are indexed by SHA-256 digest so lookups don't leak timing on the raw key
+	keys map[[sha256.Size]byte]APIKey
+}
+
+func NewAPIKeyAuthenticator(keys map[string]APIKey) *APIKeyAuthenticator {
+	hashed := make(map[[sha256.Size]byte]APIKey, len(keys))
+	for key, owner := range keys {
+		hashed[sha256.Sum256([]byte(key))] = owner
+	}
+	return &APIKeyAuthenticator{keys: hashed}
+}
+
+// LoadAPIKeys reads a JSON object mapping keys to their owners
+func LoadAPIKeys(path string) (map[string]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys map[string]APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parse API keys: %w", err)
+	}
+	for key, owner := range keys {
+		if owner.Subject == "" {
+			return nil, fmt.Errorf("API key ending %q has no subject", lastChars(key, 4))
+		}
+	}
+	return keys, nil
+}
+
+func (aka *APIKeyAuthenticator) Authenticate(r *http.Request) (*models.Principal, error) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "ApiKey ") {
+			key = strings.TrimPrefix(header, "ApiKey ")
+		}
+	}
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	digest := sha256.Sum256([]byte(key))
+	for candidate, owner := range aka.keys {
+		if subtle.ConstantTimeCompare(candidate[:], digest[:]) == 1 {
+			return &models.Principal{
+				Subject: owner.Subject,
+				Tenant:  owner.Tenant,
+				Tier:    owner.Tier,
+				Scopes:  owner.Scopes,
+				Method:  "api_key",
+			}, nil
+		}
+	}
+	return nil, ErrInvalidCredentials
+}
+
+func lastChars(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
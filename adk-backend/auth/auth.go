@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"pen-shop/models"
+)
+
+// ErrNoCredentials means the request carried nothing this authenticator understands
+var ErrNoCredentials = errors.New("auth: no credentials")
+
+// ErrInvalidCredentials means credentials were present but did not verify
+var ErrInvalidCredentials = errors.New("auth: invalid credentials")
+
+// Authenticator verifies the credentials on a request
+type Authenticator interface {
+	Authenticate(r *http.Request) (*models.Principal, error)
+}
+
+type principalKey struct{}
+
+// WithPrincipal attaches the authenticated principal to ctx
+func WithPrincipal(ctx context.Context, principal *models.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the principal attached to ctx, or nil for anonymous requests
+func PrincipalFrom(ctx context.Context) *models.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*models.Principal)
+	return principal
+}
+
+// Middleware authenticates requests for a mux router
+type Middleware struct {
+	authenticators []Authenticator
+	required       bool
+	public         map[string]bool
+	logger         models.Logger
+}
+
+// NewMiddleware tries each authenticator in order. When required is true,
+// requests without credentials are rejected except on public paths.
+func NewMiddleware(authenticators []Authenticator, required bool, publicPaths []string, logger models.Logger) *Middleware {
+	public := make(map[string]bool)
+	for _, path := range publicPaths {
+		public[path] = true
+	}
+
+	return &Middleware{
+		authenticators: authenticators,
+		required:       required,
+		public:         public,
+		logger:         logger,
+	}
+}
+
+// Handler satisfies mux.MiddlewareFunc
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, authenticator := range m.authenticators {
+			principal, err := authenticator.Authenticate(r)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			if err != nil {
+				m.logger.Info("🔒 Rejected credentials on %s: %v", r.URL.Path, err)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="pen-shop"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+			return
+		}
+
+		if m.required && !m.public[r.URL.Path] {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pen-shop"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireScope wraps a handler so only principals with scope may call it
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := PrincipalFrom(r.Context())
+		if principal == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pen-shop"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !principal.HasScope(scope) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// KeySet holds the verification keys for bearer tokens, indexed by key ID
+type KeySet struct {
+	keys []verificationKey
+}
+
+type verificationKey struct {
+	kid string
+	// key is *rsa.PublicKey, *ecdsa.PublicKey or []byte for HMAC secrets
+	key interface{}
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// NewKeySet returns an empty key set
+func NewKeySet() *KeySet {
+	return &KeySet{}
+}
+
+// AddHMACSecret adds a shared secret for HS256 tokens
+func (ks *KeySet) AddHMACSecret(kid string, secret []byte) {
+	ks.keys = append(ks.keys, verificationKey{kid: kid, key: secret})
+}
+
+// Len returns the number of keys in the set
+func (ks *KeySet) Len() int {
+	return len(ks.keys)
+}
+
+// LoadJWKS reads RSA, P-256 EC and symmetric keys from a local JWKS file
+func (ks *KeySet) LoadJWKS(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("parse JWKS: %w", err)
+	}
+
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return fmt.Errorf("JWKS key %q: %w", jwk.Kid, err)
+		}
+		ks.keys = append(ks.keys, verificationKey{kid: jwk.Kid, key: key})
+	}
+	return nil
+}
+
+// candidates returns the keys that may have signed a token with kid and alg
+func (ks *KeySet) candidates(kid, alg string) []interface{} {
+	var matches []interface{}
+	for _, vk := range ks.keys {
+		if kid != "" && vk.kid != "" && vk.kid != kid {
+			continue
+		}
+		switch vk.key.(type) {
+		case []byte:
+			if alg == "HS256" {
+				matches = append(matches, vk.key)
+			}
+		case *rsa.PublicKey:
+			if alg == "RS256" {
+				matches = append(matches, vk.key)
+			}
+		case *ecdsa.PublicKey:
+			if alg == "ES256" {
+				matches = append(matches, vk.key)
+			}
+		}
+	}
+	return matches
+}
+
+func (jwk jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("exponent: %w", err)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil {
+			return nil, fmt.Errorf("secret: %w", err)
+		}
+		return secret, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
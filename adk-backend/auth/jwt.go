@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"pen-shop/models"
+)
+
+// JWTAuthenticator verifies HS256, RS256 and ES256 bearer tokens against a KeySet
+type JWTAuthenticator struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// NewJWTAuthenticator checks iss and aud only when issuer and audience are set
+func NewJWTAuthenticator(keys *KeySet, issuer, audience string) *JWTAuthenticator {
+	return &JWTAuthenticator{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		leeway:   30 * time.Second,
+		now:      time.Now,
+	}
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type tokenClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	Scope     string          `json:"scope"`
+	Scopes    []string        `json:"scopes"`
+	Tier      string          `json:"tier"`
+	Tenant    string          `json:"tenant"`
+}
+
+func (ja *JWTAuthenticator) Authenticate(r *http.Request) (*models.Principal, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := ja.verify(strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")))
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := claims.Scopes
+	if claims.Scope != "" {
+		scopes = append(scopes, strings.Fields(claims.Scope)...)
+	}
+
+	return &models.Principal{
+		Subject: claims.Subject,
+		Tenant:  claims.Tenant,
+		Tier:    claims.Tier,
+		Scopes:  scopes,
+		Method:  "jwt",
+	}, nil
+}
+
+func (ja *JWTAuthenticator) verify(token string) (*tokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidCredentials)
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidCredentials, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature encoding", ErrInvalidCredentials)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range ja.keys.candidates(header.Kid, header.Alg) {
+		if verifySignature(header.Alg, key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("%w: signature not valid for alg %q", ErrInvalidCredentials, header.Alg)
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidCredentials, err)
+	}
+	if err := ja.validateClaims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return &claims, nil
+}
+
+func (ja *JWTAuthenticator) validateClaims(claims *tokenClaims) error {
+	now := ja.now()
+
+	if claims.Subject == "" {
+		return fmt.Errorf("missing sub")
+	}
+	if claims.ExpiresAt == nil {
+		return fmt.Errorf("missing exp")
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(ja.leeway)) {
+		return fmt.Errorf("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(ja.leeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return fmt.Errorf("token not yet valid")
+	}
+	if ja.issuer != "" && claims.Issuer != ja.issuer {
+		return fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if ja.audience != "" && !audienceContains(claims.Audience, ja.audience) {
+		return fmt.Errorf("token not issued for %q", ja.audience)
+	}
+	return nil
+}
+
+func verifySignature(alg string, key interface{}, signed, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	default:
+		return false
+	}
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// audienceContains handles aud as either a string or an array of strings
+func audienceContains(raw json.RawMessage, audience string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == audience
+	}
+
+	var many []string
+	if err := json.Unmarshal(raw, &many); err == nil {
+		for _, aud := range many {
+			if aud == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package cart
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Item is one product line in a cart. The same pen with a different nib is a
+// separate line.
+type Item struct {
+	SKU          string  `json:"sku" bson:"sku"`
+	Name         string  `json:"name" bson:"name"`
+	Brand        string  `json:"brand" bson:"brand"`
+	Nib          string  `json:"nib,omitempty" bson:"nib,omitempty"`
+	Quantity     int     `json:"quantity" bson:"quantity"`
+	UnitPriceUSD float64 `json:"unit_price_usd" bson:"unit_price_usd"`
+}
+
+// Cart is a session's basket
+type Cart struct {
+	SessionID string `json:"session_id" bson:"_id"`
+	UserID    string `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Items     []Item `json:"items" bson:"items"`
+	// CheckoutRequestedAt is set when the customer asked to check out in chat
+	// and is waiting to confirm; any change to the cart clears it
+	CheckoutRequestedAt *time.Time `json:"checkout_requested_at,omitempty" bson:"checkout_requested_at,omitempty"`
+	UpdatedAt           time.Time  `json:"updated_at" bson:"updated_at"`
+}
+
+// TotalUSD is the sum of every line at its validated price
+func (c Cart) TotalUSD() float64 {
+	var total float64
+	for _, item := range c.Items {
+		total += item.UnitPriceUSD * float64(item.Quantity)
+	}
+	return math.Round(total*100) / 100
+}
+
+// Count is the number of pens in the cart
+func (c Cart) Count() int {
+	count := 0
+	for _, item := range c.Items {
+		count += item.Quantity
+	}
+	return count
+}
+
+// find returns the index of the line for sku and nib, or -1
+func (c Cart) find(sku, nib string) int {
+	for i, item := range c.Items {
+		if item.SKU == sku && item.Nib == nib {
+			return i
+		}
+	}
+	return -1
+}
+
+const OrderStatusPlaced = "placed"
+
+// Order is a checked-out cart. Payment is mocked, so orders are placed as soon
+// as they are written.
+type Order struct {
+	ID        string    `json:"id" bson:"_id"`
+	SessionID string    `json:"session_id" bson:"session_id"`
+	UserID    string    `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Items     []Item    `json:"items" bson:"items"`
+	TotalUSD  float64   `json:"total_usd" bson:"total_usd"`
+	Status    string    `json:"status" bson:"status"`
+	Source    string    `json:"source" bson:"source"`
+	PlacedAt  time.Time `json:"placed_at" bson:"placed_at"`
+}
+
+// nibNames maps the words customers use for nib grades to catalogue codes
+var nibNames = map[string]string{
+	"extra fine":   "EF",
+	"extra-fine":   "EF",
+	"xf":           "EF",
+	"ef":           "EF",
+	"fine":         "F",
+	"f":            "F",
+	"medium":       "M",
+	"m":            "M",
+	"broad":        "B",
+	"b":            "B",
+	"double broad": "BB",
+	"bb":           "BB",
+}
+
+// NormalizeNib maps a nib name such as "fine" or "Medium" onto its catalogue
+// code; tip sizes such as "0.7mm" are returned as written
+func NormalizeNib(nib string) string {
+	nib = strings.TrimSpace(nib)
+	if code, ok := nibNames[strings.ToLower(nib)]; ok {
+		return code
+	}
+	return nib
+}
@@ -0,0 +1,74 @@
+package cart
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Product is the catalogue's current view of a pen
+type Product struct {
+	SKU      string  `json:"id"`
+	Name     string  `json:"name"`
+	Brand    string  `json:"brand"`
+	PriceUSD float64 `json:"price"`
+	InStock  bool    `json:"in_stock"`
+}
+
+// Catalogue looks up authoritative prices and availability
+type Catalogue interface {
+	// Product returns ErrUnknownProduct for SKUs the catalogue does not list
+	Product(ctx context.Context, sku string) (Product, error)
+}
+
+// HTTPCatalogue reads from the pen-catalogue service's /catalogue/{id} endpoint
+type HTTPCatalogue struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHTTPCatalogue(baseURL string) *HTTPCatalogue {
+	return &HTTPCatalogue{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (hc *HTTPCatalogue) Product(ctx context.Context, sku string) (Product, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hc.baseURL+"/catalogue/"+url.PathEscape(sku), nil)
+	if err != nil {
+		return Product{}, err
+	}
+
+	resp, err := hc.client.Do(req)
+	if err != nil {
+		return Product{}, fmt.Errorf("catalogue request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return Product{}, ErrUnknownProduct
+	default:
+		return Product{}, fmt.Errorf("catalogue returned status %d", resp.StatusCode)
+	}
+
+	var product Product
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&product); err != nil {
+		return Product{}, fmt.Errorf("catalogue returned invalid body: %w", err)
+	}
+	return product, nil
+}
+
+// CatalogueFunc adapts a function to a Catalogue
+type CatalogueFunc func(ctx context.Context, sku string) (Product, error)
+
+func (f CatalogueFunc) Product(ctx context.Context, sku string) (Product, error) {
+	return f(ctx, sku)
+}
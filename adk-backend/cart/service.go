@@ -0,0 +1,295 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrUnknownProduct  = errors.New("product not in catalogue")
+	ErrOutOfStock      = errors.New("product is out of stock")
+	ErrNotInCart       = errors.New("product is not in the cart")
+	ErrEmptyCart       = errors.New("cart is empty")
+	ErrInvalidQuantity = errors.New("quantity must be between 1 and 10")
+	// ErrNotConfirmed means no recent checkout request is waiting for confirmation
+	ErrNotConfirmed = errors.New("no checkout awaiting confirmation")
+	// ErrPriceChanged means the catalogue price moved since the item was added;
+	// the cart has been repriced and the customer must confirm again
+	ErrPriceChanged = errors.New("catalogue prices changed")
+)
+
+// NibError reports a missing or unavailable nib choice
+type NibError struct {
+	SKU       string
+	Requested string
+	Options   []string
+}
+
+func (e *NibError) Error() string {
+	if e.Requested == "" {
+		return fmt.Sprintf("choose a nib for %s: %s", e.SKU, strings.Join(e.Options, ", "))
+	}
+	return fmt.Sprintf("nib %s is not available for %s, choose from %s", e.Requested, e.SKU, strings.Join(e.Options, ", "))
+}
+
+const (
+	// maxQuantity caps a single cart line
+	maxQuantity = 10
+	// confirmationWindow is how long a chat checkout request waits for the
+	// customer's confirmation
+	confirmationWindow = 15 * time.Minute
+)
+
+// Service validates cart changes against the catalogue and places orders
+type Service struct {
+	store     Store
+	catalogue Catalogue
+	// nibs lists the nib options per SKU; SKUs without options take no nib
+	nibs map[string][]string
+
+	// mu serialises read-modify-write cycles on carts
+	mu sync.Mutex
+}
+
+func NewService(store Store, catalogue Catalogue, nibs map[string][]string) *Service {
+	return &Service{store: store, catalogue: catalogue, nibs: nibs}
+}
+
+// View returns the session's cart
+func (s *Service) View(ctx context.Context, sessionID string) (Cart, error) {
+	return s.store.Get(ctx, sessionID)
+}
+
+// Add puts quantity of a pen in the cart at its current catalogue price
+func (s *Service) Add(ctx context.Context, sessionID, userID, sku, nib string, quantity int) (Cart, error) {
+	if quantity < 1 || quantity > maxQuantity {
+		return Cart{}, ErrInvalidQuantity
+	}
+	product, err := s.catalogue.Product(ctx, sku)
+	if err != nil {
+		return Cart{}, err
+	}
+	if !product.InStock {
+		return Cart{}, ErrOutOfStock
+	}
+	nib, err = s.resolveNib(sku, nib)
+	if err != nil {
+		return Cart{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, err := s.store.Get(ctx, sessionID)
+	if err != nil {
+		return Cart{}, err
+	}
+	if i := cart.find(sku, nib); i >= 0 {
+		if cart.Items[i].Quantity+quantity > maxQuantity {
+			return Cart{}, ErrInvalidQuantity
+		}
+		cart.Items[i].Quantity += quantity
+		cart.Items[i].UnitPriceUSD = product.PriceUSD
+	} else {
+		cart.Items = append(cart.Items, Item{
+			SKU:          sku,
+			Name:         product.Name,
+			Brand:        product.Brand,
+			Nib:          nib,
+			Quantity:     quantity,
+			UnitPriceUSD: product.PriceUSD,
+		})
+	}
+	if cart.UserID == "" {
+		cart.UserID = userID
+	}
+	err = s.save(ctx, &cart)
+	return cart, err
+}
+
+// Remove drops a pen from the cart; an empty nib removes every nib of that pen
+func (s *Service) Remove(ctx context.Context, sessionID, sku, nib string) (Cart, error) {
+	nib = NormalizeNib(nib)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, err := s.store.Get(ctx, sessionID)
+	if err != nil {
+		return Cart{}, err
+	}
+	kept := cart.Items[:0]
+	for _, item := range cart.Items {
+		if item.SKU == sku && (nib == "" || NormalizeNib(item.Nib) == nib) {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	if len(kept) == len(cart.Items) {
+		return cart, ErrNotInCart
+	}
+	cart.Items = kept
+	err = s.save(ctx, &cart)
+	return cart, err
+}
+
+// RequestCheckout reprices the cart and marks it as waiting for the
+// customer's confirmation
+func (s *Service) RequestCheckout(ctx context.Context, sessionID string) (Cart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, err := s.store.Get(ctx, sessionID)
+	if err != nil {
+		return Cart{}, err
+	}
+	if len(cart.Items) == 0 {
+		return cart, ErrEmptyCart
+	}
+	if _, err := s.reprice(ctx, &cart); err != nil {
+		return cart, err
+	}
+
+	now := time.Now()
+	cart.CheckoutRequestedAt = &now
+	return cart, s.store.Save(ctx, cart)
+}
+
+// awaitingConfirmation reports whether the customer asked to check out
+// recently and has not changed the cart since
+func awaitingConfirmation(cart Cart) bool {
+	return cart.CheckoutRequestedAt != nil && time.Since(*cart.CheckoutRequestedAt) < confirmationWindow
+}
+
+// CancelCheckout withdraws a pending chat checkout request
+func (s *Service) CancelCheckout(ctx context.Context, sessionID string) (Cart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, err := s.store.Get(ctx, sessionID)
+	if err != nil {
+		return Cart{}, err
+	}
+	cart.CheckoutRequestedAt = nil
+	return cart, s.store.Save(ctx, cart)
+}
+
+// Checkout revalidates every line against the catalogue and places the order.
+// Callers are responsible for having the customer's confirmation. When a price
+// has changed the repriced cart is saved and ErrPriceChanged returned.
+func (s *Service) Checkout(ctx context.Context, sessionID, userID, source string) (Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, err := s.store.Get(ctx, sessionID)
+	if err != nil {
+		return Order{}, err
+	}
+	return s.placeOrder(ctx, cart, userID, source)
+}
+
+// ConfirmCheckout places the order only if the customer asked to check out
+// within the confirmation window and the cart has not changed since
+func (s *Service) ConfirmCheckout(ctx context.Context, sessionID, userID, source string) (Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, err := s.store.Get(ctx, sessionID)
+	if err != nil {
+		return Order{}, err
+	}
+	if !awaitingConfirmation(cart) {
+		return Order{}, ErrNotConfirmed
+	}
+	return s.placeOrder(ctx, cart, userID, source)
+}
+
+func (s *Service) placeOrder(ctx context.Context, cart Cart, userID, source string) (Order, error) {
+	if len(cart.Items) == 0 {
+		return Order{}, ErrEmptyCart
+	}
+	changed, err := s.reprice(ctx, &cart)
+	if err != nil {
+		return Order{}, err
+	}
+	if changed {
+		if err := s.save(ctx, &cart); err != nil {
+			return Order{}, err
+		}
+		return Order{}, ErrPriceChanged
+	}
+
+	if userID == "" {
+		userID = cart.UserID
+	}
+	order := Order{
+		ID:        primitive.NewObjectID().Hex(),
+		SessionID: cart.SessionID,
+		UserID:    userID,
+		Items:     cart.Items,
+		TotalUSD:  cart.TotalUSD(),
+		Status:    OrderStatusPlaced,
+		Source:    source,
+		PlacedAt:  time.Now(),
+	}
+	if err := s.store.PlaceOrder(ctx, order); err != nil {
+		return Order{}, err
+	}
+	return order, nil
+}
+
+// reprice refreshes every line from the catalogue and reports whether any
+// price changed. Lines that are no longer sold or in stock fail the cart.
+func (s *Service) reprice(ctx context.Context, cart *Cart) (bool, error) {
+	changed := false
+	for i, item := range cart.Items {
+		product, err := s.catalogue.Product(ctx, item.SKU)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", item.Name, err)
+		}
+		if !product.InStock {
+			return false, fmt.Errorf("%s: %w", item.Name, ErrOutOfStock)
+		}
+		if product.PriceUSD != item.UnitPriceUSD {
+			cart.Items[i].UnitPriceUSD = product.PriceUSD
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// save stores a changed cart, clearing any pending checkout request
+func (s *Service) save(ctx context.Context, cart *Cart) error {
+	cart.CheckoutRequestedAt = nil
+	cart.UpdatedAt = time.Now()
+	return s.store.Save(ctx, *cart)
+}
+
+// resolveNib validates the requested nib against the pen's options. Pens with
+// a single option take it by default.
+func (s *Service) resolveNib(sku, nib string) (string, error) {
+	options := s.nibs[sku]
+	if len(options) == 0 {
+		return "", nil
+	}
+
+	requested := NormalizeNib(nib)
+	if requested == "" {
+		if len(options) == 1 {
+			return options[0], nil
+		}
+		return "", &NibError{SKU: sku, Options: options}
+	}
+	for _, option := range options {
+		if strings.EqualFold(NormalizeNib(option), requested) {
+			return option, nil
+		}
+	}
+	return "", &NibError{SKU: sku, Requested: nib, Options: options}
+}
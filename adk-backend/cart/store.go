@@ -0,0 +1,99 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Store persists carts and placed orders
+type Store interface {
+	// Get returns the session's cart, or an empty one if it has none
+	Get(ctx context.Context, sessionID string) (Cart, error)
+	Save(ctx context.Context, cart Cart) error
+	// PlaceOrder writes the order and empties the session's cart
+	PlaceOrder(ctx context.Context, order Order) error
+}
+
+// MongoStore keeps carts in the carts collection and orders in orders
+type MongoStore struct {
+	carts  *mongo.Collection
+	orders *mongo.Collection
+}
+
+func NewMongoStore(carts, orders *mongo.Collection) *MongoStore {
+	return &MongoStore{carts: carts, orders: orders}
+}
+
+// EnsureIndexes creates the order lookup indexes
+func (ms *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := ms.orders.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "placed_at", Value: -1}}},
+		{Keys: bson.D{{Key: "session_id", Value: 1}}},
+	})
+	return err
+}
+
+func (ms *MongoStore) Get(ctx context.Context, sessionID string) (Cart, error) {
+	var cart Cart
+	err := ms.carts.FindOne(ctx, bson.M{"_id": sessionID}).Decode(&cart)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Cart{SessionID: sessionID}, nil
+	}
+	return cart, err
+}
+
+func (ms *MongoStore) Save(ctx context.Context, cart Cart) error {
+	_, err := ms.carts.ReplaceOne(ctx, bson.M{"_id": cart.SessionID}, cart, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (ms *MongoStore) PlaceOrder(ctx context.Context, order Order) error {
+	if _, err := ms.orders.InsertOne(ctx, order); err != nil {
+		return err
+	}
+	_, err := ms.carts.DeleteOne(ctx, bson.M{"_id": order.SessionID})
+	return err
+}
+
+// MemoryStore keeps carts and orders in process; used when MongoDB is unavailable
+type MemoryStore struct {
+	mu     sync.Mutex
+	carts  map[string]Cart
+	orders []Order
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{carts: make(map[string]Cart)}
+}
+
+func (ms *MemoryStore) Get(ctx context.Context, sessionID string) (Cart, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	cart, ok := ms.carts[sessionID]
+	if !ok {
+		return Cart{SessionID: sessionID}, nil
+	}
+	cart.Items = append([]Item(nil), cart.Items...)
+	return cart, nil
+}
+
+func (ms *MemoryStore) Save(ctx context.Context, cart Cart) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	cart.Items = append([]Item(nil), cart.Items...)
+	ms.carts[cart.SessionID] = cart
+	return nil
+}
+
+func (ms *MemoryStore) PlaceOrder(ctx context.Context, order Order) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.orders = append(ms.orders, order)
+	delete(ms.carts, order.SessionID)
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"pen-shop/auth"
+	"pen-shop/cart"
+)
+
+// maxCartBodyBytes caps the size of a cart request body
+const maxCartBodyBytes = 4 << 10
+
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{8,128}$`)
+
+// newSessionID returns an unguessable session ID; sessions key carts, so
+// they must not be enumerable
+func newSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "pen_session_" + time.Now().Format("20060102150405.000000000")
+	}
+	return "pen_session_" + hex.EncodeToString(b)
+}
+
+func validSessionID(sessionID string) bool {
+	return sessionIDPattern.MatchString(sessionID)
+}
+
+// ownsCart reports whether userID may use the session's cart. Carts started
+// anonymously are open to whoever holds the session ID. When the owner cannot
+// be read, access is denied and the error returned.
+func (psma *PenShopMultiAgent) ownsCart(ctx context.Context, sessionID, userID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	current, err := psma.carts.View(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	return current.UserID == "" || current.UserID == userID, nil
+}
+
+// cartSession reads and authorizes the {session} path variable, writing an
+// error response and returning false when the caller may not use it
+func (psma *PenShopMultiAgent) cartSession(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	sessionID := mux.Vars(r)["session"]
+	if !validSessionID(sessionID) {
+		http.Error(w, "Invalid session", http.StatusBadRequest)
+		return "", "", false
+	}
+
+	var userID string
+	if principal := auth.PrincipalFrom(r.Context()); principal != nil {
+		userID = principal.Subject
+	}
+	owns, err := psma.ownsCart(r.Context(), sessionID, userID)
+	if err != nil {
+		psma.logger.Error("Failed to check cart owner: %v", err)
+		http.Error(w, "Cart unavailable", http.StatusServiceUnavailable)
+		return "", "", false
+	}
+	// Report other users' carts as missing rather than forbidden
+	if !owns {
+		http.Error(w, "Cart not found", http.StatusNotFound)
+		return "", "", false
+	}
+	return sessionID, userID, true
+}
+
+// handleGetCart serves GET /api/cart/{session}
+func (psma *PenShopMultiAgent) handleGetCart(w http.ResponseWriter, r *http.Request) {
+	sessionID, _, ok := psma.cartSession(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	current, err := psma.carts.View(ctx, sessionID)
+	if err != nil {
+		psma.writeCartError(w, err)
+		return
+	}
+	writeCart(w, http.StatusOK, current)
+}
+
+// handleAddCartItem serves POST /api/cart/{session}/items with a body of
+// {"sku": "pen-011", "nib": "F", "quantity": 1}
+func (psma *PenShopMultiAgent) handleAddCartItem(w http.ResponseWriter, r *http.Request) {
+	sessionID, userID, ok := psma.cartSession(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		SKU      string `json:"sku"`
+		Nib      string `json:"nib"`
+		Quantity int    `json:"quantity"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxCartBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.SKU == "" {
+		http.Error(w, "sku is required", http.StatusBadRequest)
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	updated, err := psma.carts.Add(ctx, sessionID, userID, req.SKU, req.Nib, req.Quantity)
+	if err != nil {
+		psma.writeCartError(w, err)
+		return
+	}
+	writeCart(w, http.StatusOK, updated)
+}
+
+// handleRemoveCartItem serves DELETE /api/cart/{session}/items/{sku}; an
+// optional ?nib= removes only that nib
+func (psma *PenShopMultiAgent) handleRemoveCartItem(w http.ResponseWriter, r *http.Request) {
+	sessionID, _, ok := psma.cartSession(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	updated, err := psma.carts.Remove(ctx, sessionID, mux.Vars(r)["sku"], r.URL.Query().Get("nib"))
+	if err != nil {
+		psma.writeCartError(w, err)
+		return
+	}
+	writeCart(w, http.StatusOK, updated)
+}
+
+// handleCheckout serves POST /api/cart/{session}/checkout. The body must
+// carry {"confirm": true} so an order is never placed by accident.
+func (psma *PenShopMultiAgent) handleCheckout(w http.ResponseWriter, r *http.Request) {
+	sessionID, userID, ok := psma.cartSession(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Confirm bool `json:"confirm"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxCartBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if !req.Confirm {
+		http.Error(w, "Checkout must be confirmed with \"confirm\": true", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	order, err := psma.carts.Checkout(ctx, sessionID, userID, "api")
+	if err != nil {
+		psma.writeCartError(w, err)
+		return
+	}
+	psma.logger.Info("🧾 Order %s placed for session %s (%d items)", order.ID, sessionID, len(order.Items))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+func writeCart(w http.ResponseWriter, status int, current cart.Cart) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cart":      current,
+		"total_usd": current.TotalUSD(),
+		"count":     current.Count(),
+	})
+}
+
+// writeCartError maps cart errors onto HTTP statuses
+func (psma *PenShopMultiAgent) writeCartError(w http.ResponseWriter, err error) {
+	var nibErr *cart.NibError
+	switch {
+	case errors.As(err, &nibErr), errors.Is(err, cart.ErrInvalidQuantity):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, cart.ErrUnknownProduct), errors.Is(err, cart.ErrNotInCart):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, cart.ErrOutOfStock), errors.Is(err, cart.ErrEmptyCart), errors.Is(err, cart.ErrPriceChanged):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		psma.logger.Error("Cart operation failed: %v", err)
+		http.Error(w, "Cart operation failed", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,175 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"pen-shop/models"
+)
+
+// ErrNotFound is returned for unknown or expired execution IDs
+var ErrNotFound = errors.New("workflow execution not found")
+
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Execution is a workflow run as of its last checkpoint
+type Execution struct {
+	ID     string       `json:"id"`
+	Status string       `json:"status"`
+	Query  models.Query `json:"query"`
+	// Owner is the authenticated subject that started the run, "" when
+	// it was anonymous
+	Owner string `json:"owner,omitempty"`
+	// CompletedSteps lists the steps that succeeded, in order
+	CompletedSteps []string `json:"completed_steps"`
+	// State is the workflow's own snapshot: results so far, step outcomes and,
+	// once completed, the final response
+	State     json.RawMessage `json:"state"`
+	Error     string          `json:"error,omitempty"`
+	Attempts  int             `json:"attempts"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Store persists executions after every step
+type Store interface {
+	Save(ctx context.Context, exec Execution) error
+	Get(ctx context.Context, id string) (Execution, error)
+}
+
+// MemoryStore keeps executions in process; they do not survive a restart
+type MemoryStore struct {
+	retention time.Duration
+
+	mu         sync.Mutex
+	executions map[string]Execution
+}
+
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{retention: retention, executions: make(map[string]Execution)}
+}
+
+func (ms *MemoryStore) Save(ctx context.Context, exec Execution) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	// Drop expired executions as new ones arrive
+	for id, existing := range ms.executions {
+		if time.Since(existing.UpdatedAt) > ms.retention {
+			delete(ms.executions, id)
+		}
+	}
+	exec.State = append(json.RawMessage(nil), exec.State...)
+	ms.executions[exec.ID] = exec
+	return nil
+}
+
+func (ms *MemoryStore) Get(ctx context.Context, id string) (Execution, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	exec, ok := ms.executions[id]
+	if !ok || time.Since(exec.UpdatedAt) > ms.retention {
+		return Execution{}, ErrNotFound
+	}
+	return exec, nil
+}
+
+// MongoStore keeps executions in the workflow_executions collection so any
+// replica can resume them
+type MongoStore struct {
+	collection *mongo.Collection
+	retention  time.Duration
+}
+
+func NewMongoStore(collection *mongo.Collection, retention time.Duration) *MongoStore {
+	return &MongoStore{collection: collection, retention: retention}
+}
+
+// mongoExecution stores the query and state as JSON so agent metadata
+// round-trips unchanged
+type mongoExecution struct {
+	ID             string    `bson:"_id"`
+	Status         string    `bson:"status"`
+	UserID         string    `bson:"user_id,omitempty"`
+	Query          string    `bson:"query"`
+	CompletedSteps []string  `bson:"completed_steps"`
+	State          string    `bson:"state"`
+	Error          string    `bson:"error,omitempty"`
+	Attempts       int       `bson:"attempts"`
+	CreatedAt      time.Time `bson:"created_at"`
+	UpdatedAt      time.Time `bson:"updated_at"`
+	ExpiresAt      time.Time `bson:"expires_at"`
+}
+
+// EnsureIndexes expires executions after the retention period
+func (ms *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := ms.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "updated_at", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+func (ms *MongoStore) Save(ctx context.Context, exec Execution) error {
+	query, err := json.Marshal(exec.Query)
+	if err != nil {
+		return err
+	}
+	doc := mongoExecution{
+		ID:             exec.ID,
+		Status:         exec.Status,
+		UserID:         exec.Owner,
+		Query:          string(query),
+		CompletedSteps: exec.CompletedSteps,
+		State:          string(exec.State),
+		Error:          exec.Error,
+		Attempts:       exec.Attempts,
+		CreatedAt:      exec.CreatedAt,
+		UpdatedAt:      exec.UpdatedAt,
+		ExpiresAt:      exec.UpdatedAt.Add(ms.retention),
+	}
+	_, err = ms.collection.ReplaceOne(ctx, bson.M{"_id": exec.ID}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (ms *MongoStore) Get(ctx context.Context, id string) (Execution, error) {
+	var doc mongoExecution
+	err := ms.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Execution{}, ErrNotFound
+	}
+	if err != nil {
+		return Execution{}, err
+	}
+
+	exec := Execution{
+		ID:             doc.ID,
+		Status:         doc.Status,
+		Owner:          doc.UserID,
+		CompletedSteps: doc.CompletedSteps,
+		State:          json.RawMessage(doc.State),
+		Error:          doc.Error,
+		Attempts:       doc.Attempts,
+		CreatedAt:      doc.CreatedAt,
+		UpdatedAt:      doc.UpdatedAt,
+	}
+	if err := json.Unmarshal([]byte(doc.Query), &exec.Query); err != nil {
+		return Execution{}, err
+	}
+	return exec, nil
+}
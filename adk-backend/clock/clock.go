@@ -0,0 +1,15 @@
+package clock
+
+import "time"
+
+// Clock lets schedules, timeouts and backoff be driven by a fake clock in tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// System is the real wall clock
+type System struct{}
+
+func (System) Now() time.Time                         { return time.Now() }
+func (System) After(d time.Duration) <-chan time.Time { return time.After(d) }
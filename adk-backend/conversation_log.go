@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"pen-shop/agents"
+	"pen-shop/conversations"
+	"pen-shop/llm"
+	"pen-shop/models"
+)
+
+// conversationLog stores chat records and answers the admin queries over them
+type conversationLog interface {
+	Insert(ctx context.Context, rec conversations.Record) error
+	Get(ctx context.Context, id string) (conversations.Record, error)
+	List(ctx context.Context, filter conversations.Filter) ([]conversations.Record, int64, error)
+}
+
+// newConversationRecord starts the audit record for a chat request
+func newConversationRecord(query models.Query, sessionID, clientIP string) conversations.Record {
+	rec := conversations.Record{
+		ID:        primitive.NewObjectID().Hex(),
+		QueryID:   query.ID,
+		SessionID: sessionID,
+		UserID:    query.UserID,
+		ClientIP:  clientIP,
+		Request: conversations.RequestRecord{
+			Message: query.Content,
+			Source:  "web_ui",
+		},
+		AgentMode: "sequential",
+		Timestamp: time.Now(),
+	}
+	if query.Principal != nil {
+		rec.Tenant = query.Principal.Tenant
+		rec.AuthMethod = query.Principal.Method
+	}
+	return rec
+}
+
+// completeConversationRecord fills in the workflow outcome
+func completeConversationRecord(rec *conversations.Record, response models.Response, tracker *llm.UsageTracker) {
+	rec.Status = conversations.StatusCompleted
+	rec.Response = conversations.ResponseRecord{
+		Content:    response.Content,
+		AgentName:  response.AgentName,
+		Confidence: response.Confidence,
+	}
+
+	if steps, ok := response.Metadata["steps"].([]agents.StepResult); ok {
+		recordSteps(rec, steps)
+	}
+
+	rec.ToolCalls = tracker.Calls()
+	rec.TokensUsed, rec.CostUSD = tracker.Totals()
+}
+
+// failConversation logs a chat whose workflow failed, with the step outcomes
+// recorded before the failure
+func (psma *PenShopMultiAgent) failConversation(rec conversations.Record, err error, tracker *llm.UsageTracker) {
+	rec.Status = conversations.StatusFailed
+	rec.Error = err.Error()
+	rec.ToolCalls = tracker.Calls()
+	var workflowErr *agents.WorkflowError
+	if errors.As(err, &workflowErr) {
+		recordSteps(&rec, workflowErr.Steps)
+	}
+	psma.logConversation(rec)
+}
+
+// recordSteps stores every step outcome; only steps that succeeded count as
+// agents used
+func recordSteps(rec *conversations.Record, steps []agents.StepResult) {
+	for _, step := range steps {
+		if step.Status == agents.StepSucceeded {
+			rec.AgentsUsed = append(rec.AgentsUsed, step.AgentName)
+		}
+		rec.Steps = append(rec.Steps, conversations.StepRecord{
+			AgentName:  step.AgentName,
+			Status:     string(step.Status),
+			Required:   step.Required,
+			Content:    step.Content,
+			Confidence: step.Confidence,
+			DurationMs: step.DurationMs,
+			Error:      step.Error,
+			Metadata:   step.Metadata,
+		})
+	}
+}
+
+// stepSummaries lists each step's outcome without its content, for API responses
+func stepSummaries(steps []agents.StepResult) []map[string]interface{} {
+	summaries := make([]map[string]interface{}, 0, len(steps))
+	for _, step := range steps {
+		summary := map[string]interface{}{
+			"agent":       step.AgentName,
+			"status":      step.Status,
+			"required":    step.Required,
+			"duration_ms": step.DurationMs,
+		}
+		if step.Error != "" {
+			summary["error"] = step.Error
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// logConversation redacts and stores the record in the background
+func (psma *PenShopMultiAgent) logConversation(rec conversations.Record) {
+	if psma.conversations == nil {
+		return
+	}
+
+	rec.LatencyMs = time.Since(rec.Timestamp).Milliseconds()
+	conversations.RedactRecord(&rec)
+
+	psma.pendingWrites.Add(1)
+	go func() {
+		defer psma.pendingWrites.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := psma.conversations.Insert(ctx, rec); err != nil {
+			psma.logger.Error("Failed to log conversation %s: %v", rec.ID, err)
+		}
+	}()
+}
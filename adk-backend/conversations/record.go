@@ -0,0 +1,76 @@
+package conversations
+
+import (
+	"time"
+
+	"pen-shop/llm"
+)
+
+const (
+	StatusCompleted       = "completed"
+	StatusFailed          = "failed"
+	StatusSecurityBlocked = "security_blocked"
+)
+
+// Record is one chat exchange as stored in the ai_conversations collection
+type Record struct {
+	ID        string `json:"id" bson:"_id"`
+	QueryID   string `json:"query_id" bson:"query_id"`
+	SessionID string `json:"session_id" bson:"session_id"`
+
+	UserID     string `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Tenant     string `json:"tenant,omitempty" bson:"tenant,omitempty"`
+	AuthMethod string `json:"auth_method,omitempty" bson:"auth_method,omitempty"`
+	ClientIP   string `json:"client_ip,omitempty" bson:"client_ip,omitempty"`
+
+	Request  RequestRecord  `json:"request" bson:"request"`
+	Response ResponseRecord `json:"response" bson:"response"`
+
+	AgentMode  string           `json:"agent_mode" bson:"agent_mode"`
+	AgentsUsed []string         `json:"agents_used,omitempty" bson:"agents_used,omitempty"`
+	Steps      []StepRecord     `json:"steps,omitempty" bson:"steps,omitempty"`
+	ToolCalls  []llm.CallRecord `json:"tool_calls,omitempty" bson:"tool_calls,omitempty"`
+
+	Status          string    `json:"status" bson:"status"`
+	Error           string    `json:"error,omitempty" bson:"error,omitempty"`
+	SecurityBlocked bool      `json:"security_blocked" bson:"security_blocked"`
+	Redaction       Redaction `json:"redaction" bson:"redaction"`
+
+	LatencyMs  int64   `json:"latency_ms" bson:"latency_ms"`
+	TokensUsed int     `json:"tokens_used" bson:"tokens_used"`
+	CostUSD    float64 `json:"cost_usd" bson:"cost_usd"`
+
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// RequestRecord is the customer's side of the exchange
+type RequestRecord struct {
+	Message string `json:"message" bson:"message"`
+	Source  string `json:"source,omitempty" bson:"source,omitempty"`
+}
+
+// ResponseRecord is the answer returned to the customer
+type ResponseRecord struct {
+	Content    string  `json:"content" bson:"content"`
+	AgentName  string  `json:"agent_name,omitempty" bson:"agent_name,omitempty"`
+	Confidence float64 `json:"confidence,omitempty" bson:"confidence,omitempty"`
+}
+
+// StepRecord is the outcome of one workflow step
+type StepRecord struct {
+	AgentName  string                 `json:"agent_name" bson:"agent_name"`
+	Status     string                 `json:"status,omitempty" bson:"status,omitempty"`
+	Required   bool                   `json:"required" bson:"required"`
+	Content    string                 `json:"content,omitempty" bson:"content,omitempty"`
+	Confidence float64                `json:"confidence,omitempty" bson:"confidence,omitempty"`
+	DurationMs int64                  `json:"duration_ms" bson:"duration_ms"`
+	Error      string                 `json:"error,omitempty" bson:"error,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty" bson:"metadata,omitempty"`
+}
+
+// Redaction records whether personal data was masked before storage
+type Redaction struct {
+	Applied bool     `json:"applied" bson:"applied"`
+	Kinds   []string `json:"kinds,omitempty" bson:"kinds,omitempty"`
+}
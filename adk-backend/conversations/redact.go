@@ -0,0 +1,160 @@
+package conversations
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var redactionPatterns = []struct {
+	kind        string
+	pattern     *regexp.Regexp
+	replacement string
+	// accept vets each match in context; nil accepts every match
+	accept func(text string, start, end int) bool
+}{
+	{kind: "email", pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), replacement: "[REDACTED_EMAIL]"},
+	{kind: "card_number", pattern: regexp.MustCompile(`\b(?:\d[ -]?){13,19}\b`), replacement: "[REDACTED_CARD]"},
+	// An international number, or digit groups split by single spaces or dashes
+	{kind: "phone", pattern: regexp.MustCompile(`\+\d[\d ()-]{5,}\d|(?:\(\d{2,5}\)[ -]?|\d{2,5}[ -])\d{2,5}(?:[ -]\d{2,5})*`), replacement: "[REDACTED_PHONE]", accept: phoneLike},
+	{kind: "api_key", pattern: regexp.MustCompile(`\b(?:sk|pk|api)[-_][A-Za-z0-9_-]{16,}\b`), replacement: "[REDACTED_KEY]"},
+}
+
+// datePattern matches dates such as 2026-10-19 or 19-10-2026, which have the
+// digit groups of a phone number
+var datePattern = regexp.MustCompile(`^(?:\d{4}-\d{1,2}-\d{1,2}|\d{1,2}-\d{1,2}-\d{4})$`)
+
+// phoneLike rejects matches that are really prices, times, timestamps or
+// dates: too few or too many digits, part of a longer number or word, a
+// decimal, or followed by ":" or "T" as in "12:30" and "2026-10-19T12:30"
+func phoneLike(text string, start, end int) bool {
+	match := text[start:end]
+	digits := 0
+	for _, r := range match {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	if digits < 7 || digits > 15 || datePattern.MatchString(match) {
+		return false
+	}
+
+	if start > 0 {
+		before := rune(text[start-1])
+		if unicode.IsDigit(before) || unicode.IsLetter(before) || before == '.' || before == ',' {
+			return false
+		}
+	}
+	if end < len(text) {
+		after := rune(text[end])
+		if unicode.IsDigit(after) || unicode.IsLetter(after) || after == ':' {
+			return false
+		}
+		if (after == '.' || after == ',') && end+1 < len(text) && unicode.IsDigit(rune(text[end+1])) {
+			return false
+		}
+	}
+	return true
+}
+
+// Redact masks personal data and secrets in text before it is stored.
+// It returns the masked text and the kinds of data that were found.
+func Redact(text string) (string, []string) {
+	var kinds []string
+	for _, rp := range redactionPatterns {
+		var masked strings.Builder
+		last, found := 0, false
+		for _, loc := range rp.pattern.FindAllStringIndex(text, -1) {
+			if rp.accept != nil && !rp.accept(text, loc[0], loc[1]) {
+				continue
+			}
+			masked.WriteString(text[last:loc[0]])
+			masked.WriteString(rp.replacement)
+			last, found = loc[1], true
+		}
+		if found {
+			masked.WriteString(text[last:])
+			text = masked.String()
+			kinds = append(kinds, rp.kind)
+		}
+	}
+	return text, kinds
+}
+
+// RedactRecord masks the request, response, errors and step outputs of a
+// record in place. Step metadata is replaced by a redacted copy, since the
+// original may still be shared with the response.
+func RedactRecord(rec *Record) {
+	seen := make(map[string]bool)
+	redact := func(text string) string {
+		masked, kinds := Redact(text)
+		for _, kind := range kinds {
+			seen[kind] = true
+		}
+		return masked
+	}
+
+	rec.Request.Message = redact(rec.Request.Message)
+	rec.Response.Content = redact(rec.Response.Content)
+	rec.Error = redact(rec.Error)
+	for i := range rec.Steps {
+		step := &rec.Steps[i]
+		step.Content = redact(step.Content)
+		step.Error = redact(step.Error)
+		if step.Metadata != nil {
+			step.Metadata = redactValue(step.Metadata, redact).(map[string]interface{})
+		}
+	}
+
+	rec.Redaction = Redaction{Applied: len(seen) > 0}
+	for kind := range seen {
+		rec.Redaction.Kinds = append(rec.Redaction.Kinds, kind)
+	}
+	sort.Strings(rec.Redaction.Kinds)
+}
+
+// redactValue returns a copy of value with every string in it masked. Values
+// of other types (agent results such as product lists) are masked in their
+// JSON form; numbers and booleans are kept as they are.
+func redactValue(value interface{}, redact func(string) string) interface{} {
+	switch v := value.(type) {
+	case nil, bool, int, int64, float64:
+		return v
+	case string:
+		return redact(v)
+	case []string:
+		masked := make([]string, len(v))
+		for i, s := range v {
+			masked[i] = redact(s)
+		}
+		return masked
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			masked[key] = redactValue(item, redact)
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = redactValue(item, redact)
+		}
+		return masked
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return value
+	}
+	switch decoded.(type) {
+	case string, map[string]interface{}, []interface{}:
+		return redactValue(decoded, redact)
+	}
+	return value
+}
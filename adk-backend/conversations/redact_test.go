@@ -0,0 +1,97 @@
+package conversations
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactRecordMasksSteps(t *testing.T) {
+	type note struct {
+		Text string `json:"text"`
+	}
+	metadata := map[string]interface{}{
+		"contact": "write to jane.doe@example.com",
+		"notes":   []interface{}{"call +1 555 010 9999"},
+		"nested":  map[string]interface{}{"owner": "jane.doe@example.com"},
+		"struct":  note{Text: "jane.doe@example.com"},
+		"count":   3,
+	}
+	rec := Record{
+		Request: RequestRecord{Message: "find me a pen"},
+		Steps: []StepRecord{{
+			AgentName: "pen_research",
+			Content:   "Emailed the shortlist to jane.doe@example.com",
+			Error:     "send to jane.doe@example.com failed",
+			Metadata:  metadata,
+		}},
+	}
+
+	RedactRecord(&rec)
+
+	step := rec.Steps[0]
+	if strings.Contains(step.Content, "jane.doe") || !strings.Contains(step.Content, "[REDACTED_EMAIL]") {
+		t.Errorf("step content not redacted: %q", step.Content)
+	}
+	if strings.Contains(step.Error, "jane.doe") {
+		t.Errorf("step error not redacted: %q", step.Error)
+	}
+	if got := step.Metadata["contact"]; got != "write to [REDACTED_EMAIL]" {
+		t.Errorf("metadata contact = %q", got)
+	}
+	if got := step.Metadata["notes"].([]interface{})[0]; got != "call [REDACTED_PHONE]" {
+		t.Errorf("metadata notes = %q", got)
+	}
+	if got := step.Metadata["nested"].(map[string]interface{})["owner"]; got != "[REDACTED_EMAIL]" {
+		t.Errorf("nested metadata = %q", got)
+	}
+	if got := step.Metadata["struct"].(map[string]interface{})["text"]; got != "[REDACTED_EMAIL]" {
+		t.Errorf("struct metadata = %q", got)
+	}
+	if got := step.Metadata["count"]; got != 3 {
+		t.Errorf("count = %v, want 3", got)
+	}
+
+	// The response shares the agent's metadata, which must be left alone
+	if metadata["contact"] != "write to jane.doe@example.com" {
+		t.Errorf("original metadata was modified: %q", metadata["contact"])
+	}
+
+	if !rec.Redaction.Applied || strings.Join(rec.Redaction.Kinds, ",") != "email,phone" {
+		t.Errorf("redaction = %+v, want email and phone", rec.Redaction)
+	}
+}
+
+func TestRedactRecordWithoutPersonalData(t *testing.T) {
+	rec := Record{
+		Request:  RequestRecord{Message: "a fountain pen under $50"},
+		Response: ResponseRecord{Content: "Try the Lamy Safari"},
+		Steps:    []StepRecord{{AgentName: "pen_research", Content: "3 pens found"}},
+	}
+	RedactRecord(&rec)
+	if rec.Redaction.Applied || rec.Steps[0].Content != "3 pens found" {
+		t.Errorf("unexpected redaction: %+v", rec)
+	}
+}
+
+func TestRedactPhoneNumbers(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"call +1 555 010 9999", "call [REDACTED_PHONE]"},
+		{"call +1 (555) 010-9999 today", "call [REDACTED_PHONE] today"},
+		{"ring +447946095812.", "ring [REDACTED_PHONE]."},
+		{"my number is 555-010-9999", "my number is [REDACTED_PHONE]"},
+		{"or (020) 7946 0958", "or [REDACTED_PHONE]"},
+		{"delivery on 2026-10-19 12:30", "delivery on 2026-10-19 12:30"},
+		{"checked at 2026-10-19T12:30:00Z", "checked at 2026-10-19T12:30:00Z"},
+		{"ordered 19-10-2026", "ordered 19-10-2026"},
+		{"prices 125.00 165.00 895.00", "prices 125.00 165.00 895.00"},
+		{"order 1234567", "order 1234567"},
+	}
+	for _, tc := range cases {
+		if got, _ := Redact(tc.text); got != tc.want {
+			t.Errorf("Redact(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
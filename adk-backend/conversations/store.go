@@ -0,0 +1,133 @@
+package conversations
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrNotFound is returned when a conversation ID does not exist
+var ErrNotFound = errors.New("conversation not found")
+
+// Filter narrows a conversation listing. Zero values are ignored.
+type Filter struct {
+	UserID          string
+	Tenant          string
+	Status          string
+	Agent           string
+	SecurityBlocked *bool
+	Since           time.Time
+	Until           time.Time
+	Limit           int64
+	Offset          int64
+}
+
+// Store persists conversation records in MongoDB
+type Store struct {
+	collection *mongo.Collection
+	retention  time.Duration
+}
+
+// NewStore keeps records for retention before MongoDB expires them
+func NewStore(collection *mongo.Collection, retention time.Duration) *Store {
+	return &Store{collection: collection, retention: retention}
+}
+
+// EnsureIndexes creates the lookup indexes and the retention TTL index
+func (s *Store) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "agents_used", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// Insert stores a record, setting its expiry from the retention period
+func (s *Store) Insert(ctx context.Context, rec Record) error {
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = time.Now()
+	}
+	rec.ExpiresAt = rec.Timestamp.Add(s.retention)
+
+	_, err := s.collection.InsertOne(ctx, rec)
+	return err
+}
+
+// Get returns a single record by ID
+func (s *Store) Get(ctx context.Context, id string) (Record, error) {
+	var rec Record
+	err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&rec)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Record{}, ErrNotFound
+	}
+	return rec, err
+}
+
+// List returns records matching filter, newest first, with the total match count
+func (s *Store) List(ctx context.Context, filter Filter) ([]Record, int64, error) {
+	query := bson.M{}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Tenant != "" {
+		query["tenant"] = filter.Tenant
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	} else {
+		// Skip legacy message-only documents written before the audit schema
+		query["status"] = bson.M{"$exists": true}
+	}
+	if filter.Agent != "" {
+		query["agents_used"] = filter.Agent
+	}
+	if filter.SecurityBlocked != nil {
+		query["security_blocked"] = *filter.SecurityBlocked
+	}
+	if !filter.Since.IsZero() || !filter.Until.IsZero() {
+		timeRange := bson.M{}
+		if !filter.Since.IsZero() {
+			timeRange["$gte"] = filter.Since
+		}
+		if !filter.Until.IsZero() {
+			timeRange["$lt"] = filter.Until
+		}
+		query["timestamp"] = timeRange
+	}
+
+	total, err := s.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+		SetLimit(limit).
+		SetSkip(filter.Offset)
+
+	cursor, err := s.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	records := []Record{}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
@@ -0,0 +1,89 @@
+package currency
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// USD is the currency catalogue prices are stored in
+const USD = "USD"
+
+// Currency describes how amounts in one ISO 4217 currency are written
+type Currency struct {
+	Code     string
+	Symbol   string
+	Decimals int
+}
+
+// currencies lists the storefront currencies we can parse and format
+var currencies = map[string]Currency{
+	"USD": {Code: "USD", Symbol: "$", Decimals: 2},
+	"EUR": {Code: "EUR", Symbol: "€", Decimals: 2},
+	"GBP": {Code: "GBP", Symbol: "£", Decimals: 2},
+	"JPY": {Code: "JPY", Symbol: "¥", Decimals: 0},
+	"CAD": {Code: "CAD", Symbol: "C$", Decimals: 2},
+	"AUD": {Code: "AUD", Symbol: "A$", Decimals: 2},
+	"CHF": {Code: "CHF", Symbol: "CHF ", Decimals: 2},
+	"INR": {Code: "INR", Symbol: "₹", Decimals: 2},
+}
+
+// Amount is a sum of money in a specific currency
+type Amount struct {
+	Value    float64 `json:"value"`
+	Currency string  `json:"currency"`
+}
+
+// Lookup returns the currency for an ISO code, case-insensitively
+func Lookup(code string) (Currency, bool) {
+	c, ok := currencies[strings.ToUpper(strings.TrimSpace(code))]
+	return c, ok
+}
+
+// Normalize returns the upper-case ISO code, or fallback if code is unknown
+func Normalize(code, fallback string) string {
+	if c, ok := Lookup(code); ok {
+		return c.Code
+	}
+	return fallback
+}
+
+// Format writes value in the currency's symbol, precision and thousands
+// grouping, e.g. "$1,250.00" or "¥5,000". Unknown codes are suffixed.
+func Format(value float64, code string) string {
+	c, ok := Lookup(code)
+	if !ok {
+		return formatNumber(value, 2) + " " + strings.ToUpper(code)
+	}
+
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	return sign + c.Symbol + formatNumber(value, c.Decimals)
+}
+
+// String formats the amount in its own currency
+func (a Amount) String() string {
+	return Format(a.Value, a.Currency)
+}
+
+func formatNumber(value float64, decimals int) string {
+	scale := math.Pow(10, float64(decimals))
+	text := strconv.FormatFloat(math.Round(value*scale)/scale, 'f', decimals, 64)
+
+	whole, fraction := text, ""
+	if dot := strings.IndexByte(text, '.'); dot >= 0 {
+		whole, fraction = text[:dot], text[dot:]
+	}
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	return grouped.String() + fraction
+}
@@ -0,0 +1,164 @@
+package currency
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// symbolCodes maps currency symbols to ISO codes. ¥ is read as yen.
+var symbolCodes = map[string]string{
+	"us$": "USD", "$": "USD", "€": "EUR", "£": "GBP", "¥": "JPY",
+	"c$": "CAD", "a$": "AUD", "₹": "INR",
+}
+
+// wordCodes maps ISO codes and the words customers use to ISO codes
+var wordCodes = map[string]string{
+	"usd": "USD", "dollar": "USD", "dollars": "USD", "buck": "USD", "bucks": "USD",
+	"eur": "EUR", "euro": "EUR", "euros": "EUR",
+	"gbp": "GBP", "pound": "GBP", "pounds": "GBP", "quid": "GBP", "sterling": "GBP",
+	"jpy": "JPY", "yen": "JPY",
+	"cad": "CAD", "aud": "AUD",
+	"chf": "CHF", "franc": "CHF", "francs": "CHF",
+	"inr": "INR", "rupee": "INR", "rupees": "INR",
+}
+
+const amountPattern = `(\d[\d,]*(?:\.\d+)?)`
+
+// currencyWords are the codes and words wordCodes knows, singular and plural
+const currencyWords = `usd|dollars?|bucks?|eur|euros?|gbp|pounds?|quid|sterling|jpy|yen|cad|aud|chf|francs?|inr|rupees?`
+
+var (
+	// "$50", "£ 100", "c$80", "eur 80", "usd80"
+	prefixPattern = regexp.MustCompile(`(?i)(us\$|c\$|a\$|\$|€|£|¥|₹|\b(?:usd|eur|gbp|jpy|cad|aud|chf|inr))\s*` + amountPattern)
+	// "80 euros", "5000¥", "100 gbp", "80euros"
+	suffixPattern = regexp.MustCompile(`(?i)` + amountPattern + `\s*(€|£|¥|₹|\$|(?:` + currencyWords + `)\b)`)
+	// any currency symbol or word, with or without an amount
+	currencyPattern = regexp.MustCompile(`(?i)(us\$|c\$|a\$|\$|€|£|¥|₹)|(?:^|[^\pL])(` + currencyWords + `)\b`)
+
+	budgetBefore = regexp.MustCompile(`(?i)(under|less\s+than|below|max|maximum|up\s+to|no\s+more\s+than|at\s+most|budget\s+(?:of|is)|within|spend)\s*$`)
+	budgetAfter  = regexp.MustCompile(`(?i)^\s*(or\s+less|or\s+under|max|maximum|tops|at\s+most)\b`)
+	// "I have $150 for a pen", "got 80 euros to spend"
+	fundsBefore = regexp.MustCompile(`(?i)\b(have|got)\s*$`)
+	fundsAfter  = regexp.MustCompile(`(?i)^\s*(for|to\s+spend)\b`)
+	// "between $50 and $100", "$50-100", "£50 to £80"
+	rangeStart = regexp.MustCompile(`(?i)\bbetween\s*$`)
+	rangeUpper = regexp.MustCompile(`(?i)^\s*(and|to|-|–)\s*`)
+	bareAmount = regexp.MustCompile(`^` + amountPattern + `\b`)
+)
+
+// mention is a money amount found in free text
+type mention struct {
+	amount     Amount
+	start, end int
+}
+
+// findAmounts returns every money amount in text, in order of appearance
+func findAmounts(text string) []mention {
+	var mentions []mention
+	taken := func(start, end int) bool {
+		for _, m := range mentions {
+			if start < m.end && end > m.start {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, loc := range prefixPattern.FindAllStringSubmatchIndex(text, -1) {
+		if value, ok := parseValue(text[loc[4]:loc[5]]); ok {
+			code := codeFor(text[loc[2]:loc[3]])
+			mentions = append(mentions, mention{Amount{value, code}, loc[0], loc[1]})
+		}
+	}
+	for _, loc := range suffixPattern.FindAllStringSubmatchIndex(text, -1) {
+		if taken(loc[0], loc[1]) {
+			continue
+		}
+		if value, ok := parseValue(text[loc[2]:loc[3]]); ok {
+			code := codeFor(text[loc[4]:loc[5]])
+			mentions = append(mentions, mention{Amount{value, code}, loc[0], loc[1]})
+		}
+	}
+
+	// Restore reading order after the two passes
+	sort.Slice(mentions, func(i, j int) bool {
+		return mentions[i].start < mentions[j].start
+	})
+	return mentions
+}
+
+func codeFor(token string) string {
+	token = strings.ToLower(strings.TrimSpace(token))
+	if code, ok := symbolCodes[token]; ok {
+		return code
+	}
+	return wordCodes[token]
+}
+
+func parseValue(text string) (float64, bool) {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(text, ",", ""), 64)
+	return value, err == nil && value > 0
+}
+
+// ParseBudget finds a spending limit such as "under £100", "80 euros or
+// less" or "max ¥5000" and returns it in the currency the customer used.
+// For a range like "between $50 and $100" the limit is the upper bound.
+func ParseBudget(text string) (Amount, bool) {
+	mentions := findAmounts(text)
+	for i, m := range mentions {
+		if upper, ok := rangeEnd(text, m, mentions[i+1:]); ok {
+			return upper, true
+		}
+		if budgetBefore.MatchString(text[:m.start]) || budgetAfter.MatchString(text[m.end:]) {
+			return m.amount, true
+		}
+		if fundsBefore.MatchString(text[:m.start]) && fundsAfter.MatchString(text[m.end:]) {
+			return m.amount, true
+		}
+	}
+	return Amount{}, false
+}
+
+// rangeEnd returns the upper bound of a range that starts at m, either the
+// next amount or a bare number in m's currency as in "$50-100"
+func rangeEnd(text string, m mention, rest []mention) (Amount, bool) {
+	join := rangeUpper.FindStringSubmatchIndex(text[m.end:])
+	if join == nil {
+		return Amount{}, false
+	}
+	// "the $50 and $100 pens" names two prices, not a range
+	if strings.EqualFold(text[m.end+join[2]:m.end+join[3]], "and") && !rangeStart.MatchString(text[:m.start]) {
+		return Amount{}, false
+	}
+	upperStart := m.end + join[1]
+	if len(rest) > 0 && rest[0].start == upperStart {
+		return rest[0].amount, true
+	}
+	if loc := bareAmount.FindStringSubmatchIndex(text[upperStart:]); loc != nil {
+		if value, ok := parseValue(text[upperStart+loc[2] : upperStart+loc[3]]); ok {
+			return Amount{value, m.amount.Currency}, true
+		}
+	}
+	return Amount{}, false
+}
+
+// Codes lists the currencies text mentions, in order of appearance, so "a
+// hundred quid" gives GBP even though it has no amount to parse
+func Codes(text string) []string {
+	var codes []string
+	for _, match := range currencyPattern.FindAllStringSubmatch(text, -1) {
+		codes = append(codes, codeFor(match[1]+match[2]))
+	}
+	return codes
+}
+
+// ParseAmount returns the first money amount in text, qualified or not
+func ParseAmount(text string) (Amount, bool) {
+	mentions := findAmounts(text)
+	if len(mentions) == 0 {
+		return Amount{}, false
+	}
+	return mentions[0].amount, true
+}
@@ -0,0 +1,74 @@
+package currency
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBudget(t *testing.T) {
+	cases := []struct {
+		text string
+		want Amount
+		ok   bool
+	}{
+		{"a fountain pen under £100", Amount{100, "GBP"}, true},
+		{"80 euros or less", Amount{80, "EUR"}, true},
+		{"gel pens, ¥5000 max", Amount{5000, "JPY"}, true},
+		{"something under 1,250.50 chf", Amount{1250.50, "CHF"}, true},
+		{"under 80euros please", Amount{80, "EUR"}, true},
+		{"no more than usd80", Amount{80, "USD"}, true},
+		{"up to c$120", Amount{120, "CAD"}, true},
+		{"between $50 and $100", Amount{100, "USD"}, true},
+		{"anything from £50 to £80", Amount{80, "GBP"}, true},
+		{"pens at $50-100", Amount{100, "USD"}, true},
+		{"I have $150 for a pen and ink", Amount{150, "USD"}, true},
+		{"got 80 euros to spend", Amount{80, "EUR"}, true},
+		{"I can spend 60 quid", Amount{60, "GBP"}, true},
+		{"compare the $50 and $100 pens", Amount{}, false},
+		{"is the $50 pen any good?", Amount{}, false},
+		{"a pen for my 3 kids", Amount{}, false},
+	}
+	for _, tc := range cases {
+		got, ok := ParseBudget(tc.text)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("ParseBudget(%q) = %+v, %v, want %+v, %v", tc.text, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	cases := []struct {
+		text string
+		want Amount
+		ok   bool
+	}{
+		{"is the $50 pen any good?", Amount{50, "USD"}, true},
+		{"it was 1,250 rupees", Amount{1250, "INR"}, true},
+		{"what about 30euros", Amount{30, "EUR"}, true},
+		{"a pen for my 3 kids", Amount{}, false},
+	}
+	for _, tc := range cases {
+		got, ok := ParseAmount(tc.text)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("ParseAmount(%q) = %+v, %v, want %+v, %v", tc.text, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestCodes(t *testing.T) {
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"around a hundred quid", []string{"GBP"}},
+		{"around a hundred bucks", []string{"USD"}},
+		{"80euros or £70", []string{"EUR", "GBP"}},
+		{"compounds and europe", nil},
+		{"a nice fountain pen", nil},
+	}
+	for _, tc := range cases {
+		if got := Codes(tc.text); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Codes(%q) = %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
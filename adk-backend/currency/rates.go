@@ -0,0 +1,128 @@
+package currency
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rates converts amounts between currencies
+type Rates interface {
+	Convert(value float64, from, to string) (float64, error)
+}
+
+// Table holds exchange rates as units of each currency per one unit of Base
+type Table struct {
+	Base    string             `json:"base"`
+	Rates   map[string]float64 `json:"rates"`
+	Updated time.Time          `json:"updated"`
+}
+
+// DefaultTable carries indicative rates used until a rates file is loaded
+func DefaultTable() Table {
+	return Table{
+		Base: USD,
+		Rates: map[string]float64{
+			"USD": 1,
+			"EUR": 0.92,
+			"GBP": 0.79,
+			"JPY": 150,
+			"CAD": 1.36,
+			"AUD": 1.52,
+			"CHF": 0.88,
+			"INR": 83,
+		},
+	}
+}
+
+// Convert converts value between any two currencies in the table
+func (t Table) Convert(value float64, from, to string) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return value, nil
+	}
+
+	fromRate, err := t.rate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := t.rate(to)
+	if err != nil {
+		return 0, err
+	}
+	return value / fromRate * toRate, nil
+}
+
+func (t Table) rate(code string) (float64, error) {
+	if code == strings.ToUpper(t.Base) {
+		return 1, nil
+	}
+	rate, ok := t.Rates[code]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("no exchange rate for %s", code)
+	}
+	return rate, nil
+}
+
+// FileRates serves rates from a JSON file that can be re-read at runtime
+type FileRates struct {
+	path string
+
+	mu    sync.RWMutex
+	table Table
+}
+
+// NewFileRates loads rates from path. Until a load succeeds the default
+// table is served, so a missing file degrades to indicative rates.
+func NewFileRates(path string) (*FileRates, error) {
+	fr := &FileRates{path: path, table: DefaultTable()}
+	return fr, fr.Refresh()
+}
+
+// Refresh re-reads the rates file, keeping the current table on error
+func (fr *FileRates) Refresh() error {
+	data, err := os.ReadFile(fr.path)
+	if err != nil {
+		return err
+	}
+
+	var table Table
+	if err := json.Unmarshal(data, &table); err != nil {
+		return fmt.Errorf("parse rates file: %w", err)
+	}
+	if table.Base == "" {
+		table.Base = USD
+	}
+	if len(table.Rates) == 0 {
+		return fmt.Errorf("rates file %s has no rates", fr.path)
+	}
+	normalized := make(map[string]float64, len(table.Rates))
+	for code, rate := range table.Rates {
+		normalized[strings.ToUpper(code)] = rate
+	}
+	table.Rates = normalized
+	if table.Updated.IsZero() {
+		if info, err := os.Stat(fr.path); err == nil {
+			table.Updated = info.ModTime()
+		}
+	}
+
+	fr.mu.Lock()
+	fr.table = table
+	fr.mu.Unlock()
+	return nil
+}
+
+// Table returns the rates currently in use
+func (fr *FileRates) Table() Table {
+	fr.mu.RLock()
+	defer fr.mu.RUnlock()
+	return fr.table
+}
+
+func (fr *FileRates) Convert(value float64, from, to string) (float64, error) {
+	return fr.Table().Convert(value, from, to)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"pen-shop/cart"
+	"pen-shop/checkpoint"
+	"pen-shop/clock"
+	"pen-shop/conversations"
+	"pen-shop/inventory"
+	"pen-shop/models"
+	"pen-shop/pricehistory"
+	"pen-shop/ratelimit"
+	"pen-shop/resilience"
+	"pen-shop/respcache"
+)
+
+// newResilience builds the per-dependency breakers, bulkheads and retry
+// policies, with overrides from the JSON file at RESILIENCE_CONFIG
+func newResilience(logger models.Logger) *resilience.Registry {
+	policies := resilience.DefaultPolicies()
+	if path := os.Getenv("RESILIENCE_CONFIG"); path != "" {
+		if loaded, err := resilience.LoadPolicies(path); err == nil {
+			policies = loaded
+			logger.Info("🛡️ Dependency policies loaded from %s", path)
+		} else {
+			logger.Error("❌ Resilience config %s: %v, using defaults", path, err)
+		}
+	}
+	return resilience.NewRegistry(policies, clock.System{})
+}
+
+// guardedCatalogue looks up products through the catalogue breaker. Unknown
+// SKUs are a healthy answer, so they neither retry nor count as failures.
+func guardedCatalogue(catalogue cart.Catalogue, dep *resilience.Dependency) cart.Catalogue {
+	return cart.CatalogueFunc(func(ctx context.Context, sku string) (cart.Product, error) {
+		return resilience.Call(ctx, dep, func(ctx context.Context) (cart.Product, error) {
+			product, err := catalogue.Product(ctx, sku)
+			if errors.Is(err, cart.ErrUnknownProduct) {
+				return product, resilience.Permanent(err)
+			}
+			return product, err
+		})
+	})
+}
+
+// guardedPriceSource lists catalogue prices through the catalogue breaker
+type guardedPriceSource struct {
+	source pricehistory.Source
+	dep    *resilience.Dependency
+}
+
+func (gs guardedPriceSource) Prices(ctx context.Context) ([]pricehistory.Snapshot, error) {
+	return resilience.Call(ctx, gs.dep, gs.source.Prices)
+}
+
+// catalogueStock reads availability for the stock poller from the same
+// catalogue listing the price recorder uses
+func catalogueStock(source pricehistory.Source) inventory.Source {
+	return inventory.SourceFunc(func(ctx context.Context) ([]inventory.Product, error) {
+		snapshots, err := source.Prices(ctx)
+		if err != nil {
+			return nil, err
+		}
+		products := make([]inventory.Product, 0, len(snapshots))
+		for _, snapshot := range snapshots {
+			products = append(products, inventory.Product{
+				SKU:      snapshot.SKU,
+				Name:     snapshot.Name,
+				PriceUSD: snapshot.PriceUSD,
+				InStock:  snapshot.InStock,
+			})
+		}
+		return products, nil
+	})
+}
+
+// guardedCartStore retries cart reads and whole-cart saves, but places an
+// order at most once
+type guardedCartStore struct {
+	store cart.Store
+	dep   *resilience.Dependency
+}
+
+func (gs guardedCartStore) Get(ctx context.Context, sessionID string) (cart.Cart, error) {
+	return resilience.Call(ctx, gs.dep, func(ctx context.Context) (cart.Cart, error) {
+		return gs.store.Get(ctx, sessionID)
+	})
+}
+
+func (gs guardedCartStore) Save(ctx context.Context, current cart.Cart) error {
+	return gs.dep.Do(ctx, func(ctx context.Context) error {
+		return gs.store.Save(ctx, current)
+	})
+}
+
+func (gs guardedCartStore) PlaceOrder(ctx context.Context, order cart.Order) error {
+	return gs.dep.DoOnce(ctx, func(ctx context.Context) error {
+		return gs.store.PlaceOrder(ctx, order)
+	})
+}
+
+// guardedQuotaStore never retries Add, which increments counters
+type guardedQuotaStore struct {
+	store ratelimit.QuotaStore
+	dep   *resilience.Dependency
+}
+
+func (gs guardedQuotaStore) Usage(ctx context.Context, subject string, day string) (ratelimit.Usage, error) {
+	return resilience.Call(ctx, gs.dep, func(ctx context.Context) (ratelimit.Usage, error) {
+		return gs.store.Usage(ctx, subject, day)
+	})
+}
+
+func (gs guardedQuotaStore) Add(ctx context.Context, subject string, day string, usage ratelimit.Usage) error {
+	return gs.dep.DoOnce(ctx, func(ctx context.Context) error {
+		return gs.store.Add(ctx, subject, day, usage)
+	})
+}
+
+// guardedPriceHistory never retries Append, which inserts snapshots
+type guardedPriceHistory struct {
+	store pricehistory.Store
+	dep   *resilience.Dependency
+}
+
+func (gs guardedPriceHistory) Append(ctx context.Context, snapshots []pricehistory.Snapshot) error {
+	return gs.dep.DoOnce(ctx, func(ctx context.Context) error {
+		return gs.store.Append(ctx, snapshots)
+	})
+}
+
+func (gs guardedPriceHistory) History(ctx context.Context, sku string, since time.Time) ([]pricehistory.Snapshot, error) {
+	return resilience.Call(ctx, gs.dep, func(ctx context.Context) ([]pricehistory.Snapshot, error) {
+		return gs.store.History(ctx, sku, since)
+	})
+}
+
+// guardedSubscriptions guards back-in-stock subscriptions; every call is
+// idempotent, so all of them retry
+type guardedSubscriptions struct {
+	store inventory.Store
+	dep   *resilience.Dependency
+}
+
+func (gs guardedSubscriptions) Subscribe(ctx context.Context, sub inventory.Subscription) (inventory.Subscription, error) {
+	return resilience.Call(ctx, gs.dep, func(ctx context.Context) (inventory.Subscription, error) {
+		return gs.store.Subscribe(ctx, sub)
+	})
+}
+
+func (gs guardedSubscriptions) Pending(ctx context.Context, sku string) ([]inventory.Subscription, error) {
+	return resilience.Call(ctx, gs.dep, func(ctx context.Context) ([]inventory.Subscription, error) {
+		return gs.store.Pending(ctx, sku)
+	})
+}
+
+func (gs guardedSubscriptions) MarkNotified(ctx context.Context, id string, at time.Time) error {
+	return gs.dep.Do(ctx, func(ctx context.Context) error {
+		return gs.store.MarkNotified(ctx, id, at)
+	})
+}
+
+// guardedNotifier never retries Notify, which inserts a notification
+type guardedNotifier struct {
+	notifier inventory.Notifier
+	dep      *resilience.Dependency
+}
+
+func (gn guardedNotifier) Notify(ctx context.Context, n inventory.Notification) error {
+	return gn.dep.DoOnce(ctx, func(ctx context.Context) error {
+		return gn.notifier.Notify(ctx, n)
+	})
+}
+
+// guardedCheckpoints guards workflow checkpoints; saves replace the whole
+// execution, so they retry
+type guardedCheckpoints struct {
+	store checkpoint.Store
+	dep   *resilience.Dependency
+}
+
+func (gs guardedCheckpoints) Save(ctx context.Context, exec checkpoint.Execution) error {
+	return gs.dep.Do(ctx, func(ctx context.Context) error {
+		return gs.store.Save(ctx, exec)
+	})
+}
+
+func (gs guardedCheckpoints) Get(ctx context.Context, id string) (checkpoint.Execution, error) {
+	return resilience.Call(ctx, gs.dep, func(ctx context.Context) (checkpoint.Execution, error) {
+		exec, err := gs.store.Get(ctx, id)
+		if errors.Is(err, checkpoint.ErrNotFound) {
+			return exec, resilience.Permanent(err)
+		}
+		return exec, err
+	})
+}
+
+// guardedConversations never retries Insert, which adds an audit record
+type guardedConversations struct {
+	store *conversations.Store
+	dep   *resilience.Dependency
+}
+
+func (gs guardedConversations) Insert(ctx context.Context, rec conversations.Record) error {
+	return gs.dep.DoOnce(ctx, func(ctx context.Context) error {
+		return gs.store.Insert(ctx, rec)
+	})
+}
+
+func (gs guardedConversations) Get(ctx context.Context, id string) (conversations.Record, error) {
+	return resilience.Call(ctx, gs.dep, func(ctx context.Context) (conversations.Record, error) {
+		rec, err := gs.store.Get(ctx, id)
+		if errors.Is(err, conversations.ErrNotFound) {
+			return rec, resilience.Permanent(err)
+		}
+		return rec, err
+	})
+}
+
+func (gs guardedConversations) List(ctx context.Context, filter conversations.Filter) ([]conversations.Record, int64, error) {
+	var records []conversations.Record
+	var total int64
+	err := gs.dep.Do(ctx, func(ctx context.Context) error {
+		var err error
+		records, total, err = gs.store.List(ctx, filter)
+		return err
+	})
+	return records, total, err
+}
+
+// guardedResponseStore guards the shared response cache
+type guardedResponseStore struct {
+	store respcache.Store
+	dep   *resilience.Dependency
+}
+
+func (gs guardedResponseStore) Get(ctx context.Context, key string) (respcache.Entry, bool, error) {
+	var entry respcache.Entry
+	var found bool
+	err := gs.dep.Do(ctx, func(ctx context.Context) error {
+		var err error
+		entry, found, err = gs.store.Get(ctx, key)
+		return err
+	})
+	return entry, found, err
+}
+
+func (gs guardedResponseStore) Set(ctx context.Context, entry respcache.Entry) error {
+	return gs.dep.Do(ctx, func(ctx context.Context) error {
+		return gs.store.Set(ctx, entry)
+	})
+}
+
+func (gs guardedResponseStore) Purge(ctx context.Context) (int64, error) {
+	return resilience.Call(ctx, gs.dep, gs.store.Purge)
+}
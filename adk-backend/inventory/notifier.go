@@ -0,0 +1,55 @@
+package inventory
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"pen-shop/models"
+)
+
+// Notification tells a subscriber their product is back in stock
+type Notification struct {
+	SubscriptionID string    `json:"subscription_id" bson:"subscription_id"`
+	UserID         string    `json:"user_id" bson:"user_id"`
+	Email          string    `json:"email,omitempty" bson:"email,omitempty"`
+	SKU            string    `json:"sku" bson:"sku"`
+	Name           string    `json:"name" bson:"name"`
+	PriceUSD       float64   `json:"price_usd" bson:"price_usd"`
+	At             time.Time `json:"at" bson:"at"`
+}
+
+// Notifier delivers back-in-stock notifications. Implementations may send
+// email or push messages; the default records them for the storefront to show.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// MongoNotifier records notifications in the stock_notifications collection
+type MongoNotifier struct {
+	collection *mongo.Collection
+}
+
+func NewMongoNotifier(collection *mongo.Collection) *MongoNotifier {
+	return &MongoNotifier{collection: collection}
+}
+
+func (mn *MongoNotifier) Notify(ctx context.Context, n Notification) error {
+	_, err := mn.collection.InsertOne(ctx, n)
+	return err
+}
+
+// LogNotifier only logs notifications; used when MongoDB is unavailable
+type LogNotifier struct {
+	logger models.Logger
+}
+
+func NewLogNotifier(logger models.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (ln *LogNotifier) Notify(ctx context.Context, n Notification) error {
+	ln.logger.Info("🔔 %s is back in stock, notifying user %s", n.Name, n.UserID)
+	return nil
+}
@@ -0,0 +1,198 @@
+package inventory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"pen-shop/clock"
+	"pen-shop/models"
+)
+
+// Poller watches catalogue availability and notifies subscribers when a
+// product comes back in stock
+type Poller struct {
+	source        Source
+	subscriptions Store
+	notifier      Notifier
+	clock         clock.Clock
+	interval      time.Duration
+	logger        models.Logger
+
+	mu sync.RWMutex
+	// stock is the availability seen on the last poll, keyed by SKU
+	stock map[string]Product
+	// retry holds in-stock SKUs whose notifications did not all go out
+	retry map[string]bool
+	// version fingerprints the last catalogue read; listeners hear when it changes
+	version   string
+	listeners []func(version string)
+}
+
+func NewPoller(source Source, subscriptions Store, notifier Notifier, clock clock.Clock, interval time.Duration, logger models.Logger) *Poller {
+	return &Poller{
+		source:        source,
+		subscriptions: subscriptions,
+		notifier:      notifier,
+		clock:         clock,
+		interval:      interval,
+		logger:        logger,
+		retry:         make(map[string]bool),
+	}
+}
+
+// Availability reports whether a SKU was in stock on the last poll; known is
+// false before the first poll or for SKUs the catalogue does not list
+func (p *Poller) Availability(sku string) (inStock, known bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	product, known := p.stock[sku]
+	return product.InStock, known
+}
+
+// Version fingerprints the catalogue prices and availability seen on the
+// last poll, or "" before the first poll
+func (p *Poller) Version() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.version
+}
+
+// OnCatalogueChange registers fn to run after a poll finds different prices,
+// products or availability than the poll before it
+func (p *Poller) OnCatalogueChange(fn func(version string)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.listeners = append(p.listeners, fn)
+}
+
+// Polled reports whether the catalogue has been read at least once
+func (p *Poller) Polled() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.stock != nil
+}
+
+// PollOnce reads the catalogue and notifies pending subscribers of every
+// product that went from out of stock to in stock since the last poll. The
+// first poll also notifies for products already in stock, so restocks missed
+// while the service was down are not lost.
+func (p *Poller) PollOnce(ctx context.Context) (int, error) {
+	products, err := p.source.Stock(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	current := make(map[string]Product, len(products))
+	for _, product := range products {
+		current[product.SKU] = product
+	}
+
+	version := catalogueVersion(products)
+	p.mu.Lock()
+	previous, previousVersion := p.stock, p.version
+	p.stock, p.version = current, version
+	listeners := p.listeners
+	p.mu.Unlock()
+
+	if previousVersion != "" && version != previousVersion {
+		for _, listener := range listeners {
+			listener(version)
+		}
+	}
+
+	var restocked []Product
+	for _, product := range products {
+		if !product.InStock {
+			delete(p.retry, product.SKU)
+			continue
+		}
+		before, seen := previous[product.SKU]
+		if previous == nil || (seen && !before.InStock) || p.retry[product.SKU] {
+			restocked = append(restocked, product)
+		}
+	}
+
+	notified := 0
+	var firstErr error
+	for _, product := range restocked {
+		count, err := p.notifyRestock(ctx, product)
+		notified += count
+		if err != nil {
+			p.retry[product.SKU] = true
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		delete(p.retry, product.SKU)
+	}
+	return notified, firstErr
+}
+
+// catalogueVersion hashes every product's price and availability in SKU order
+func catalogueVersion(products []Product) string {
+	sorted := append([]Product(nil), products...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].SKU < sorted[j].SKU })
+
+	hash := sha256.New()
+	for _, product := range sorted {
+		fmt.Fprintf(hash, "%s|%s|%.2f|%t\n", product.SKU, product.Name, product.PriceUSD, product.InStock)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+func (p *Poller) notifyRestock(ctx context.Context, product Product) (int, error) {
+	pending, err := p.subscriptions.Pending(ctx, product.SKU)
+	if err != nil {
+		return 0, fmt.Errorf("pending subscriptions for %s: %w", product.SKU, err)
+	}
+
+	now := p.clock.Now()
+	notified := 0
+	for _, sub := range pending {
+		err := p.notifier.Notify(ctx, Notification{
+			SubscriptionID: sub.ID,
+			UserID:         sub.UserID,
+			Email:          sub.Email,
+			SKU:            product.SKU,
+			Name:           product.Name,
+			PriceUSD:       product.PriceUSD,
+			At:             now,
+		})
+		if err != nil {
+			return notified, fmt.Errorf("notify %s about %s: %w", sub.UserID, product.SKU, err)
+		}
+		if err := p.subscriptions.MarkNotified(ctx, sub.ID, now); err != nil {
+			return notified, fmt.Errorf("mark subscription %s notified: %w", sub.ID, err)
+		}
+		notified++
+	}
+	return notified, nil
+}
+
+// Run polls immediately and then every interval until ctx is cancelled.
+// Failed notifications are retried on the next tick.
+func (p *Poller) Run(ctx context.Context) {
+	for {
+		pollCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		notified, err := p.PollOnce(pollCtx)
+		cancel()
+		if err != nil {
+			p.logger.Error("❌ Stock poll failed: %v", err)
+		}
+		if notified > 0 {
+			p.logger.Info("🔔 Sent %d back-in-stock notifications", notified)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.clock.After(p.interval):
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package inventory
+
+import "context"
+
+// Product is one catalogue entry's price and availability
+type Product struct {
+	SKU      string
+	Name     string
+	PriceUSD float64
+	InStock  bool
+}
+
+// Source lists every catalogue product with its current availability
+type Source interface {
+	Stock(ctx context.Context) ([]Product, error)
+}
+
+// SourceFunc adapts a function to a Source
+type SourceFunc func(ctx context.Context) ([]Product, error)
+
+func (f SourceFunc) Stock(ctx context.Context) ([]Product, error) {
+	return f(ctx)
+}
@@ -0,0 +1,168 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	StatusPending  = "pending"
+	StatusNotified = "notified"
+)
+
+// Subscription asks to be told when a product is back in stock
+type Subscription struct {
+	ID         string     `json:"id" bson:"_id"`
+	SKU        string     `json:"sku" bson:"sku"`
+	UserID     string     `json:"user_id" bson:"user_id"`
+	Email      string     `json:"email,omitempty" bson:"email,omitempty"`
+	Status     string     `json:"status" bson:"status"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty" bson:"notified_at,omitempty"`
+}
+
+// Store persists back-in-stock subscriptions
+type Store interface {
+	// Subscribe records a pending subscription, returning the existing one
+	// when the user is already waiting on the same product
+	Subscribe(ctx context.Context, sub Subscription) (Subscription, error)
+	// Pending lists a product's subscriptions still waiting, oldest first
+	Pending(ctx context.Context, sku string) ([]Subscription, error)
+	MarkNotified(ctx context.Context, id string, at time.Time) error
+}
+
+// ErrNotFound is returned when a subscription does not exist
+var ErrNotFound = errors.New("subscription not found")
+
+// MongoStore keeps subscriptions in the stock_subscriptions collection
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoStore(collection *mongo.Collection) *MongoStore {
+	return &MongoStore{collection: collection}
+}
+
+// EnsureIndexes allows one pending subscription per user and product
+func (ms *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := ms.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "sku", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": StatusPending}),
+		},
+		{Keys: bson.D{{Key: "sku", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+	})
+	return err
+}
+
+func (ms *MongoStore) Subscribe(ctx context.Context, sub Subscription) (Subscription, error) {
+	filter := bson.M{"user_id": sub.UserID, "sku": sub.SKU, "status": StatusPending}
+	update := bson.M{"$setOnInsert": bson.M{
+		"_id":        primitive.NewObjectID().Hex(),
+		"email":      sub.Email,
+		"created_at": sub.CreatedAt,
+	}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var stored Subscription
+	if err := ms.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&stored); err != nil {
+		return Subscription{}, err
+	}
+	return stored, nil
+}
+
+func (ms *MongoStore) Pending(ctx context.Context, sku string) ([]Subscription, error) {
+	cursor, err := ms.collection.Find(ctx,
+		bson.M{"sku": sku, "status": StatusPending},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []Subscription
+	if err := cursor.All(ctx, &subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (ms *MongoStore) MarkNotified(ctx context.Context, id string, at time.Time) error {
+	result, err := ms.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": StatusPending},
+		bson.M{"$set": bson.M{"status": StatusNotified, "notified_at": at}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MemoryStore keeps subscriptions in process; used when MongoDB is unavailable
+type MemoryStore struct {
+	mu   sync.Mutex
+	byID map[string]Subscription
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{byID: make(map[string]Subscription)}
+}
+
+func (ms *MemoryStore) Subscribe(ctx context.Context, sub Subscription) (Subscription, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, existing := range ms.byID {
+		if existing.UserID == sub.UserID && existing.SKU == sub.SKU && existing.Status == StatusPending {
+			return existing, nil
+		}
+	}
+	sub.ID = primitive.NewObjectID().Hex()
+	sub.Status = StatusPending
+	sub.NotifiedAt = nil
+	ms.byID[sub.ID] = sub
+	return sub, nil
+}
+
+func (ms *MemoryStore) Pending(ctx context.Context, sku string) ([]Subscription, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var subs []Subscription
+	for _, sub := range ms.byID {
+		if sub.SKU == sku && sub.Status == StatusPending {
+			subs = append(subs, sub)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if !subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+		}
+		return subs[i].ID < subs[j].ID
+	})
+	return subs, nil
+}
+
+func (ms *MemoryStore) MarkNotified(ctx context.Context, id string, at time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	sub, ok := ms.byID[id]
+	if !ok || sub.Status != StatusPending {
+		return ErrNotFound
+	}
+	sub.Status = StatusNotified
+	sub.NotifiedAt = &at
+	ms.byID[id] = sub
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"pen-shop/conversations"
+	"pen-shop/jobs"
+	"pen-shop/models"
+)
+
+// errChatFailed is the error recorded on async chats whose workflow failed;
+// the details stay in the logs and the conversation record
+var errChatFailed = errors.New("failed to process chat")
+
+// newJobPool sizes the async chat workers from JOBS_WORKERS, JOBS_QUEUE_SIZE,
+// JOBS_TIMEOUT and JOBS_RETENTION. Callbacks are only made to the hosts in
+// JOBS_WEBHOOK_HOSTS and are signed with JOBS_WEBHOOK_SECRET.
+func newJobPool(logger models.Logger) (*jobs.Pool, *jobs.Webhook) {
+	config := jobs.Config{
+		Workers:   4,
+		QueueSize: 32,
+		Timeout:   5 * time.Minute,
+		Retention: time.Hour,
+	}
+	sizes := []struct {
+		name   string
+		target *int
+	}{
+		{"JOBS_WORKERS", &config.Workers},
+		{"JOBS_QUEUE_SIZE", &config.QueueSize},
+	}
+	for _, size := range sizes {
+		if value := os.Getenv(size.name); value != "" {
+			if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+				*size.target = parsed
+			} else {
+				logger.Error("❌ Invalid %s %q, using %d", size.name, value, *size.target)
+			}
+		}
+	}
+	durations := []struct {
+		name   string
+		target *time.Duration
+	}{
+		{"JOBS_TIMEOUT", &config.Timeout},
+		{"JOBS_RETENTION", &config.Retention},
+	}
+	for _, d := range durations {
+		if value := os.Getenv(d.name); value != "" {
+			if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+				*d.target = parsed
+			} else {
+				logger.Error("❌ Invalid %s %q, using %s", d.name, value, *d.target)
+			}
+		}
+	}
+
+	var hosts []string
+	if value := os.Getenv("JOBS_WEBHOOK_HOSTS"); value != "" {
+		hosts = strings.Split(value, ",")
+	}
+	callbacks := jobs.NewWebhook(hosts, os.Getenv("JOBS_WEBHOOK_SECRET"))
+
+	logger.Info("🧵 Async jobs: %d workers, queue of %d, %s timeout", config.Workers, config.QueueSize, config.Timeout)
+	if callbacks.Enabled() {
+		logger.Info("📬 Job callbacks allowed to %s", strings.Join(hosts, ", "))
+	}
+	return jobs.NewPool(config, callbacks, logger), callbacks
+}
+
+// submitChatJob queues a chat and answers 202 with the job to poll
+func (psma *PenShopMultiAgent) submitChatJob(w http.ResponseWriter, req ChatRequest, query models.Query, record conversations.Record, caller callerLimits, startTime time.Time) {
+	if req.CallbackURL != "" {
+		if err := psma.callbacks.Validate(req.CallbackURL); err != nil {
+			http.Error(w, "callback_url is not allowed", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Only an authenticated caller owns a job; a user_id from the body alone
+	// would hide the job from the anonymous caller who queued it
+	var owner string
+	if query.Principal != nil {
+		owner = query.Principal.Subject
+	}
+
+	checkpointing := psma.workflows.Checkpointing()
+	job, err := psma.jobs.Submit(jobs.Job{
+		UserID:      owner,
+		SessionID:   query.SessionID,
+		CallbackURL: req.CallbackURL,
+	}, func(ctx context.Context) (interface{}, error) {
+		chatResponse, err := psma.runChat(ctx, query, record, caller, startTime)
+		if err != nil {
+			// A typed nil map would encode as a null result
+			if failure := chatFailure(query.SessionID, err, checkpointing); failure != nil {
+				return failure, errChatFailed
+			}
+			return nil, errChatFailed
+		}
+		return chatResponse, nil
+	})
+	switch {
+	case errors.Is(err, jobs.ErrQueueFull):
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Too many queued requests", http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	psma.logger.Info("🧵 Queued chat job %s", job.ID)
+	statusURL := "/api/jobs/" + job.ID
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", statusURL)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job_id":     job.ID,
+		"status":     job.Status,
+		"session_id": job.SessionID,
+		"status_url": statusURL,
+	})
+}
+
+// handleGetJob serves GET /api/jobs/{id}
+func (psma *PenShopMultiAgent) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := psma.jobs.Get(mux.Vars(r)["id"])
+	// Report other users' jobs as missing rather than forbidden
+	if err != nil || !visibleTo(r, job.UserID) {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// handleCancelJob serves DELETE /api/jobs/{id}. Queued jobs are canceled at
+// once (200); running jobs are asked to stop and answer 202 until they do.
+func (psma *PenShopMultiAgent) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	job, err := psma.jobs.Get(id)
+	if err != nil || !visibleTo(r, job.UserID) {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	job, err = psma.jobs.Cancel(id)
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	case errors.Is(err, jobs.ErrFinished):
+		http.Error(w, "Job already finished", http.StatusConflict)
+		return
+	}
+
+	status := http.StatusOK
+	if !job.Status.Finished() {
+		status = http.StatusAccepted
+	}
+	psma.logger.Info("🛑 Canceled chat job %s", job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,367 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"pen-shop/models"
+)
+
+var (
+	// ErrNotFound is returned for unknown or expired job IDs
+	ErrNotFound = errors.New("job not found")
+	// ErrQueueFull is returned by Submit when every worker is busy and the
+	// queue has no room
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrFinished is returned when cancelling a job that already finished
+	ErrFinished = errors.New("job already finished")
+	// ErrClosed is returned by Submit once the pool is stopping
+	ErrClosed = errors.New("job pool is stopped")
+)
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Finished reports whether a job in this status will not change again
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+// Job is a long-running request processed in the background
+type Job struct {
+	ID          string `json:"id"`
+	Status      Status `json:"status"`
+	UserID      string `json:"user_id,omitempty"`
+	SessionID   string `json:"session_id,omitempty"`
+	CallbackURL string `json:"callback_url,omitempty"`
+	// Result is the job's answer, or details of the failure such as a
+	// workflow execution to resume
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+}
+
+// Func does a job's work. A result returned with an error is kept as the
+// failure's details.
+type Func func(ctx context.Context) (interface{}, error)
+
+// Config sizes the pool
+type Config struct {
+	Workers   int
+	QueueSize int
+	// Timeout bounds each job once it starts running
+	Timeout time.Duration
+	// Retention is how long finished jobs can be fetched
+	Retention time.Duration
+}
+
+// Stats describes the pool's current load
+type Stats struct {
+	Workers   int `json:"workers"`
+	QueueSize int `json:"queue_size"`
+	Queued    int `json:"queued"`
+	Running   int `json:"running"`
+}
+
+type entry struct {
+	job      Job
+	fn       Func
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+// Pool runs jobs on a fixed number of workers fed by a bounded queue. Jobs
+// live in process, so they do not survive a restart.
+type Pool struct {
+	config   Config
+	notifier Notifier
+	logger   models.Logger
+
+	// ctx is the parent of every running job; Stop cancels it when draining
+	// takes too long
+	ctx    context.Context
+	cancel context.CancelFunc
+	queue  chan *entry
+
+	mu     sync.Mutex
+	jobs   map[string]*entry
+	closed bool
+
+	workers    sync.WaitGroup
+	deliveries sync.WaitGroup
+}
+
+// NewPool starts the workers. A nil notifier disables callbacks.
+func NewPool(config Config, notifier Notifier, logger models.Logger) *Pool {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.QueueSize < 0 {
+		config.QueueSize = 0
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Minute
+	}
+	if config.Retention <= 0 {
+		config.Retention = time.Hour
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		config:   config,
+		notifier: notifier,
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+		queue:    make(chan *entry, config.QueueSize),
+		jobs:     make(map[string]*entry),
+	}
+	for i := 0; i < config.Workers; i++ {
+		p.workers.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues fn as a new job built from job's owner, session and callback
+func (p *Pool) Submit(job Job, fn Func) (Job, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return Job{}, ErrClosed
+	}
+	p.prune()
+
+	job.ID = newJobID()
+	job.Status = StatusQueued
+	job.CreatedAt = time.Now()
+	job.Result, job.Error, job.StartedAt, job.FinishedAt = nil, "", nil, nil
+
+	e := &entry{job: job, fn: fn}
+	select {
+	case p.queue <- e:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	p.jobs[job.ID] = e
+	return job, nil
+}
+
+// Get returns a job's current state
+func (p *Pool) Get(id string) (Job, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.jobs[id]
+	if !ok || p.expired(e) {
+		return Job{}, ErrNotFound
+	}
+	return e.job, nil
+}
+
+// Cancel stops a job. A queued job is canceled at once; a running job's
+// context is canceled and it is marked canceled when its work returns.
+func (p *Pool) Cancel(id string) (Job, error) {
+	p.mu.Lock()
+	e, ok := p.jobs[id]
+	if !ok || p.expired(e) {
+		p.mu.Unlock()
+		return Job{}, ErrNotFound
+	}
+	if e.job.Status.Finished() {
+		job := e.job
+		p.mu.Unlock()
+		return job, ErrFinished
+	}
+
+	e.canceled = true
+	if e.job.Status == StatusRunning {
+		e.cancel()
+		job := e.job
+		p.mu.Unlock()
+		return job, nil
+	}
+	p.finish(e, StatusCanceled, nil, "canceled")
+	job := e.job
+	p.mu.Unlock()
+
+	p.notify(job)
+	return job, nil
+}
+
+// Stats reports the pool's size and load
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := Stats{Workers: p.config.Workers, QueueSize: p.config.QueueSize}
+	for _, e := range p.jobs {
+		switch e.job.Status {
+		case StatusQueued:
+			stats.Queued++
+		case StatusRunning:
+			stats.Running++
+		}
+	}
+	return stats
+}
+
+// Stop refuses new jobs, cancels queued ones and waits for running jobs and
+// callbacks. When ctx ends first, running jobs are canceled.
+func (p *Pool) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	var canceled []Job
+	for _, e := range p.jobs {
+		if e.job.Status == StatusQueued {
+			e.canceled = true
+			p.finish(e, StatusCanceled, nil, "server shutting down")
+			canceled = append(canceled, e.job)
+		}
+	}
+	close(p.queue)
+	p.mu.Unlock()
+
+	for _, job := range canceled {
+		p.notify(job)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		p.deliveries.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) work() {
+	defer p.workers.Done()
+	for e := range p.queue {
+		p.run(e)
+	}
+}
+
+func (p *Pool) run(e *entry) {
+	p.mu.Lock()
+	if e.job.Status != StatusQueued {
+		p.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithTimeout(p.ctx, p.config.Timeout)
+	defer cancel()
+	e.cancel = cancel
+	started := time.Now()
+	e.job.Status = StatusRunning
+	e.job.StartedAt = &started
+	p.mu.Unlock()
+
+	result, err := p.call(ctx, e.fn)
+
+	p.mu.Lock()
+	switch {
+	case e.canceled:
+		p.finish(e, StatusCanceled, nil, "canceled")
+	case err == nil:
+		p.finish(e, StatusSucceeded, result, "")
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		p.finish(e, StatusFailed, result, fmt.Sprintf("timed out after %s", p.config.Timeout))
+	case p.ctx.Err() != nil:
+		p.finish(e, StatusFailed, result, "server shutting down")
+	default:
+		p.finish(e, StatusFailed, result, err.Error())
+	}
+	job := e.job
+	p.mu.Unlock()
+
+	p.notify(job)
+}
+
+// call runs fn, turning a panic into a failed job instead of a lost worker
+func (p *Pool) call(ctx context.Context, fn Func) (result interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			p.logger.Error("❌ Job panicked: %v", recovered)
+			result, err = nil, errors.New("internal error")
+		}
+	}()
+	return fn(ctx)
+}
+
+// finish records a job's outcome; the caller holds p.mu
+func (p *Pool) finish(e *entry, status Status, result interface{}, message string) {
+	finished := time.Now()
+	e.job.Status = status
+	e.job.Result = result
+	e.job.Error = message
+	e.job.FinishedAt = &finished
+}
+
+// notify delivers a finished job to its callback in the background
+func (p *Pool) notify(job Job) {
+	if p.notifier == nil || job.CallbackURL == "" {
+		return
+	}
+
+	p.deliveries.Add(1)
+	go func() {
+		defer p.deliveries.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := p.notifier.Notify(ctx, job); err != nil {
+			p.logger.Error("❌ Callback for job %s failed: %v", job.ID, err)
+			return
+		}
+		p.logger.Info("📬 Job %s delivered to its callback", job.ID)
+	}()
+}
+
+// prune drops finished jobs past their retention; the caller holds p.mu
+func (p *Pool) prune() {
+	for id, e := range p.jobs {
+		if p.expired(e) {
+			delete(p.jobs, id)
+		}
+	}
+}
+
+func (p *Pool) expired(e *entry) bool {
+	return e.job.FinishedAt != nil && time.Since(*e.job.FinishedAt) > p.config.Retention
+}
+
+// newJobID returns an unguessable ID; jobs hold the customer's question and
+// answer, so they must not be enumerable
+func newJobID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "job_" + time.Now().Format("20060102150405.000000000")
+	}
+	return "job_" + hex.EncodeToString(b)
+}
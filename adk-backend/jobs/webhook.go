@@ -0,0 +1,138 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"pen-shop/resilience"
+)
+
+// ErrCallbackNotAllowed is returned for callback URLs outside the allowlist
+var ErrCallbackNotAllowed = errors.New("callback URL is not allowed")
+
+// Notifier tells a job's owner that it finished
+type Notifier interface {
+	Notify(ctx context.Context, job Job) error
+}
+
+// Webhook POSTs finished jobs to their callback URL. Only hosts on the
+// allowlist are called, so callers cannot point the backend at internal
+// services.
+type Webhook struct {
+	hosts  map[string]bool
+	secret []byte
+	retry  resilience.RetryConfig
+	client *http.Client
+}
+
+// NewWebhook allows callbacks to hosts. When secret is set, each delivery is
+// signed with an HMAC-SHA256 of the body in the X-PenShop-Signature header.
+func NewWebhook(hosts []string, secret string) *Webhook {
+	allowed := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			allowed[host] = true
+		}
+	}
+	return &Webhook{
+		hosts:  allowed,
+		secret: []byte(secret),
+		retry:  resilience.RetryConfig{Attempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second},
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			// A redirect could lead off the allowlist
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Enabled reports whether any callback host is allowed
+func (wh *Webhook) Enabled() bool {
+	return wh != nil && len(wh.hosts) > 0
+}
+
+// Validate checks a callback URL before a job is accepted
+func (wh *Webhook) Validate(callbackURL string) error {
+	parsed, err := url.Parse(callbackURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" || parsed.User != nil {
+		return fmt.Errorf("%w: must be an http(s) URL", ErrCallbackNotAllowed)
+	}
+	if !wh.Enabled() || !wh.hosts[strings.ToLower(parsed.Hostname())] {
+		return fmt.Errorf("%w: host %s is not on the allowlist", ErrCallbackNotAllowed, parsed.Hostname())
+	}
+	return nil
+}
+
+// Notify delivers the job, retrying server errors and timeouts
+func (wh *Webhook) Notify(ctx context.Context, job Job) error {
+	if err := wh.Validate(job.CallbackURL); err != nil {
+		return err
+	}
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= wh.retry.Attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(wh.retry.Backoff(attempt - 1)):
+			case <-ctx.Done():
+				return fmt.Errorf("%w (last error: %v)", ctx.Err(), lastErr)
+			}
+		}
+
+		retry, err := wh.deliver(ctx, job, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+// deliver makes one attempt, reporting whether a failure is worth retrying
+func (wh *Webhook) deliver(ctx context.Context, job Job, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-PenShop-Job", job.ID)
+	if len(wh.secret) > 0 {
+		mac := hmac.New(sha256.New, wh.secret)
+		mac.Write(body)
+		req.Header.Set("X-PenShop-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("callback request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("callback returned status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,178 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Embedder turns text into vectors for semantic search
+type Embedder interface {
+	// Name identifies the embedder and model so stored vectors can be invalidated
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// OpenAIEmbedder calls an OpenAI-compatible /embeddings endpoint
+type OpenAIEmbedder struct {
+	provider   string
+	baseURL    string
+	apiKey     string
+	model      string
+	requireKey bool
+	client     *http.Client
+}
+
+// NewOpenAIEmbedder creates an embedder for the hosted OpenAI API
+func NewOpenAIEmbedder(baseURL, apiKey, model string) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		provider:   ProviderOpenAI,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		requireKey: true,
+		client:     &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// NewLocalEmbedder creates an embedder for a local OpenAI-compatible runner
+func NewLocalEmbedder(baseURL, model string) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		provider: ProviderLocal,
+		baseURL:  localEndpoint(baseURL),
+		model:    model,
+		client:   &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (oe *OpenAIEmbedder) Name() string {
+	return oe.provider + ":" + oe.model
+}
+
+func (oe *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if oe.requireKey && oe.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model": oe.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, oe.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if oe.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+oe.apiKey)
+	}
+
+	resp, err := oe.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s embeddings request failed: %w", oe.provider, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Service: oe.provider + " embeddings", Status: resp.StatusCode}
+	}
+
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("%s embeddings returned invalid body: %w", oe.provider, err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("%s returned %d embeddings for %d inputs", oe.provider, len(parsed.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range parsed.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("%s returned embedding index %d out of range", oe.provider, item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
+
+// HashEmbedder is a deterministic, dependency-free embedder that hashes word
+// unigrams and bigrams into a fixed number of dimensions. It is meant for
+// tests and offline development; similarity reflects shared vocabulary only.
+type HashEmbedder struct {
+	Dimensions int
+}
+
+func NewHashEmbedder(dimensions int) *HashEmbedder {
+	return &HashEmbedder{Dimensions: dimensions}
+}
+
+func (he *HashEmbedder) Name() string {
+	return fmt.Sprintf("hash:%d", he.Dimensions)
+}
+
+func (he *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = he.embed(text)
+	}
+	return vectors, nil
+}
+
+func (he *HashEmbedder) embed(text string) []float32 {
+	vector := make([]float32, he.Dimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	add := func(feature string, weight float32) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		index := int(sum % uint64(he.Dimensions))
+		// Use a hash bit for the sign so collisions tend to cancel out
+		if sum&(1<<63) != 0 {
+			weight = -weight
+		}
+		vector[index] += weight
+	}
+
+	for i, word := range words {
+		add(word, 1)
+		if i+1 < len(words) {
+			add(word+" "+words[i+1], 0.5)
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"pen-shop/models"
+)
+
+// FailoverProvider sends requests to a primary provider and retries on the
+// fallback provider when the primary errors or has no key configured
+type FailoverProvider struct {
+	primary  Provider
+	fallback Provider
+	logger   models.Logger
+}
+
+func NewFailoverProvider(primary, fallback Provider, logger models.Logger) *FailoverProvider {
+	return &FailoverProvider{
+		primary:  primary,
+		fallback: fallback,
+		logger:   logger,
+	}
+}
+
+func (fp *FailoverProvider) Name() string {
+	return fp.primary.Name()
+}
+
+func (fp *FailoverProvider) Model() string {
+	return fp.primary.Model()
+}
+
+func (fp *FailoverProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	resp, err := fp.primary.Complete(ctx, req)
+	if err == nil {
+		return resp, nil
+	}
+
+	// Don't fail over once the caller has given up
+	if ctx.Err() != nil {
+		return CompletionResponse{}, err
+	}
+
+	// A missing key is reported once at startup, not on every request
+	if !errors.Is(err, ErrNoAPIKey) {
+		fp.logger.Info("⚠️ Provider %s failed (%v), failing over to %s", fp.primary.Name(), err, fp.fallback.Name())
+	}
+	return fp.fallback.Complete(ctx, req)
+}
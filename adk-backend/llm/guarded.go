@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"pen-shop/resilience"
+)
+
+// GuardedProvider runs completions behind a dependency's breaker, bulkhead
+// and retries
+type GuardedProvider struct {
+	Provider
+	dependency *resilience.Dependency
+}
+
+func NewGuardedProvider(provider Provider, dependency *resilience.Dependency) *GuardedProvider {
+	return &GuardedProvider{Provider: provider, dependency: dependency}
+}
+
+func (gp *GuardedProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	return resilience.Call(ctx, gp.dependency, func(ctx context.Context) (CompletionResponse, error) {
+		resp, err := gp.Provider.Complete(ctx, req)
+		return resp, classify(err)
+	})
+}
+
+// GuardedEmbedder runs embedding calls behind a dependency's breaker,
+// bulkhead and retries
+type GuardedEmbedder struct {
+	Embedder
+	dependency *resilience.Dependency
+}
+
+func NewGuardedEmbedder(embedder Embedder, dependency *resilience.Dependency) *GuardedEmbedder {
+	return &GuardedEmbedder{Embedder: embedder, dependency: dependency}
+}
+
+func (ge *GuardedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return resilience.Call(ctx, ge.dependency, func(ctx context.Context) ([][]float32, error) {
+		vectors, err := ge.Embedder.Embed(ctx, texts)
+		return vectors, classify(err)
+	})
+}
+
+// classify marks errors that retrying cannot fix, such as a missing key or a
+// rejected request, so they neither retry nor trip the breaker. Rate limits
+// and server errors stay retryable.
+func classify(err error) error {
+	if errors.Is(err, ErrNoAPIKey) {
+		return resilience.Permanent(err)
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Status < http.StatusInternalServerError && statusErr.Status != http.StatusTooManyRequests {
+		return resilience.Permanent(err)
+	}
+	return err
+}
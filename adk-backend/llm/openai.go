@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIProvider talks to any OpenAI-compatible chat completions endpoint.
+// It is used both for api.openai.com and for Docker Model Runner.
+type OpenAIProvider struct {
+	name       string
+	baseURL    string
+	apiKey     string
+	model      string
+	requireKey bool
+	client     *http.Client
+}
+
+// NewOpenAIProvider creates a provider for the hosted OpenAI API
+func NewOpenAIProvider(baseURL, apiKey, model string) *OpenAIProvider {
+	return &OpenAIProvider{
+		name:       "openai",
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		requireKey: true,
+		client:     &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// NewLocalProvider creates a provider for a local OpenAI-compatible runner
+// that does not need an API key
+func NewLocalProvider(baseURL, model string) *OpenAIProvider {
+	return &OpenAIProvider{
+		name:    "local",
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (op *OpenAIProvider) Name() string {
+	return op.name
+}
+
+func (op *OpenAIProvider) Model() string {
+	return op.model
+}
+
+type chatCompletionRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature float64   `json:"temperature,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+}
+
+type chatCompletionResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+	Usage Usage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (op *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	if op.requireKey && op.apiKey == "" {
+		return CompletionResponse{}, ErrNoAPIKey
+	}
+
+	body, err := json.Marshal(chatCompletionRequest{
+		Model:       op.model,
+		Messages:    req.Messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	})
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, op.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if op.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+op.apiKey)
+	}
+
+	resp, err := op.client.Do(httpReq)
+	if err != nil {
+		return CompletionResponse{}, fmt.Errorf("%s request failed: %w", op.name, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return CompletionResponse{}, fmt.Errorf("%s response read failed: %w", op.name, err)
+	}
+
+	var parsed chatCompletionResponse
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return CompletionResponse{}, &StatusError{Service: op.name, Status: resp.StatusCode, Message: "invalid body"}
+		}
+		return CompletionResponse{}, fmt.Errorf("%s returned status %d with invalid body", op.name, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		statusErr := &StatusError{Service: op.name, Status: resp.StatusCode}
+		if parsed.Error != nil {
+			statusErr.Message = parsed.Error.Message
+		}
+		return CompletionResponse{}, statusErr
+	}
+	if len(parsed.Choices) == 0 {
+		return CompletionResponse{}, fmt.Errorf("%s returned no choices", op.name)
+	}
+
+	model := parsed.Model
+	if model == "" {
+		model = op.model
+	}
+
+	return CompletionResponse{
+		Content:  strings.TrimSpace(parsed.Choices[0].Message.Content),
+		Provider: op.name,
+		Model:    model,
+		Usage:    parsed.Usage,
+	}, nil
+}
@@ -0,0 +1,38 @@
+package llm
+
+import "strings"
+
+// modelPrice is the USD cost per million prompt and completion tokens
+type modelPrice struct {
+	prompt     float64
+	completion float64
+}
+
+// modelPrices lists hosted models by name prefix; longer prefixes win
+var modelPrices = map[string]modelPrice{
+	"gpt-4o-mini":   {prompt: 0.15, completion: 0.60},
+	"gpt-4o":        {prompt: 2.50, completion: 10.00},
+	"gpt-4-turbo":   {prompt: 10.00, completion: 30.00},
+	"gpt-4":         {prompt: 30.00, completion: 60.00},
+	"gpt-3.5-turbo": {prompt: 0.50, completion: 1.50},
+}
+
+// EstimateCost returns the USD cost of a completion. Local models are free.
+func EstimateCost(provider, model string, usage Usage) float64 {
+	if provider == ProviderLocal {
+		return 0
+	}
+
+	var best string
+	for prefix := range modelPrices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return 0
+	}
+
+	price := modelPrices[best]
+	return (float64(usage.PromptTokens)*price.prompt + float64(usage.CompletionTokens)*price.completion) / 1e6
+}
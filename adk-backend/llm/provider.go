@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoAPIKey is returned by providers that require a key but were configured without one
+var ErrNoAPIKey = errors.New("llm: provider has no API key configured")
+
+// ErrNoProvider is returned when an agent has no model provider assigned
+var ErrNoProvider = errors.New("llm: no provider configured")
+
+// StatusError is a non-200 reply from a model or embeddings endpoint
+type StatusError struct {
+	Service string
+	Status  int
+	Message string
+}
+
+func (se *StatusError) Error() string {
+	if se.Message != "" {
+		return fmt.Sprintf("%s returned status %d: %s", se.Service, se.Status, se.Message)
+	}
+	return fmt.Sprintf("%s returned status %d", se.Service, se.Status)
+}
+
+// Message is a single chat message sent to a model
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// CompletionRequest describes a chat completion call
+type CompletionRequest struct {
+	Messages    []Message `json:"messages"`
+	Temperature float64   `json:"temperature,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+}
+
+// Usage reports token consumption for a completion
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// CompletionResponse is the result of a chat completion call
+type CompletionResponse struct {
+	Content  string `json:"content"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Usage    Usage  `json:"usage"`
+	// Cached is set when the semantic cache answered instead of the model
+	Cached bool `json:"cached,omitempty"`
+}
+
+// Provider is a chat completion backend such as OpenAI or a local model runner
+type Provider interface {
+	Name() string
+	Model() string
+	Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error)
+}
@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"strings"
+
+	"pen-shop/models"
+	"pen-shop/resilience"
+)
+
+const (
+	ProviderOpenAI = "openai"
+	ProviderLocal  = "local"
+)
+
+// Config describes the available model providers and which one each agent uses
+type Config struct {
+	OpenAIKey     string
+	OpenAIBaseURL string
+	OpenAIModel   string
+
+	LocalBaseURL string
+	LocalModel   string
+
+	// DefaultProvider is used for agents without an entry in AgentProviders
+	DefaultProvider string
+	// AgentProviders maps agent names to "openai" or "local"
+	AgentProviders map[string]string
+	// Failover routes OpenAI calls to the local runner when OpenAI errors or has no key
+	Failover bool
+	// Resilience guards each provider with its own breaker; nil leaves calls unguarded
+	Resilience *resilience.Registry
+}
+
+// Router hands out the configured provider for each agent
+type Router struct {
+	providers      map[string]Provider
+	defaultName    string
+	agentProviders map[string]string
+	logger         models.Logger
+}
+
+func NewRouter(cfg Config, logger models.Logger) *Router {
+	providers := make(map[string]Provider)
+
+	var local Provider
+	if cfg.LocalBaseURL != "" {
+		local = NewGuardedProvider(NewLocalProvider(localEndpoint(cfg.LocalBaseURL), cfg.LocalModel),
+			cfg.Resilience.Dependency(resilience.ModelRunner))
+		providers[ProviderLocal] = local
+	}
+
+	// The breaker sits inside failover, so an open OpenAI breaker fails over at once
+	var openai Provider = NewGuardedProvider(NewOpenAIProvider(cfg.OpenAIBaseURL, cfg.OpenAIKey, cfg.OpenAIModel),
+		cfg.Resilience.Dependency(resilience.OpenAI))
+	if cfg.Failover && local != nil {
+		openai = NewFailoverProvider(openai, local, logger)
+	}
+	// Without a key or a runner to fail over to, agents keep their rule-based answers
+	if cfg.OpenAIKey != "" || (cfg.Failover && local != nil) {
+		providers[ProviderOpenAI] = openai
+	}
+
+	if cfg.OpenAIKey == "" && cfg.Failover && local != nil {
+		logger.Info("⚠️ No OpenAI key configured, OpenAI requests go to the local model runner")
+	}
+
+	defaultName := ProviderOpenAI
+	if name := normalizeProvider(cfg.DefaultProvider); name != "" {
+		if validProvider(name) {
+			defaultName = name
+		} else {
+			logger.Error("❌ Invalid LLM_PROVIDER %q, using %s", cfg.DefaultProvider, defaultName)
+		}
+	}
+
+	// Agents with an unknown provider use the default
+	agentProviders := make(map[string]string)
+	for agent, name := range cfg.AgentProviders {
+		if normalized := normalizeProvider(name); validProvider(normalized) {
+			agentProviders[agent] = normalized
+		} else {
+			logger.Error("❌ Unknown model provider %q for agent %s, using %s", name, agent, defaultName)
+		}
+	}
+
+	return &Router{
+		providers:      providers,
+		defaultName:    defaultName,
+		agentProviders: agentProviders,
+		logger:         logger,
+	}
+}
+
+// ForAgent returns the provider an agent should use, or nil if none is available
+func (r *Router) ForAgent(agentName string) Provider {
+	name, ok := r.agentProviders[agentName]
+	if !ok {
+		name = r.defaultName
+	}
+
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil
+	}
+	return provider
+}
+
+// Provider returns a provider by name
+func (r *Router) Provider(name string) Provider {
+	return r.providers[name]
+}
+
+// HasLocal reports whether a local model runner is configured
+func (r *Router) HasLocal() bool {
+	return r.providers[ProviderLocal] != nil
+}
+
+func normalizeProvider(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func validProvider(name string) bool {
+	return name == ProviderOpenAI || name == ProviderLocal
+}
+
+// localEndpoint turns a Docker Model Runner base URL such as
+// http://model-runner:8080 into its OpenAI-compatible API root
+func localEndpoint(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if strings.HasSuffix(baseURL, "/v1") {
+		return baseURL
+	}
+	return baseURL + "/engines/v1"
+}
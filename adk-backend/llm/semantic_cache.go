@@ -0,0 +1,268 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pen-shop/currency"
+	"pen-shop/models"
+	"pen-shop/vectors"
+)
+
+// SemanticCacheConfig tunes when an earlier completion may answer a new prompt
+type SemanticCacheConfig struct {
+	// Threshold is the minimum cosine similarity between prompts
+	Threshold float64
+	TTL       time.Duration
+	// MaxEntries bounds each scope; the oldest entries are evicted first
+	MaxEntries int
+}
+
+// CacheStats reports one agent's semantic cache outcomes since startup
+type CacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+	Entries int     `json:"entries"`
+}
+
+// SemanticCache reuses completions for prompts that mean the same thing as
+// an earlier one. Entries are scoped per agent and prompt version, where the
+// version fingerprints the system prompt and model, so editing a prompt
+// never serves answers written for the old one.
+type SemanticCache struct {
+	embedder Embedder
+	config   SemanticCacheConfig
+	logger   models.Logger
+
+	mu     sync.Mutex
+	scopes map[string]*cacheScope
+	stats  map[string]*CacheStats
+}
+
+type cacheScope struct {
+	index   *vectors.Index
+	entries map[string]cachedCompletion
+}
+
+type cachedCompletion struct {
+	response CompletionResponse
+	// literals are the numbers and currencies in the prompt; paraphrases
+	// must agree on them
+	literals  string
+	createdAt time.Time
+}
+
+func NewSemanticCache(embedder Embedder, config SemanticCacheConfig, logger models.Logger) *SemanticCache {
+	if config.Threshold <= 0 {
+		config.Threshold = 0.92
+	}
+	if config.TTL <= 0 {
+		config.TTL = time.Hour
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 500
+	}
+	return &SemanticCache{
+		embedder: embedder,
+		config:   config,
+		logger:   logger,
+		scopes:   make(map[string]*cacheScope),
+		stats:    make(map[string]*CacheStats),
+	}
+}
+
+// Wrap returns a provider that consults the cache before calling provider
+// on behalf of agent
+func (sc *SemanticCache) Wrap(provider Provider, agent string) Provider {
+	return &cachedProvider{Provider: provider, cache: sc, agent: agent}
+}
+
+// Stats returns hit rates per agent
+func (sc *SemanticCache) Stats() map[string]CacheStats {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	entries := make(map[string]int)
+	for key, scope := range sc.scopes {
+		agent := strings.SplitN(key, "\x00", 2)[0]
+		entries[agent] += len(scope.entries)
+	}
+
+	stats := make(map[string]CacheStats, len(sc.stats))
+	for agent, counts := range sc.stats {
+		snapshot := *counts
+		if total := snapshot.Hits + snapshot.Misses; total > 0 {
+			snapshot.HitRate = math.Round(float64(snapshot.Hits)/float64(total)*1000) / 1000
+		}
+		snapshot.Entries = entries[agent]
+		stats[agent] = snapshot
+	}
+	return stats
+}
+
+type cachedProvider struct {
+	Provider
+	cache *SemanticCache
+	agent string
+}
+
+func (cp *cachedProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	scopeKey := cp.agent + "\x00" + promptVersion(req, cp.Provider.Model())
+	text := cacheText(req)
+
+	vector, err := cp.cache.embed(ctx, text)
+	if err != nil {
+		cp.cache.logger.Error("❌ Semantic cache embedding failed for %s: %v", cp.agent, err)
+		return cp.Provider.Complete(ctx, req)
+	}
+
+	if resp, ok := cp.cache.lookup(scopeKey, vector, text); ok {
+		cp.cache.record(cp.agent, true)
+		return resp, nil
+	}
+	cp.cache.record(cp.agent, false)
+
+	resp, err := cp.Provider.Complete(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	cp.cache.store(scopeKey, vector, text, resp)
+	return resp, nil
+}
+
+func (sc *SemanticCache) embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := sc.embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for 1 prompt", len(vectors))
+	}
+	return vectors[0], nil
+}
+
+// lookup returns the freshest completion whose prompt is similar enough and
+// mentions the same numbers and currencies
+func (sc *SemanticCache) lookup(scopeKey string, vector []float32, text string) (CompletionResponse, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	scope, ok := sc.scopes[scopeKey]
+	if !ok {
+		return CompletionResponse{}, false
+	}
+
+	literals := promptLiterals(text)
+	now := time.Now()
+	for _, match := range scope.index.Search(vector, 0, nil) {
+		if match.Score < sc.config.Threshold {
+			break
+		}
+		entry, ok := scope.entries[match.Item.ID]
+		if !ok || now.Sub(entry.createdAt) > sc.config.TTL || entry.literals != literals {
+			continue
+		}
+
+		resp := entry.response
+		// A cached answer costs nothing, so quotas are not charged again
+		resp.Usage = Usage{}
+		resp.Cached = true
+		return resp, true
+	}
+	return CompletionResponse{}, false
+}
+
+func (sc *SemanticCache) store(scopeKey string, vector []float32, text string, resp CompletionResponse) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	scope, ok := sc.scopes[scopeKey]
+	if !ok {
+		scope = &cacheScope{index: vectors.NewIndex(sc.embedder.Name()), entries: make(map[string]cachedCompletion)}
+		sc.scopes[scopeKey] = scope
+	}
+
+	id := vectors.HashText(text)
+	scope.index.Upsert(vectors.Item{ID: id, Text: text, Vector: vector})
+	scope.entries[id] = cachedCompletion{response: resp, literals: promptLiterals(text), createdAt: time.Now()}
+
+	if len(scope.entries) > sc.config.MaxEntries {
+		ids := make([]string, 0, len(scope.entries))
+		for entryID := range scope.entries {
+			ids = append(ids, entryID)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			return scope.entries[ids[i]].createdAt.Before(scope.entries[ids[j]].createdAt)
+		})
+
+		keep := make(map[string]bool, sc.config.MaxEntries)
+		for _, entryID := range ids[len(ids)-sc.config.MaxEntries:] {
+			keep[entryID] = true
+		}
+		for entryID := range scope.entries {
+			if !keep[entryID] {
+				delete(scope.entries, entryID)
+			}
+		}
+		scope.index.Retain(keep)
+	}
+}
+
+func (sc *SemanticCache) record(agent string, hit bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	counts, ok := sc.stats[agent]
+	if !ok {
+		counts = &CacheStats{}
+		sc.stats[agent] = counts
+	}
+	if hit {
+		counts.Hits++
+	} else {
+		counts.Misses++
+	}
+}
+
+// promptVersion fingerprints the parts of a request that define the task
+// rather than the question: system prompts, model and sampling settings
+func promptVersion(req CompletionRequest, model string) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s|%.2f|%d\n", model, req.Temperature, req.MaxTokens)
+	for _, message := range req.Messages {
+		if message.Role == "system" {
+			hash.Write([]byte(message.Content))
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// cacheText is the part of a request that varies between questions
+func cacheText(req CompletionRequest) string {
+	var parts []string
+	for _, message := range req.Messages {
+		if message.Role != "system" {
+			parts = append(parts, message.Role+": "+message.Content)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+var literalPattern = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+
+// promptLiterals lists the numbers and currencies in a prompt. Embeddings
+// barely separate "under 80 euros" from "under 90 euros", or "a hundred quid"
+// from "a hundred bucks", so cached answers must agree on them.
+func promptLiterals(text string) string {
+	return strings.Join(literalPattern.FindAllString(text, -1), ",") + "|" +
+		strings.Join(currency.Codes(text), ",")
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+
+// sameEmbedder embeds every text to the same vector, so any two prompts
+// look like paraphrases of each other
+type sameEmbedder struct{}
+
+func (sameEmbedder) Name() string { return "same" }
+
+func (sameEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{1, 0}
+	}
+	return vectors, nil
+}
+
+// echoProvider answers with the prompt it was sent
+type echoProvider struct{ calls int }
+
+func (ep *echoProvider) Name() string  { return "echo" }
+func (ep *echoProvider) Model() string { return "echo" }
+
+func (ep *echoProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	ep.calls++
+	return CompletionResponse{Content: req.Messages[len(req.Messages)-1].Content}, nil
+}
+
+func TestSemanticCacheKeepsCurrenciesApart(t *testing.T) {
+	cases := []struct {
+		first, second string
+		shared        bool
+	}{
+		{"around a hundred quid", "around a hundred bucks", false},
+		{"pens under 80 euros", "pens under 90 euros", false},
+		{"pens under 80 euros", "pens under $80", false},
+		{"pens under £100", "pens under 100 pounds", true},
+		{"a nice fountain pen", "a lovely fountain pen", true},
+	}
+	for _, tc := range cases {
+		provider := &echoProvider{}
+		cached := NewSemanticCache(sameEmbedder{}, SemanticCacheConfig{}, nopLogger{}).Wrap(provider, "extraction")
+
+		if _, err := cached.Complete(context.Background(), CompletionRequest{Messages: []Message{{Role: "user", Content: tc.first}}}); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := cached.Complete(context.Background(), CompletionRequest{Messages: []Message{{Role: "user", Content: tc.second}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Cached != tc.shared {
+			t.Errorf("%q after %q: cached = %v, want %v (answer %q)", tc.second, tc.first, resp.Cached, tc.shared, resp.Content)
+		}
+	}
+}
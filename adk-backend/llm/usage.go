@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CallRecord describes one completed model call
+type CallRecord struct {
+	Provider  string    `json:"provider" bson:"provider"`
+	Model     string    `json:"model" bson:"model"`
+	Usage     Usage     `json:"usage" bson:"usage"`
+	CostUSD   float64   `json:"cost_usd" bson:"cost_usd"`
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+}
+
+// UsageTracker collects the model calls made while handling one request
+type UsageTracker struct {
+	mu    sync.Mutex
+	calls []CallRecord
+}
+
+type usageTrackerKey struct{}
+
+// WithUsageTracker attaches a tracker to ctx so agents can report model usage
+func WithUsageTracker(ctx context.Context, tracker *UsageTracker) context.Context {
+	return context.WithValue(ctx, usageTrackerKey{}, tracker)
+}
+
+// TrackerFrom returns the tracker attached to ctx, or nil
+func TrackerFrom(ctx context.Context) *UsageTracker {
+	tracker, _ := ctx.Value(usageTrackerKey{}).(*UsageTracker)
+	return tracker
+}
+
+// Record adds a completed call to the tracker
+func (ut *UsageTracker) Record(resp CompletionResponse) {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	ut.calls = append(ut.calls, CallRecord{
+		Provider:  resp.Provider,
+		Model:     resp.Model,
+		Usage:     resp.Usage,
+		CostUSD:   EstimateCost(resp.Provider, resp.Model, resp.Usage),
+		Timestamp: time.Now(),
+	})
+}
+
+// Calls returns a copy of the recorded calls
+func (ut *UsageTracker) Calls() []CallRecord {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+	return append([]CallRecord(nil), ut.calls...)
+}
+
+// Totals sums tokens and cost across all recorded calls
+func (ut *UsageTracker) Totals() (int, float64) {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	var tokens int
+	var cost float64
+	for _, call := range ut.calls {
+		tokens += call.Usage.TotalTokens
+		cost += call.CostUSD
+	}
+	return tokens, cost
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"pen-shop/llm"
+	"pen-shop/models"
+)
+
+// newLLMCache builds the semantic cache placed in front of agent model calls.
+// It is on whenever an embedder is available unless LLM_SEMANTIC_CACHE=off;
+// LLM_CACHE_THRESHOLD, LLM_CACHE_TTL and LLM_CACHE_SIZE tune it.
+func newLLMCache(embedder llm.Embedder, logger models.Logger) *llm.SemanticCache {
+	if embedder == nil || os.Getenv("LLM_SEMANTIC_CACHE") == "off" {
+		return nil
+	}
+
+	config := llm.SemanticCacheConfig{Threshold: 0.92, TTL: time.Hour, MaxEntries: 500}
+	if value := os.Getenv("LLM_CACHE_THRESHOLD"); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil && parsed > 0 && parsed <= 1 {
+			config.Threshold = parsed
+		} else {
+			logger.Error("❌ Invalid LLM_CACHE_THRESHOLD %q, using %.2f", value, config.Threshold)
+		}
+	}
+	if value := os.Getenv("LLM_CACHE_TTL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			config.TTL = parsed
+		} else {
+			logger.Error("❌ Invalid LLM_CACHE_TTL %q, using %s", value, config.TTL)
+		}
+	}
+	if n, err := strconv.Atoi(os.Getenv("LLM_CACHE_SIZE")); err == nil && n > 0 {
+		config.MaxEntries = n
+	}
+
+	logger.Info("🧠 LLM semantic cache enabled (%s, similarity ≥ %.2f, TTL %s)", embedder.Name(), config.Threshold, config.TTL)
+	return llm.NewSemanticCache(embedder, config, logger)
+}
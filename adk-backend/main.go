@@ -3,11 +3,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -16,20 +21,79 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"pen-shop/agents"
+	"pen-shop/auth"
+	"pen-shop/cart"
+	"pen-shop/clock"
+	"pen-shop/conversations"
+	"pen-shop/currency"
+	"pen-shop/inventory"
+	"pen-shop/jobs"
+	"pen-shop/llm"
 	"pen-shop/models"
+	"pen-shop/pricehistory"
+	"pen-shop/promotions"
+	"pen-shop/ratelimit"
+	"pen-shop/resilience"
 	"pen-shop/utils"
 )
 
 type PenShopMultiAgent struct {
 	sequentialAgent models.Agent
+	workflows       *agents.PenShopSequentialAgent
+	registry        *agents.AgentRegistry
 	mongodb         *mongo.Client
 	catalogueURL    string
+	llmRouter       *llm.Router
+	limiter         *ratelimit.Limiter
+	limits          ratelimit.Config
+	quotas          ratelimit.QuotaStore
+	trustProxy      bool
+	auth            *auth.Middleware
+	conversations   conversationLog
+	rates           *currency.FileRates
+	defaultCurrency string
+	subscriptions   inventory.Store
+	carts           *cart.Service
+	responseCache   *agents.CachingAgent
+	llmCache        *llm.SemanticCache
+	dependencies    *resilience.Registry
+	stockPoller     *inventory.Poller
+	jobs            *jobs.Pool
+	callbacks       *jobs.Webhook
 	logger          models.Logger
+
+	// pendingWrites tracks background MongoDB writes so shutdown can drain them
+	pendingWrites sync.WaitGroup
+	// stopBackground cancels scheduled jobs such as the price history recorder
+	// and the stock poller
+	stopBackground context.CancelFunc
 }
 
+const (
+	// maxChatBodyBytes caps the size of a /api/chat request body
+	maxChatBodyBytes = 64 << 10
+	// workflowTimeout bounds one chat or resumed workflow
+	workflowTimeout = 60 * time.Second
+	// shutdownTimeout bounds how long in-flight workflows and writes may drain.
+	// It outlasts workflowTimeout so a workflow started just before the signal
+	// can still finish.
+	shutdownTimeout = workflowTimeout + 15*time.Second
+	// disconnectTimeout bounds the MongoDB disconnect after draining
+	disconnectTimeout = 5 * time.Second
+)
+
 type ChatRequest struct {
 	Message string `json:"message"`
-	UserID  string `json:"user_id,omitempty"`
+	// UserID is replaced by the authenticated subject, and dropped for
+	// anonymous callers
+	UserID string `json:"user_id,omitempty"`
+	// Currency is the storefront currency (ISO 4217) to quote prices in
+	Currency string `json:"currency,omitempty"`
+	// SessionID continues an earlier conversation and its cart; a new
+	// session is started when empty
+	SessionID string `json:"session_id,omitempty"`
+	// CallbackURL receives the finished job of an async request
+	CallbackURL string `json:"callback_url,omitempty"`
 }
 
 type ChatResponse struct {
@@ -64,26 +128,51 @@ func NewPenShopMultiAgent() (*PenShopMultiAgent, error) {
 		model = strings.TrimPrefix(model, "openai/")
 	}
 
-	// Initialize sequential agent
-	sequentialAgent := agents.NewPenShopSequentialAgent(mcpGateway, logger)
-
-	// Register specialized agents
-	penResearchAgent := agents.NewPenResearchAgent(logger)
-	priceResearchAgent := agents.NewPriceResearchAgent(logger)
-	reviewAgent := agents.NewReviewAgent(logger)
-	recommendAgent := agents.NewRecommendationAgent(logger)
-
-	// Set MCP configuration for all agents
-	penResearchAgent.SetMCPConfig(openaiKey, baseURL, model, mcpGateway)
-	priceResearchAgent.SetMCPConfig(openaiKey, baseURL, model, mcpGateway)
-	reviewAgent.SetMCPConfig(openaiKey, baseURL, model, mcpGateway)
-	recommendAgent.SetMCPConfig(openaiKey, baseURL, model, mcpGateway)
-
-	// Register sub-agents with sequential agent
-	sequentialAgent.RegisterSubAgent(penResearchAgent)
-	sequentialAgent.RegisterSubAgent(priceResearchAgent)
-	sequentialAgent.RegisterSubAgent(reviewAgent)
-	sequentialAgent.RegisterSubAgent(recommendAgent)
+	// Model providers: OpenAI plus an optional local Docker Model Runner
+	localModel := os.Getenv("MODEL_RUNNER_MODEL")
+	if localModel == "" {
+		localModel = "ai/qwen3"
+	}
+	if !strings.Contains(localModel, "/") {
+		localModel = "ai/" + localModel
+	}
+	// Breakers, bulkheads and retries for every downstream dependency
+	dependencies := newResilience(logger)
+
+	llmRouter := llm.NewRouter(llm.Config{
+		OpenAIKey:       openaiKey,
+		OpenAIBaseURL:   baseURL,
+		OpenAIModel:     model,
+		LocalBaseURL:    os.Getenv("MODEL_RUNNER_URL"),
+		LocalModel:      localModel,
+		DefaultProvider: os.Getenv("LLM_PROVIDER"),
+		AgentProviders:  agentProvidersFromEnv("pen_research", "price_research", "review_agent", "recommend_agent"),
+		Failover:        os.Getenv("LLM_FAILOVER") != "false",
+		Resilience:      dependencies,
+	}, logger)
+
+	// Agents are created from factories once their dependencies are ready;
+	// the workflow looks each one up as it runs
+	registry := agents.NewAgentRegistry(logger)
+	sequentialAgent := agents.NewPenShopSequentialAgent(mcpGateway, registry, logger)
+	products := agents.Catalogue()
+
+	// Embeddings back semantic search and the model response cache
+	embedder := newEmbedder(openaiKey, baseURL, dependencies, logger)
+	llmCache := newLLMCache(embedder, logger)
+
+	// Exchange rates for quoting catalogue prices in the customer's currency
+	var rates *currency.FileRates
+	if path := os.Getenv("CURRENCY_RATES_FILE"); path != "" {
+		loaded, err := currency.NewFileRates(path)
+		if err != nil {
+			logger.Error("❌ Currency rates %s: %v, using indicative rates", path, err)
+		} else {
+			logger.Info("💱 Currency rates loaded from %s", path)
+		}
+		rates = loaded
+	}
+	defaultCurrency := currency.Normalize(os.Getenv("DEFAULT_CURRENCY"), currency.USD)
 
 	// Initialize MongoDB
 	mongoURI := os.Getenv("MONGODB_URI")
@@ -102,43 +191,331 @@ func NewPenShopMultiAgent() (*PenShopMultiAgent, error) {
 		logger.Error("❌ MongoDB connection failed: %v", err)
 	}
 
+	// Semantic search over catalogue descriptions and customer reviews
+	var semantic *agents.SemanticIndex
+	if embedder != nil {
+		semantic = agents.NewSemanticIndex(ctx, embedder, newVectorStore(mongoClient, logger), logger)
+		buildSemanticIndex(semantic, products, mongoClient, logger)
+		logger.Info("🧭 Semantic search enabled with %s", embedder.Name())
+	}
+
+	// Promotion rules from PROMOTIONS_FILE, otherwise the promotions collection
+	var promotionSource promotions.Source
+	if path := os.Getenv("PROMOTIONS_FILE"); path != "" {
+		promotionSource = promotions.NewFileSource(path)
+		logger.Info("🏷️ Promotions loaded from %s", path)
+	} else if mongoClient != nil {
+		promotionSource = promotions.NewMongoSource(mongoClient.Database("penstore").Collection("promotions"))
+	}
+	var promotionEngine *promotions.Engine
+	if promotionSource != nil {
+		promotionEngine = promotions.NewEngine(promotionSource, time.Minute)
+	}
+
+	// Rate limits and daily quotas per tier
+	limits := ratelimit.DefaultConfig()
+	if path := os.Getenv("RATE_LIMIT_CONFIG"); path != "" {
+		if loaded, err := ratelimit.LoadConfig(path); err == nil {
+			limits = loaded
+			logger.Info("🚦 Rate limit tiers loaded from %s", path)
+		} else {
+			logger.Error("❌ Rate limit config %s: %v, using defaults", path, err)
+		}
+	}
+
+	var quotas ratelimit.QuotaStore = ratelimit.NewMemoryQuotaStore()
+	if mongoClient != nil {
+		mongoQuotas := ratelimit.NewMongoQuotaStore(mongoClient.Database("penstore").Collection("usage_quotas"))
+		go func() {
+			indexCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := mongoQuotas.EnsureIndexes(indexCtx); err != nil {
+				logger.Error("❌ usage_quotas indexes: %v", err)
+			}
+		}()
+		quotas = guardedQuotaStore{store: mongoQuotas, dep: dependencies.Dependency(resilience.MongoDB)}
+	}
+
+	// Conversation audit log with a retention TTL
+	var conversationStore conversationLog
+	if mongoClient != nil {
+		retentionDays := 90
+		if days, err := strconv.Atoi(os.Getenv("CONVERSATION_RETENTION_DAYS")); err == nil && days > 0 {
+			retentionDays = days
+		}
+		mongoConversations := conversations.NewStore(
+			mongoClient.Database("penstore").Collection("ai_conversations"),
+			time.Duration(retentionDays)*24*time.Hour,
+		)
+		go func() {
+			indexCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := mongoConversations.EnsureIndexes(indexCtx); err != nil {
+				logger.Error("❌ ai_conversations indexes: %v", err)
+			}
+		}()
+		conversationStore = guardedConversations{store: mongoConversations, dep: dependencies.Dependency(resilience.MongoDB)}
+	}
+
 	catalogueURL := os.Getenv("CATALOGUE_URL")
 	if catalogueURL == "" {
 		catalogueURL = "http://pen-catalogue:8081"
 	}
 
+	// Carts and mock checkout, with prices validated against the catalogue
+	var cartStore cart.Store = cart.NewMemoryStore()
+	if mongoClient != nil {
+		mongoCarts := cart.NewMongoStore(
+			mongoClient.Database("penstore").Collection("carts"),
+			mongoClient.Database("penstore").Collection("orders"),
+		)
+		go func() {
+			indexCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := mongoCarts.EnsureIndexes(indexCtx); err != nil {
+				logger.Error("❌ orders indexes: %v", err)
+			}
+		}()
+		cartStore = guardedCartStore{store: mongoCarts, dep: dependencies.Dependency(resilience.MongoDB)}
+	}
+	nibOptions := make(map[string][]string)
+	for _, product := range products {
+		nibOptions[product.ID] = product.NibSizes
+	}
+	catalogueDependency := dependencies.Dependency(resilience.Catalogue)
+	carts := cart.NewService(cartStore, guardedCatalogue(cart.NewHTTPCatalogue(catalogueURL), catalogueDependency), nibOptions)
+
+	// Snapshot catalogue prices on a schedule so agents can judge trends
+	var priceHistory pricehistory.Store = pricehistory.NewMemoryStore()
+	if mongoClient != nil {
+		mongoHistory := pricehistory.NewMongoStore(mongoClient.Database("penstore").Collection("price_history"))
+		go func() {
+			indexCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := mongoHistory.EnsureIndexes(indexCtx); err != nil {
+				logger.Error("❌ price_history indexes: %v", err)
+			}
+		}()
+		priceHistory = guardedPriceHistory{store: mongoHistory, dep: dependencies.Dependency(resilience.MongoDB)}
+	}
+	snapshotInterval := 6 * time.Hour
+	if value := os.Getenv("PRICE_HISTORY_INTERVAL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			snapshotInterval = parsed
+		} else {
+			logger.Error("❌ Invalid PRICE_HISTORY_INTERVAL %q, using %s", value, snapshotInterval)
+		}
+	}
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	priceSource := guardedPriceSource{source: pricehistory.NewCatalogueSource(catalogueURL), dep: catalogueDependency}
+	recorder := pricehistory.NewRecorder(priceSource, priceHistory,
+		clock.System{}, snapshotInterval, logger)
+	go recorder.Run(backgroundCtx)
+	logger.Info("📈 Recording catalogue prices every %s", snapshotInterval)
+
+	// Watch availability and tell subscribers when pens come back in stock
+	var subscriptions inventory.Store = inventory.NewMemoryStore()
+	var notifier inventory.Notifier = inventory.NewLogNotifier(logger)
+	if mongoClient != nil {
+		mongoSubscriptions := inventory.NewMongoStore(mongoClient.Database("penstore").Collection("stock_subscriptions"))
+		go func() {
+			indexCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := mongoSubscriptions.EnsureIndexes(indexCtx); err != nil {
+				logger.Error("❌ stock_subscriptions indexes: %v", err)
+			}
+		}()
+		subscriptions = guardedSubscriptions{store: mongoSubscriptions, dep: dependencies.Dependency(resilience.MongoDB)}
+		notifier = guardedNotifier{
+			notifier: inventory.NewMongoNotifier(mongoClient.Database("penstore").Collection("stock_notifications")),
+			dep:      dependencies.Dependency(resilience.MongoDB),
+		}
+	}
+	stockInterval := 5 * time.Minute
+	if value := os.Getenv("STOCK_POLL_INTERVAL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			stockInterval = parsed
+		} else {
+			logger.Error("❌ Invalid STOCK_POLL_INTERVAL %q, using %s", value, stockInterval)
+		}
+	}
+	stockPoller := inventory.NewPoller(catalogueStock(priceSource), subscriptions, notifier,
+		clock.System{}, stockInterval, logger)
+	go stockPoller.Run(backgroundCtx)
+	logger.Info("🔔 Checking stock for back-in-stock alerts every %s", stockInterval)
+
+	aggregation, critiqueIterations := recommendationDefaults(logger)
+	registerAgentTypes(registry, agentDeps{
+		openaiKey:          openaiKey,
+		baseURL:            baseURL,
+		model:              model,
+		mcpGateway:         mcpGateway,
+		router:             llmRouter,
+		llmCache:           llmCache,
+		rates:              rates,
+		semantic:           semantic,
+		promotions:         promotionEngine,
+		priceHistory:       priceHistory,
+		carts:              carts,
+		products:           products,
+		availability:       stockAvailability(stockPoller),
+		aggregation:        aggregation,
+		critiqueIterations: critiqueIterations,
+		logger:             logger,
+	})
+	createAgents(registry, logger)
+
+	// Checkpoint workflows after every step so they can be inspected and resumed
+	if checkpoints := newCheckpointStore(mongoClient, dependencies, logger); checkpoints != nil {
+		sequentialAgent.SetCheckpoints(checkpoints)
+	}
+
+	// Cache workflow answers to repeated questions until the catalogue changes
+	var chatAgent models.Agent = sequentialAgent
+	responseCache := newResponseCache(sequentialAgent, mongoClient, dependencies, stockPoller, logger)
+	if responseCache != nil {
+		chatAgent = responseCache
+		stockPoller.OnCatalogueChange(func(version string) {
+			purgeCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if dropped, err := responseCache.Invalidate(purgeCtx); err != nil {
+				logger.Error("❌ Response cache invalidation failed: %v", err)
+			} else {
+				logger.Info("🧹 Catalogue changed (%s), dropped %d cached responses", version, dropped)
+			}
+		})
+	}
+
+	authMiddleware, err := newAuthMiddleware(logger)
+	if err != nil {
+		stopBackground()
+		return nil, err
+	}
+
+	// Background workers for async chat requests
+	jobPool, callbacks := newJobPool(logger)
+
 	return &PenShopMultiAgent{
-		sequentialAgent: sequentialAgent,
+		sequentialAgent: chatAgent,
+		workflows:       sequentialAgent,
+		registry:        registry,
+		responseCache:   responseCache,
+		llmCache:        llmCache,
+		dependencies:    dependencies,
 		mongodb:         mongoClient,
 		catalogueURL:    catalogueURL,
+		llmRouter:       llmRouter,
+		limiter:         ratelimit.NewLimiter(),
+		limits:          limits,
+		quotas:          quotas,
+		trustProxy:      os.Getenv("TRUST_PROXY_HEADERS") == "true",
+		auth:            authMiddleware,
+		conversations:   conversationStore,
+		rates:           rates,
+		defaultCurrency: defaultCurrency,
+		subscriptions:   subscriptions,
+		carts:           carts,
+		stockPoller:     stockPoller,
+		jobs:            jobPool,
+		callbacks:       callbacks,
 		logger:          logger,
+		stopBackground:  stopBackground,
 	}, nil
 }
 
+// newAuthMiddleware builds the authenticator chain from AUTH_* settings.
+// Static API keys come from AUTH_API_KEYS_FILE, bearer tokens are verified
+// against AUTH_HMAC_SECRET and the local JWKS in AUTH_JWKS_FILE.
+func newAuthMiddleware(logger models.Logger) (*auth.Middleware, error) {
+	var authenticators []auth.Authenticator
+
+	if path := os.Getenv("AUTH_API_KEYS_FILE"); path != "" {
+		keys, err := auth.LoadAPIKeys(path)
+		if err != nil {
+			return nil, fmt.Errorf("load API keys: %w", err)
+		}
+		authenticators = append(authenticators, auth.NewAPIKeyAuthenticator(keys))
+		logger.Info("🔐 Loaded %d API keys", len(keys))
+	}
+
+	keySet := auth.NewKeySet()
+	if secret := os.Getenv("AUTH_HMAC_SECRET"); secret != "" {
+		keySet.AddHMACSecret("", []byte(secret))
+	}
+	if path := os.Getenv("AUTH_JWKS_FILE"); path != "" {
+		if err := keySet.LoadJWKS(path); err != nil {
+			return nil, fmt.Errorf("load JWKS: %w", err)
+		}
+	}
+	if keySet.Len() > 0 {
+		authenticators = append(authenticators, auth.NewJWTAuthenticator(keySet, os.Getenv("AUTH_JWT_ISSUER"), os.Getenv("AUTH_JWT_AUDIENCE")))
+		logger.Info("🔐 Bearer tokens verified against %d keys", keySet.Len())
+	}
+
+	required := os.Getenv("AUTH_REQUIRED") == "true"
+	if required && len(authenticators) == 0 {
+		return nil, fmt.Errorf("AUTH_REQUIRED is set but no API keys, HMAC secret or JWKS are configured")
+	}
+
+	return auth.NewMiddleware(authenticators, required, []string{"/api/health"}, logger), nil
+}
+
+// agentProvidersFromEnv reads per-agent provider overrides such as
+// LLM_PROVIDER_PEN_RESEARCH=local
+func agentProvidersFromEnv(agentNames ...string) map[string]string {
+	providers := make(map[string]string)
+	for _, name := range agentNames {
+		if value := os.Getenv("LLM_PROVIDER_" + strings.ToUpper(name)); value != "" {
+			providers[name] = value
+		}
+	}
+	return providers
+}
+
 func (psma *PenShopMultiAgent) handleChat(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 
+	r.Body = http.MaxBytesReader(w, r.Body, maxChatBodyBytes)
+
 	var req ChatRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	// Log to MongoDB if available
-	if psma.mongodb != nil {
-		go func() {
-			collection := psma.mongodb.Database("penstore").Collection("ai_conversations")
-			collection.InsertOne(context.Background(), map[string]interface{}{
-				"message":    req.Message,
-				"user_id":    req.UserID,
-				"timestamp":  time.Now(),
-				"agent_mode": "sequential",
-			})
-		}()
+	// Only an authenticated subject identifies the caller. Anyone can put a
+	// user_id in the body, and it would open that user's carts and quota.
+	principal := auth.PrincipalFrom(r.Context())
+	if principal != nil {
+		req.UserID = principal.Subject
+	} else {
+		req.UserID = ""
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
+	caller, allowed := psma.enforceRateLimits(w, r, principal)
+	if !allowed {
+		return
+	}
+
+	sessionID := req.SessionID
+	if sessionID == "" {
+		sessionID = newSessionID()
+	} else if !validSessionID(sessionID) {
+		http.Error(w, "Invalid session_id", http.StatusBadRequest)
+		return
+	} else if owns, err := psma.ownsCart(r.Context(), sessionID, req.UserID); err != nil {
+		psma.logger.Error("Failed to check cart owner: %v", err)
+		http.Error(w, "Session unavailable, try again later", http.StatusServiceUnavailable)
+		return
+	} else if !owns {
+		http.Error(w, "Session belongs to another user", http.StatusForbidden)
+		return
+	}
 
 	// Create query
 	query := models.Query{
@@ -150,52 +527,193 @@ func (psma *PenShopMultiAgent) handleChat(w http.ResponseWriter, r *http.Request
 			"source":       "web_ui",
 			"catalogue_url": psma.catalogueURL,
 		},
-		Priority: 1,
+		Priority:  1,
+		Principal: principal,
+		Currency:  currency.Normalize(req.Currency, psma.defaultCurrency),
+		SessionID: sessionID,
+	}
+	record := newConversationRecord(query, sessionID, clientIP(r, psma.trustProxy))
+
+	// Refuse requests for sensitive data before any agent sees them
+	if blocked, boundary := CheckSensitiveRequest(req.Message); blocked {
+		record.Status = conversations.StatusSecurityBlocked
+		record.SecurityBlocked = true
+		record.Response.Content = boundary
+		psma.logConversation(record)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ChatResponse{
+			Response:  boundary,
+			SessionID: sessionID,
+			Metadata: map[string]interface{}{
+				"security_boundary": true,
+				"conversation_id":   record.ID,
+			},
+		})
+		return
 	}
 
+	// Long research questions run in the background and are polled for
+	if async, _ := strconv.ParseBool(r.URL.Query().Get("async")); async {
+		psma.submitChatJob(w, req, query, record, caller, startTime)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), workflowTimeout)
+	defer cancel()
+
+	chatResponse, err := psma.runChat(ctx, query, record, caller, startTime)
+	if err != nil {
+		if failure := chatFailure(sessionID, err, psma.workflows.Checkpointing()); failure != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(failure)
+			return
+		}
+		http.Error(w, "Failed to process chat", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chatResponse)
+}
+
+// runChat processes a query with the sequential agent and builds the chat
+// response, logging the conversation whether or not it succeeds
+func (psma *PenShopMultiAgent) runChat(ctx context.Context, query models.Query, record conversations.Record, caller callerLimits, startTime time.Time) (ChatResponse, error) {
+	usageTracker := &llm.UsageTracker{}
+	ctx = llm.WithUsageTracker(ctx, usageTracker)
+
 	// Process with sequential agent
 	response, err := psma.sequentialAgent.Process(ctx, query)
 	if err != nil {
 		psma.logger.Error("Failed to process query: %v", err)
-		http.Error(w, "Failed to process chat", http.StatusInternalServerError)
-		return
+		// Tokens spent before the failure still count against the quota
+		psma.recordUsage(caller, usageTracker)
+		psma.failConversation(record, err, usageTracker)
+		return ChatResponse{}, err
 	}
 
+	psma.recordUsage(caller, usageTracker)
+	tokensUsed, costUSD := usageTracker.Totals()
+
+	completeConversationRecord(&record, response, usageTracker)
+	psma.logConversation(record)
+
 	processingTime := time.Since(startTime)
 
 	chatResponse := ChatResponse{
 		Response:       response.Content,
-		SessionID:      fmt.Sprintf("pen_session_%d", time.Now().Unix()),
+		SessionID:      query.SessionID,
+		AgentsUsed:     record.AgentsUsed,
 		ProcessingTime: processingTime,
 		Metadata: map[string]interface{}{
 			"agent_mode":      "sequential",
 			"query_id":        query.ID,
+			"conversation_id": record.ID,
 			"processing_time": processingTime.Milliseconds(),
 			"agents_executed": response.Metadata["steps_executed"],
+			"rate_limit_tier": caller.tierName,
+			"tokens_used":     tokensUsed,
+			"cost_usd":        costUSD,
 		},
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(chatResponse)
+	if steps, ok := response.Metadata["steps"].([]agents.StepResult); ok {
+		chatResponse.Metadata["steps"] = stepSummaries(steps)
+	}
+	for _, key := range []string{"comparison", "cart", "order", "awaiting_confirmation", "cache_hit", "degraded", "degraded_steps", "execution_id", "intent", "route"} {
+		if value, ok := response.Metadata[key]; ok {
+			chatResponse.Metadata[key] = value
+		}
+	}
+	chatResponse.Metadata["currency"] = query.Currency
+	if code, ok := response.Metadata["currency"]; ok {
+		chatResponse.Metadata["currency"] = code
+	}
+	return chatResponse, nil
+}
+
+// chatFailure describes a failed chat whose workflow was checkpointed, so the
+// client can pick it up where it stopped; it is nil when there is nothing to
+// resume
+func chatFailure(sessionID string, err error, checkpointing bool) map[string]interface{} {
+	var workflowErr *agents.WorkflowError
+	if !checkpointing || !errors.As(err, &workflowErr) || workflowErr.ExecutionID == "" {
+		return nil
+	}
+	return map[string]interface{}{
+		"error":        "Failed to process chat",
+		"session_id":   sessionID,
+		"execution_id": workflowErr.ExecutionID,
+		"resume_url":   "/api/workflows/" + workflowErr.ExecutionID + "/resume",
+	}
 }
 
 func (psma *PenShopMultiAgent) handleHealth(w http.ResponseWriter, r *http.Request) {
 	status := map[string]interface{}{
-		"status":         "healthy",
-		"service":        "pen-shop-sequential-agent",
-		"agent_mode":     "sequential",
-		"has_openai":     os.Getenv("OPENAI_API_KEY") != "",
-		"has_mcp":        os.Getenv("MCPGATEWAY_ENDPOINT") != "",
-		"has_mongodb":    psma.mongodb != nil,
-		"catalogue":      psma.catalogueURL,
-		"timestamp":      time.Now(),
-		"architecture":   "ADK Sequential Agent Pattern",
+		"status":          "healthy",
+		"service":         "pen-shop-sequential-agent",
+		"agent_mode":      "sequential",
+		"has_openai":      os.Getenv("OPENAI_API_KEY") != "",
+		"has_mcp":         os.Getenv("MCPGATEWAY_ENDPOINT") != "",
+		"has_mongodb":     psma.mongodb != nil,
+		"has_local_model": psma.llmRouter.HasLocal(),
+		"catalogue":       psma.catalogueURL,
+		"timestamp":       time.Now(),
+		"architecture":    "ADK Sequential Agent Pattern",
+	}
+	if psma.responseCache != nil {
+		status["response_cache"] = psma.responseCache.Stats()
+	}
+	if psma.llmCache != nil {
+		status["llm_cache"] = psma.llmCache.Stats()
+	}
+	status["jobs"] = psma.jobs.Stats()
+	status["dependencies"] = psma.dependencies.Statuses()
+	if degraded := psma.dependencies.Degraded(); len(degraded) > 0 {
+		status["status"] = "degraded"
+		status["degraded_dependencies"] = degraded
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
 }
 
+// Close waits for pending background writes and disconnects from MongoDB.
+// The disconnect gets its own deadline, since ctx may have run out draining.
+func (psma *PenShopMultiAgent) Close(ctx context.Context) error {
+	psma.stopBackground()
+
+	// Async jobs log their conversations, so they finish before writes drain
+	if err := psma.jobs.Stop(ctx); err != nil {
+		psma.logger.Error("❌ Timed out waiting for async jobs: %v", err)
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		psma.pendingWrites.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		psma.logger.Info("📝 Pending conversation writes flushed")
+	case <-ctx.Done():
+		psma.logger.Error("❌ Timed out waiting for pending writes: %v", ctx.Err())
+	}
+
+	if psma.mongodb != nil {
+		disconnectCtx, cancel := context.WithTimeout(context.Background(), disconnectTimeout)
+		defer cancel()
+		if err := psma.mongodb.Disconnect(disconnectCtx); err != nil {
+			return fmt.Errorf("mongodb disconnect: %w", err)
+		}
+		psma.logger.Info("🍃 MongoDB disconnected")
+	}
+	return nil
+}
+
 func main() {
 	penShop, err := NewPenShopMultiAgent()
 	if err != nil {
@@ -203,14 +721,34 @@ func main() {
 	}
 
 	r := mux.NewRouter()
+	r.Use(penShop.auth.Handler)
 
 	r.HandleFunc("/api/chat", penShop.handleChat).Methods("POST")
 	r.HandleFunc("/api/health", penShop.handleHealth).Methods("GET")
+	r.HandleFunc("/api/notify", penShop.handleNotify).Methods("POST")
+	r.HandleFunc("/api/jobs/{id}", penShop.handleGetJob).Methods("GET")
+	r.HandleFunc("/api/jobs/{id}", penShop.handleCancelJob).Methods("DELETE")
+	r.HandleFunc("/api/workflows/{id}", penShop.handleGetWorkflow).Methods("GET")
+	r.HandleFunc("/api/workflows/{id}/resume", penShop.handleResumeWorkflow).Methods("POST")
+	r.HandleFunc("/api/cart/{session}", penShop.handleGetCart).Methods("GET")
+	r.HandleFunc("/api/cart/{session}/items", penShop.handleAddCartItem).Methods("POST")
+	r.HandleFunc("/api/cart/{session}/items/{sku}", penShop.handleRemoveCartItem).Methods("DELETE")
+	r.HandleFunc("/api/cart/{session}/checkout", penShop.handleCheckout).Methods("POST")
+	r.HandleFunc("/api/admin/conversations", auth.RequireScope("admin", penShop.handleListConversations)).Methods("GET")
+	r.HandleFunc("/api/admin/conversations/{id}", auth.RequireScope("admin", penShop.handleGetConversation)).Methods("GET")
+	r.HandleFunc("/api/admin/currency/refresh", auth.RequireScope("admin", penShop.handleRefreshRates)).Methods("POST")
+	r.HandleFunc("/api/admin/cache/invalidate", auth.RequireScope("admin", penShop.handleInvalidateCache)).Methods("POST")
+	r.HandleFunc("/api/admin/agents", auth.RequireScope("admin", penShop.handleListAgents)).Methods("GET")
+	r.HandleFunc("/api/admin/agents", auth.RequireScope("admin", penShop.handleCreateAgent)).Methods("POST")
+	r.HandleFunc("/api/admin/agents/{name}", auth.RequireScope("admin", penShop.handleGetAgent)).Methods("GET")
+	r.HandleFunc("/api/admin/agents/{name}", auth.RequireScope("admin", penShop.handleUpdateAgent)).Methods("PATCH")
+	r.HandleFunc("/api/admin/agents/{name}", auth.RequireScope("admin", penShop.handleDeleteAgent)).Methods("DELETE")
 
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{"http://localhost:3000", "http://localhost:9090", "*"},
-		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
+		AllowedMethods: []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders: []string{"*"},
+		ExposedHeaders: []string{"Retry-After"},
 	})
 
 	handler := c.Handler(r)
@@ -226,7 +764,42 @@ func main() {
 	}())
 	penShop.logger.Info("🔗 MCP Gateway: %s", os.Getenv("MCPGATEWAY_ENDPOINT"))
 
-	if err := http.ListenAndServe(":"+port, handler); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		// Chat workflows may run for up to workflowTimeout
+		WriteTimeout: workflowTimeout + 15*time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	case <-ctx.Done():
+		penShop.logger.Info("🛑 Shutdown signal received, draining in-flight requests")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Shutdown stops accepting connections and waits for in-flight workflows to finish
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		penShop.logger.Error("❌ HTTP shutdown: %v", err)
+	}
+	if err := penShop.Close(shutdownCtx); err != nil {
+		penShop.logger.Error("❌ Cleanup: %v", err)
 	}
+	penShop.logger.Info("👋 Pen Shop stopped")
 }
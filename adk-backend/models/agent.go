@@ -12,6 +12,12 @@ type Query struct {
 	UserID   string                 `json:"user_id"`
 	Context  map[string]interface{} `json:"context"`
 	Priority int                    `json:"priority"`
+	// Principal is the authenticated caller, nil for anonymous queries
+	Principal *Principal `json:"principal,omitempty"`
+	// Currency is the storefront currency (ISO 4217) prices are quoted in
+	Currency string `json:"currency,omitempty"`
+	// SessionID identifies the conversation, and with it the customer's cart
+	SessionID string `json:"session_id,omitempty"`
 }
 
 // Response represents an agent response
@@ -0,0 +1,24 @@
+package models
+
+// Principal is the authenticated caller behind a query
+type Principal struct {
+	Subject string   `json:"subject"`
+	Tenant  string   `json:"tenant,omitempty"`
+	Tier    string   `json:"tier,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"`
+	// Method is how the caller authenticated: "api_key" or "jwt"
+	Method string `json:"method"`
+}
+
+// HasScope reports whether the principal was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"pen-shop/auth"
+	"pen-shop/inventory"
+)
+
+// maxNotifyBodyBytes caps the size of a /api/notify request body
+const maxNotifyBodyBytes = 4 << 10
+
+// NotifyRequest asks to be told when a product is back in stock
+type NotifyRequest struct {
+	SKU string `json:"sku"`
+	// UserID is replaced by the authenticated subject, and by the chat
+	// session for anonymous callers
+	UserID    string `json:"user_id,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	Email     string `json:"email,omitempty"`
+}
+
+// handleNotify serves POST /api/notify. Subscribing twice to the same product
+// returns the existing subscription; products already in stock are refused
+// with 409 and SKUs the catalogue does not list with 400. Anonymous callers
+// subscribe for their chat session and get 401 without one.
+func (psma *PenShopMultiAgent) handleNotify(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxNotifyBodyBytes)
+
+	var req NotifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Only the authenticated subject may subscribe as a user; anyone could
+	// claim a user_id in the body, so anonymous subscriptions belong to the
+	// chat session instead and can never collide with a user's
+	principal := auth.PrincipalFrom(r.Context())
+	if principal != nil {
+		req.UserID = principal.Subject
+	} else if validSessionID(req.SessionID) {
+		req.UserID = "session:" + req.SessionID
+	} else {
+		http.Error(w, "Sign in or send the session_id of your chat", http.StatusUnauthorized)
+		return
+	}
+
+	req.SKU = strings.TrimSpace(req.SKU)
+	req.Email = strings.TrimSpace(req.Email)
+	if req.SKU == "" {
+		http.Error(w, "sku is required", http.StatusBadRequest)
+		return
+	}
+	if req.Email != "" {
+		if _, err := mail.ParseAddress(req.Email); err != nil {
+			http.Error(w, "Invalid email", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, allowed := psma.enforceRateLimits(w, r, principal); !allowed {
+		return
+	}
+
+	// Until the first stock poll completes every SKU is accepted
+	if inStock, known := psma.stockPoller.Availability(req.SKU); known && inStock {
+		http.Error(w, "Product is in stock", http.StatusConflict)
+		return
+	} else if !known && psma.stockPoller.Polled() {
+		http.Error(w, "Unknown sku", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	sub, err := psma.subscriptions.Subscribe(ctx, inventory.Subscription{
+		SKU:       req.SKU,
+		UserID:    req.UserID,
+		Email:     req.Email,
+		Status:    inventory.StatusPending,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		psma.logger.Error("Failed to save stock subscription: %v", err)
+		http.Error(w, "Failed to save subscription", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sub)
+}
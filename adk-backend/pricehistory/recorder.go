@@ -0,0 +1,60 @@
+package pricehistory
+
+import (
+	"context"
+	"time"
+
+	"pen-shop/clock"
+	"pen-shop/models"
+)
+
+// Recorder snapshots catalogue prices into a store on a fixed interval
+type Recorder struct {
+	source   Source
+	store    Store
+	clock    clock.Clock
+	interval time.Duration
+	logger   models.Logger
+}
+
+func NewRecorder(source Source, store Store, clock clock.Clock, interval time.Duration, logger models.Logger) *Recorder {
+	return &Recorder{source: source, store: store, clock: clock, interval: interval, logger: logger}
+}
+
+// RecordOnce stores one snapshot of every catalogue price, stamped with the clock's time
+func (r *Recorder) RecordOnce(ctx context.Context) (int, error) {
+	snapshots, err := r.source.Prices(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := r.clock.Now()
+	for i := range snapshots {
+		snapshots[i].At = now
+	}
+	if err := r.store.Append(ctx, snapshots); err != nil {
+		return 0, err
+	}
+	return len(snapshots), nil
+}
+
+// Run records immediately and then every interval until ctx is cancelled.
+// Failed snapshots are logged and retried on the next tick.
+func (r *Recorder) Run(ctx context.Context) {
+	for {
+		recordCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		count, err := r.RecordOnce(recordCtx)
+		cancel()
+		if err != nil {
+			r.logger.Error("❌ Price snapshot failed: %v", err)
+		} else {
+			r.logger.Debug("📈 Recorded %d catalogue prices", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.clock.After(r.interval):
+		}
+	}
+}
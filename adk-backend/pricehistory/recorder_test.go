@@ -0,0 +1,113 @@
+package pricehistory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"pen-shop/clock"
+)
+
+type testLogger struct {
+	mu     sync.Mutex
+	errors int
+}
+
+func (l *testLogger) Info(msg string, args ...interface{})  {}
+func (l *testLogger) Debug(msg string, args ...interface{}) {}
+func (l *testLogger) Error(msg string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors++
+}
+
+func (l *testLogger) Errors() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.errors
+}
+
+// priceList serves the next price on every call, failing where the price is negative
+type priceList struct {
+	mu     sync.Mutex
+	prices []float64
+	calls  int
+}
+
+func (pl *priceList) Prices(ctx context.Context) ([]Snapshot, error) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	price := pl.prices[pl.calls%len(pl.prices)]
+	pl.calls++
+	if price < 0 {
+		return nil, errors.New("catalogue unavailable")
+	}
+	return []Snapshot{{SKU: "lamy-safari", PriceUSD: price}}, nil
+}
+
+// waitForTick blocks until the recorder is waiting on its next tick
+func waitForTick(t *testing.T, clock *clock.Fake) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for clock.Waiters() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("recorder never waited for its next tick")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRecorderRun(t *testing.T) {
+	clock := clock.NewFake(epoch)
+	store := NewMemoryStore()
+	source := &priceList{prices: []float64{30, -1, 25}}
+	logger := &testLogger{}
+	recorder := NewRecorder(source, store, clock, 6*time.Hour, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		recorder.Run(ctx)
+		close(done)
+	}()
+
+	// Records straight away, then once per interval
+	waitForTick(t, clock)
+	clock.Advance(5 * time.Hour)
+	if clock.Waiters() != 1 {
+		t.Fatal("recorder ticked before its interval")
+	}
+	clock.Advance(time.Hour)
+	waitForTick(t, clock)
+	clock.Advance(6 * time.Hour)
+	waitForTick(t, clock)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not stop when its context was cancelled")
+	}
+
+	snapshots, _ := store.History(context.Background(), "lamy-safari", time.Time{})
+	if len(snapshots) != 2 {
+		t.Fatalf("recorded %d snapshots, want 2: %+v", len(snapshots), snapshots)
+	}
+	// The failed tick is skipped, and its slot is not filled in later
+	if snapshots[0].PriceUSD != 30 || !snapshots[0].At.Equal(epoch) {
+		t.Errorf("first snapshot = %+v, want 30 at %s", snapshots[0], epoch)
+	}
+	if want := epoch.Add(12 * time.Hour); snapshots[1].PriceUSD != 25 || !snapshots[1].At.Equal(want) {
+		t.Errorf("second snapshot = %+v, want 25 at %s", snapshots[1], want)
+	}
+	if got := logger.Errors(); got != 1 {
+		t.Errorf("logged %d errors, want 1 for the failed tick", got)
+	}
+
+	trend, _ := Analyze(snapshots)
+	if trend.Verdict() != VerdictLowest {
+		t.Errorf("verdict = %s, want %s after the drop", trend.Verdict(), VerdictLowest)
+	}
+}
@@ -0,0 +1,76 @@
+package pricehistory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Source lists current catalogue prices
+type Source interface {
+	Prices(ctx context.Context) ([]Snapshot, error)
+}
+
+// CatalogueSource reads prices from the pen-catalogue service's /catalogue endpoint
+type CatalogueSource struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewCatalogueSource(baseURL string) *CatalogueSource {
+	return &CatalogueSource{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (cs *CatalogueSource) Prices(ctx context.Context) ([]Snapshot, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cs.baseURL+"/catalogue", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cs.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("catalogue request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("catalogue returned status %d", resp.StatusCode)
+	}
+
+	var pens []struct {
+		ID      string  `json:"id"`
+		Name    string  `json:"name"`
+		Brand   string  `json:"brand"`
+		Price   float64 `json:"price"`
+		InStock bool    `json:"in_stock"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(&pens); err != nil {
+		return nil, fmt.Errorf("catalogue returned invalid body: %w", err)
+	}
+
+	snapshots := make([]Snapshot, 0, len(pens))
+	for _, pen := range pens {
+		snapshots = append(snapshots, Snapshot{
+			SKU:      pen.ID,
+			Name:     pen.Name,
+			Brand:    pen.Brand,
+			PriceUSD: pen.Price,
+			InStock:  pen.InStock,
+		})
+	}
+	return snapshots, nil
+}
+
+// SourceFunc adapts a function to a Source
+type SourceFunc func(ctx context.Context) ([]Snapshot, error)
+
+func (f SourceFunc) Prices(ctx context.Context) ([]Snapshot, error) {
+	return f(ctx)
+}
@@ -0,0 +1,107 @@
+package pricehistory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Snapshot is a product's catalogue price at one point in time
+type Snapshot struct {
+	SKU      string    `json:"sku" bson:"sku"`
+	Name     string    `json:"name" bson:"name"`
+	Brand    string    `json:"brand" bson:"brand"`
+	PriceUSD float64   `json:"price_usd" bson:"price_usd"`
+	InStock  bool      `json:"in_stock" bson:"in_stock"`
+	At       time.Time `json:"at" bson:"at"`
+}
+
+// Store persists price snapshots
+type Store interface {
+	Append(ctx context.Context, snapshots []Snapshot) error
+	// History returns a product's snapshots since a time, oldest first
+	History(ctx context.Context, sku string, since time.Time) ([]Snapshot, error)
+}
+
+// MongoStore keeps snapshots in the price_history collection
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoStore(collection *mongo.Collection) *MongoStore {
+	return &MongoStore{collection: collection}
+}
+
+// EnsureIndexes creates the per-product time index
+func (ms *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := ms.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "sku", Value: 1}, {Key: "at", Value: 1}},
+	})
+	return err
+}
+
+func (ms *MongoStore) Append(ctx context.Context, snapshots []Snapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(snapshots))
+	for i, snapshot := range snapshots {
+		docs[i] = snapshot
+	}
+	_, err := ms.collection.InsertMany(ctx, docs)
+	return err
+}
+
+func (ms *MongoStore) History(ctx context.Context, sku string, since time.Time) ([]Snapshot, error) {
+	cursor, err := ms.collection.Find(ctx,
+		bson.M{"sku": sku, "at": bson.M{"$gte": since}},
+		options.Find().SetSort(bson.D{{Key: "at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// MemoryStore keeps snapshots in process; used when MongoDB is unavailable
+type MemoryStore struct {
+	mu    sync.RWMutex
+	bySKU map[string][]Snapshot
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{bySKU: make(map[string][]Snapshot)}
+}
+
+func (ms *MemoryStore) Append(ctx context.Context, snapshots []Snapshot) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, snapshot := range snapshots {
+		history := append(ms.bySKU[snapshot.SKU], snapshot)
+		sort.SliceStable(history, func(i, j int) bool { return history[i].At.Before(history[j].At) })
+		ms.bySKU[snapshot.SKU] = history
+	}
+	return nil
+}
+
+func (ms *MemoryStore) History(ctx context.Context, sku string, since time.Time) ([]Snapshot, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var snapshots []Snapshot
+	for _, snapshot := range ms.bySKU[sku] {
+		if !snapshot.At.Before(since) {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots, nil
+}
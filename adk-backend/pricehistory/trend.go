@@ -0,0 +1,95 @@
+package pricehistory
+
+import (
+	"math"
+	"time"
+)
+
+// Change is a price movement between two consecutive snapshots
+type Change struct {
+	FromUSD float64   `json:"from_usd"`
+	ToUSD   float64   `json:"to_usd"`
+	At      time.Time `json:"at"`
+	Percent float64   `json:"percent"`
+}
+
+// Trend summarises a product's recorded prices
+type Trend struct {
+	SKU        string    `json:"sku"`
+	Samples    int       `json:"samples"`
+	Since      time.Time `json:"since"`
+	CurrentUSD float64   `json:"current_usd"`
+	MinUSD     float64   `json:"min_usd"`
+	MinAt      time.Time `json:"min_at"`
+	MaxUSD     float64   `json:"max_usd"`
+	MaxAt      time.Time `json:"max_at"`
+	AverageUSD float64   `json:"average_usd"`
+	// RecentDrop is the latest price decrease, nil if the price never fell
+	RecentDrop *Change `json:"recent_drop,omitempty"`
+}
+
+// Verdicts on whether the current price is worth buying at
+const (
+	VerdictLowest  = "lowest"
+	VerdictGood    = "good"
+	VerdictTypical = "typical"
+	VerdictHigh    = "high"
+)
+
+// Analyze summarises snapshots ordered oldest first. It returns false when
+// there is no history to summarise.
+func Analyze(snapshots []Snapshot) (Trend, bool) {
+	if len(snapshots) == 0 {
+		return Trend{}, false
+	}
+
+	first := snapshots[0]
+	trend := Trend{
+		SKU:     first.SKU,
+		Samples: len(snapshots),
+		Since:   first.At,
+		MinUSD:  first.PriceUSD,
+		MinAt:   first.At,
+		MaxUSD:  first.PriceUSD,
+		MaxAt:   first.At,
+	}
+
+	var sum float64
+	for i, snapshot := range snapshots {
+		sum += snapshot.PriceUSD
+		// Ties keep the most recent date so "lowest since" reads naturally
+		if snapshot.PriceUSD <= trend.MinUSD {
+			trend.MinUSD, trend.MinAt = snapshot.PriceUSD, snapshot.At
+		}
+		if snapshot.PriceUSD >= trend.MaxUSD {
+			trend.MaxUSD, trend.MaxAt = snapshot.PriceUSD, snapshot.At
+		}
+		if i > 0 && snapshot.PriceUSD < snapshots[i-1].PriceUSD {
+			previous := snapshots[i-1].PriceUSD
+			trend.RecentDrop = &Change{
+				FromUSD: previous,
+				ToUSD:   snapshot.PriceUSD,
+				At:      snapshot.At,
+				Percent: math.Round((snapshot.PriceUSD-previous)/previous*1000) / 10,
+			}
+		}
+	}
+
+	trend.CurrentUSD = snapshots[len(snapshots)-1].PriceUSD
+	trend.AverageUSD = math.Round(sum/float64(len(snapshots))*100) / 100
+	return trend, true
+}
+
+// Verdict classifies the current price against the recorded range
+func (t Trend) Verdict() string {
+	switch {
+	case t.MinUSD < t.MaxUSD && t.CurrentUSD <= t.MinUSD:
+		return VerdictLowest
+	case t.CurrentUSD <= t.AverageUSD*0.97:
+		return VerdictGood
+	case t.CurrentUSD >= t.AverageUSD*1.03:
+		return VerdictHigh
+	default:
+		return VerdictTypical
+	}
+}
@@ -0,0 +1,87 @@
+package pricehistory
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// history builds daily snapshots of one product, oldest first
+func history(prices ...float64) []Snapshot {
+	snapshots := make([]Snapshot, len(prices))
+	for i, price := range prices {
+		snapshots[i] = Snapshot{SKU: "lamy-safari", PriceUSD: price, At: epoch.AddDate(0, 0, i)}
+	}
+	return snapshots
+}
+
+func TestAnalyzeEmpty(t *testing.T) {
+	if _, ok := Analyze(nil); ok {
+		t.Fatal("Analyze(nil) reported a trend")
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	trend, ok := Analyze(history(30, 25, 28, 25, 27))
+	if !ok {
+		t.Fatal("Analyze reported no trend")
+	}
+
+	if trend.SKU != "lamy-safari" || trend.Samples != 5 || !trend.Since.Equal(epoch) {
+		t.Errorf("trend = %+v, want 5 samples of lamy-safari since %s", trend, epoch)
+	}
+	if trend.CurrentUSD != 27 || trend.AverageUSD != 27 {
+		t.Errorf("current = %v, average = %v, want 27 and 27", trend.CurrentUSD, trend.AverageUSD)
+	}
+	if trend.MaxUSD != 30 || !trend.MaxAt.Equal(epoch) {
+		t.Errorf("max = %v at %s, want 30 on day 0", trend.MaxUSD, trend.MaxAt)
+	}
+	// Ties on the minimum keep the most recent date
+	if trend.MinUSD != 25 || !trend.MinAt.Equal(epoch.AddDate(0, 0, 3)) {
+		t.Errorf("min = %v at %s, want 25 on day 3", trend.MinUSD, trend.MinAt)
+	}
+
+	drop := trend.RecentDrop
+	if drop == nil {
+		t.Fatal("no recent drop")
+	}
+	if drop.FromUSD != 28 || drop.ToUSD != 25 || !drop.At.Equal(epoch.AddDate(0, 0, 3)) || drop.Percent != -10.7 {
+		t.Errorf("recent drop = %+v, want 28 -> 25 (-10.7%%) on day 3", *drop)
+	}
+}
+
+func TestAnalyzeRisingPrice(t *testing.T) {
+	trend, _ := Analyze(history(20, 22, 24))
+	if trend.RecentDrop != nil {
+		t.Errorf("recent drop = %+v, want none", *trend.RecentDrop)
+	}
+	if trend.AverageUSD != 22 {
+		t.Errorf("average = %v, want 22", trend.AverageUSD)
+	}
+}
+
+func TestVerdict(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []float64
+		want   string
+	}{
+		{"at the recorded low", []float64{30, 28, 25}, VerdictLowest},
+		{"well under average", []float64{30, 30, 20, 30, 26}, VerdictGood},
+		{"well over average", []float64{20, 20, 20, 24}, VerdictHigh},
+		{"close to average", []float64{25, 26, 24, 25}, VerdictTypical},
+		// A flat price has no low to beat
+		{"never changed", []float64{25, 25, 25}, VerdictTypical},
+		{"single sample", []float64{25}, VerdictTypical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trend, _ := Analyze(history(tt.prices...))
+			if got := trend.Verdict(); got != tt.want {
+				t.Errorf("Verdict() = %s, want %s (trend %+v)", got, tt.want, trend)
+			}
+		})
+	}
+}
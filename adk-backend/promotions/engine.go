@@ -0,0 +1,126 @@
+package promotions
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quote is a product's price after the best promotion that applies to it
+type Quote struct {
+	SKU          string  `json:"sku"`
+	OriginalUSD  float64 `json:"original_usd"`
+	EffectiveUSD float64 `json:"effective_usd"`
+	// Applied is the discount used for EffectiveUSD, nil when none applies
+	Applied *Rule `json:"applied,omitempty"`
+	// WithCode lists code-only discounts the customer could still claim
+	WithCode []Rule `json:"with_code,omitempty"`
+}
+
+// Engine evaluates promotion rules, re-reading them from its source at most
+// once per refresh interval so edits in MongoDB or the rules file go live
+type Engine struct {
+	source  Source
+	refresh time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	rules    []Rule
+	loadedAt time.Time
+}
+
+func NewEngine(source Source, refresh time.Duration) *Engine {
+	return &Engine{source: source, refresh: refresh, now: time.Now}
+}
+
+// Rules returns every configured rule, refreshing the cache when stale. On a
+// load error the previous rules keep being served.
+func (e *Engine) Rules(ctx context.Context) ([]Rule, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.loadedAt.IsZero() || e.now().Sub(e.loadedAt) >= e.refresh {
+		rules, err := e.source.Rules(ctx)
+		if err != nil {
+			return e.rules, err
+		}
+		e.rules = rules
+		e.loadedAt = e.now()
+	}
+	return e.rules, nil
+}
+
+// Active returns the rules in effect now
+func (e *Engine) Active(ctx context.Context) ([]Rule, error) {
+	rules, err := e.Rules(ctx)
+	now := e.now()
+
+	var active []Rule
+	for _, rule := range rules {
+		if rule.ActiveAt(now) {
+			active = append(active, rule)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].ID < active[j].ID })
+	return active, err
+}
+
+// Evaluate prices a product against the active rules. Discounts do not
+// stack: the largest automatic discount wins, and codes apply only when the
+// customer supplied them.
+func Evaluate(rules []Rule, product Product, codes []string) Quote {
+	quote := Quote{SKU: product.SKU, OriginalUSD: product.PriceUSD, EffectiveUSD: product.PriceUSD}
+
+	for i := range rules {
+		rule := rules[i]
+		if !rule.AppliesTo(product) || rule.Percent <= 0 {
+			continue
+		}
+		if rule.Code != "" && !containsFold(codes, rule.Code) {
+			quote.WithCode = append(quote.WithCode, rule)
+			continue
+		}
+
+		price := math.Round(product.PriceUSD*(100-math.Min(rule.Percent, 100))) / 100
+		if price < quote.EffectiveUSD {
+			quote.EffectiveUSD = price
+			quote.Applied = &rule
+		}
+	}
+	return quote
+}
+
+// FreeShipping returns the lowest free-shipping threshold among rules
+func FreeShipping(rules []Rule) (Rule, bool) {
+	var best Rule
+	found := false
+	for _, rule := range rules {
+		if rule.Kind != KindFreeShipping || rule.Code != "" {
+			continue
+		}
+		if !found || rule.MinOrderUSD < best.MinOrderUSD {
+			best, found = rule, true
+		}
+	}
+	return best, found
+}
+
+// Describe names a discount for customers, e.g. "10% off Parker"
+func Describe(rule Rule) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	scope := "everything"
+	if !rule.Storewide() {
+		var targets []string
+		targets = append(targets, rule.Brands...)
+		targets = append(targets, rule.Types...)
+		targets = append(targets, rule.SKUs...)
+		scope = strings.Join(targets, ", ")
+	}
+	return fmt.Sprintf("%g%% off %s", rule.Percent, scope)
+}
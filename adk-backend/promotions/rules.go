@@ -0,0 +1,80 @@
+package promotions
+
+import (
+	"strings"
+	"time"
+)
+
+// Rule kinds
+const (
+	// KindPercentOff takes Percent off every product the rule applies to
+	KindPercentOff = "percent_off"
+	// KindFreeShipping waives shipping on orders of at least MinOrderUSD
+	KindFreeShipping = "free_shipping"
+)
+
+// Rule is one promotion. Brands, Types and SKUs narrow which products a
+// percentage discount applies to; a rule with none of them is storewide.
+type Rule struct {
+	ID          string   `json:"id" bson:"_id"`
+	Name        string   `json:"name" bson:"name"`
+	Kind        string   `json:"kind" bson:"kind"`
+	Percent     float64  `json:"percent,omitempty" bson:"percent,omitempty"`
+	MinOrderUSD float64  `json:"min_order_usd,omitempty" bson:"min_order_usd,omitempty"`
+	Brands      []string `json:"brands,omitempty" bson:"brands,omitempty"`
+	Types       []string `json:"types,omitempty" bson:"types,omitempty"`
+	SKUs        []string `json:"skus,omitempty" bson:"skus,omitempty"`
+	// Code must be presented at checkout, e.g. a student discount code
+	Code string `json:"code,omitempty" bson:"code,omitempty"`
+	// StartsAt and EndsAt bound the validity window; nil leaves that side open
+	StartsAt *time.Time `json:"starts_at,omitempty" bson:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty" bson:"ends_at,omitempty"`
+	Disabled bool       `json:"disabled,omitempty" bson:"disabled,omitempty"`
+}
+
+// Product is the part of a catalogue product promotions are matched on
+type Product struct {
+	SKU      string
+	Brand    string
+	Type     string
+	PriceUSD float64
+}
+
+// ActiveAt reports whether the rule is enabled and inside its validity window
+func (r Rule) ActiveAt(now time.Time) bool {
+	if r.Disabled {
+		return false
+	}
+	if r.StartsAt != nil && now.Before(*r.StartsAt) {
+		return false
+	}
+	if r.EndsAt != nil && !now.Before(*r.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// Storewide reports whether the rule is not limited to brands, types or SKUs
+func (r Rule) Storewide() bool {
+	return len(r.Brands) == 0 && len(r.Types) == 0 && len(r.SKUs) == 0
+}
+
+// AppliesTo reports whether a percentage discount covers the product
+func (r Rule) AppliesTo(p Product) bool {
+	if r.Kind != KindPercentOff {
+		return false
+	}
+	if r.Storewide() {
+		return true
+	}
+	return containsFold(r.Brands, p.Brand) || containsFold(r.Types, p.Type) || containsFold(r.SKUs, p.SKU)
+}
+
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
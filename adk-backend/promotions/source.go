@@ -0,0 +1,61 @@
+package promotions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Source supplies promotion rules
+type Source interface {
+	Rules(ctx context.Context) ([]Rule, error)
+}
+
+// FileSource reads rules from a JSON array on disk
+type FileSource struct {
+	Path string
+}
+
+func NewFileSource(path string) *FileSource {
+	return &FileSource{Path: path}
+}
+
+func (fs *FileSource) Rules(ctx context.Context) ([]Rule, error) {
+	data, err := os.ReadFile(fs.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse promotions file: %w", err)
+	}
+	return rules, nil
+}
+
+// MongoSource reads rules from the promotions collection
+type MongoSource struct {
+	collection *mongo.Collection
+}
+
+func NewMongoSource(collection *mongo.Collection) *MongoSource {
+	return &MongoSource{collection: collection}
+}
+
+// Rules skips disabled rules server-side; validity windows are checked by the engine
+func (ms *MongoSource) Rules(ctx context.Context) ([]Rule, error) {
+	cursor, err := ms.collection.Find(ctx, bson.M{"disabled": bson.M{"$ne": true}})
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"pen-shop/llm"
+	"pen-shop/models"
+	"pen-shop/ratelimit"
+)
+
+// callerLimits identifies who a chat request is charged to
+type callerLimits struct {
+	subject  string
+	tierName string
+	tier     ratelimit.Tier
+}
+
+type limitBucket struct {
+	key  string
+	rate ratelimit.Rate
+}
+
+// enforceRateLimits applies the per-IP, per-API-key and per-user token
+// buckets plus the daily quota. Only authenticated principals are limited as
+// users; anonymous callers are limited by client IP, since any user_id they
+// send is their own say-so. It writes a 429 and returns false when the caller
+// is over any limit.
+func (psma *PenShopMultiAgent) enforceRateLimits(w http.ResponseWriter, r *http.Request, principal *models.Principal) (callerLimits, bool) {
+	apiKey := r.Header.Get("X-API-Key")
+	tierName, tier := psma.limits.TierFor(apiKey)
+	if principal != nil && principal.Tier != "" {
+		tierName, tier = psma.limits.Tier(principal.Tier)
+	}
+	ip := clientIP(r, psma.trustProxy)
+
+	caller := callerLimits{subject: "ip:" + ip, tierName: tierName, tier: tier}
+	if principal != nil && principal.Subject != "" {
+		caller.subject = "user:" + principal.Subject
+	}
+
+	buckets := []limitBucket{
+		{key: "ip:" + ip, rate: psma.limits.IPRate},
+		{key: "tier:" + caller.subject, rate: tier.Rate},
+	}
+	if apiKey != "" {
+		buckets = append(buckets, limitBucket{key: "key:" + apiKey, rate: tier.Rate})
+	}
+
+	for _, b := range buckets {
+		if ok, retryAfter := psma.limiter.Allow(b.key, b.rate); !ok {
+			psma.logger.Info("🚦 Rate limited %s (%s tier)", caller.subject, tierName)
+			writeTooManyRequests(w, retryAfter, "Rate limit exceeded")
+			return caller, false
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	usage, err := psma.quotas.Usage(ctx, caller.subject, ratelimit.Day(time.Now()))
+	if err != nil {
+		// Fail open: a quota store outage shouldn't take the shop down
+		psma.logger.Error("Failed to read quota for %s: %v", caller.subject, err)
+		return caller, true
+	}
+	if tier.Exceeded(usage) {
+		psma.logger.Info("🚦 Daily quota exhausted for %s (%d tokens, $%.4f)", caller.subject, usage.Tokens, usage.CostUSD)
+		writeTooManyRequests(w, ratelimit.UntilReset(time.Now()), "Daily quota exceeded")
+		return caller, false
+	}
+
+	return caller, true
+}
+
+// recordUsage charges the model usage of one request to the caller's daily
+// quota, whether or not the request succeeded
+func (psma *PenShopMultiAgent) recordUsage(caller callerLimits, tracker *llm.UsageTracker) {
+	tokens, cost := tracker.Totals()
+
+	psma.pendingWrites.Add(1)
+	go func() {
+		defer psma.pendingWrites.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		usage := ratelimit.Usage{Requests: 1, Tokens: tokens, CostUSD: cost}
+		if err := psma.quotas.Add(ctx, caller.subject, ratelimit.Day(time.Now()), usage); err != nil {
+			psma.logger.Error("Failed to record usage for %s: %v", caller.subject, err)
+		}
+	}()
+}
+
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", fmt.Sprintf("%d", seconds))
+	http.Error(w, message, http.StatusTooManyRequests)
+}
+
+// clientIP returns the caller's address, honouring X-Forwarded-For only when
+// the service sits behind a trusted proxy
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Rate describes a token bucket: RequestsPerMinute refill rate and Burst capacity
+type Rate struct {
+	RequestsPerMinute float64 `json:"requests_per_minute"`
+	Burst             int     `json:"burst"`
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// Limiter keeps one token bucket per key (user, API key or client IP)
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+	idleTTL time.Duration
+	sweepAt time.Time
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+		idleTTL: 10 * time.Minute,
+	}
+}
+
+// Allow takes one token from the bucket for key. When the bucket is empty it
+// returns false and how long the caller should wait before retrying.
+func (l *Limiter) Allow(key string, rate Rate) (bool, time.Duration) {
+	if rate.RequestsPerMinute <= 0 || rate.Burst <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rate.Burst), lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	perSecond := rate.RequestsPerMinute / 60
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	b.tokens = math.Min(float64(rate.Burst), b.tokens+elapsed*perSecond)
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets that have been idle long enough to be full again
+func (l *Limiter) sweep(now time.Time) {
+	if now.Before(l.sweepAt) {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > l.idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+	l.sweepAt = now.Add(l.idleTTL)
+}
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Usage is the amount of model capacity a user consumed on one day
+type Usage struct {
+	Requests int     `json:"requests" bson:"requests"`
+	Tokens   int     `json:"tokens" bson:"tokens"`
+	CostUSD  float64 `json:"cost_usd" bson:"cost_usd"`
+}
+
+// QuotaStore persists daily usage per user
+type QuotaStore interface {
+	Usage(ctx context.Context, subject string, day string) (Usage, error)
+	Add(ctx context.Context, subject string, day string, usage Usage) error
+}
+
+// Day returns the UTC quota day for t
+func Day(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// UntilReset returns the time left before the daily quota resets at UTC midnight
+func UntilReset(now time.Time) time.Duration {
+	now = now.UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return midnight.Sub(now)
+}
+
+// Exceeded reports whether usage has reached either daily limit of the tier
+func (t Tier) Exceeded(usage Usage) bool {
+	if t.DailyTokens > 0 && usage.Tokens >= t.DailyTokens {
+		return true
+	}
+	if t.DailyCostUSD > 0 && usage.CostUSD >= t.DailyCostUSD {
+		return true
+	}
+	return false
+}
+
+// MongoQuotaStore keeps daily usage documents in the usage_quotas collection
+type MongoQuotaStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoQuotaStore(collection *mongo.Collection) *MongoQuotaStore {
+	return &MongoQuotaStore{collection: collection}
+}
+
+// EnsureIndexes expires usage documents a week after their last update
+func (mqs *MongoQuotaStore) EnsureIndexes(ctx context.Context) error {
+	_, err := mqs.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "updated_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(7 * 24 * 60 * 60),
+	})
+	return err
+}
+
+func (mqs *MongoQuotaStore) Usage(ctx context.Context, subject string, day string) (Usage, error) {
+	var usage Usage
+	err := mqs.collection.FindOne(ctx, bson.M{"_id": subject + ":" + day}).Decode(&usage)
+	if err == mongo.ErrNoDocuments {
+		return Usage{}, nil
+	}
+	return usage, err
+}
+
+func (mqs *MongoQuotaStore) Add(ctx context.Context, subject string, day string, usage Usage) error {
+	_, err := mqs.collection.UpdateOne(ctx,
+		bson.M{"_id": subject + ":" + day},
+		bson.M{
+			"$inc": bson.M{
+				"requests": usage.Requests,
+				"tokens":   usage.Tokens,
+				"cost_usd": usage.CostUSD,
+			},
+			"$set":         bson.M{"updated_at": time.Now()},
+			"$setOnInsert": bson.M{"subject": subject, "day": day},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// MemoryQuotaStore keeps usage in process; used when MongoDB is unavailable
+type MemoryQuotaStore struct {
+	mu    sync.Mutex
+	usage map[string]Usage
+}
+
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{usage: make(map[string]Usage)}
+}
+
+func (mqs *MemoryQuotaStore) Usage(ctx context.Context, subject string, day string) (Usage, error) {
+	mqs.mu.Lock()
+	defer mqs.mu.Unlock()
+	return mqs.usage[subject+":"+day], nil
+}
+
+func (mqs *MemoryQuotaStore) Add(ctx context.Context, subject string, day string, usage Usage) error {
+	mqs.mu.Lock()
+	defer mqs.mu.Unlock()
+
+	// Only today's usage matters, so drop entries from earlier days
+	for key := range mqs.usage {
+		if !strings.HasSuffix(key, ":"+day) {
+			delete(mqs.usage, key)
+		}
+	}
+
+	key := subject + ":" + day
+	current := mqs.usage[key]
+	current.Requests += usage.Requests
+	current.Tokens += usage.Tokens
+	current.CostUSD += usage.CostUSD
+	mqs.usage[key] = current
+	return nil
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Tier holds the request rate and daily quotas for a class of caller
+type Tier struct {
+	Rate
+	DailyTokens  int     `json:"daily_tokens"`
+	DailyCostUSD float64 `json:"daily_cost_usd"`
+}
+
+// Config maps tier names to limits and API keys to tiers
+type Config struct {
+	DefaultTier string            `json:"default_tier"`
+	Tiers       map[string]Tier   `json:"tiers"`
+	APIKeyTiers map[string]string `json:"api_keys"`
+	// IPRate applies to every client IP regardless of tier
+	IPRate Rate `json:"ip_rate"`
+}
+
+// DefaultConfig returns conservative limits for anonymous web traffic
+func DefaultConfig() Config {
+	return Config{
+		DefaultTier: "anonymous",
+		Tiers: map[string]Tier{
+			"anonymous": {Rate: Rate{RequestsPerMinute: 10, Burst: 5}, DailyTokens: 20000, DailyCostUSD: 0.50},
+			"standard":  {Rate: Rate{RequestsPerMinute: 30, Burst: 10}, DailyTokens: 200000, DailyCostUSD: 5},
+			"premium":   {Rate: Rate{RequestsPerMinute: 120, Burst: 30}, DailyTokens: 1000000, DailyCostUSD: 25},
+		},
+		APIKeyTiers: map[string]string{},
+		IPRate:      Rate{RequestsPerMinute: 60, Burst: 20},
+	}
+}
+
+// LoadConfig reads a JSON tier configuration, filling gaps from DefaultConfig
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	var fileCfg Config
+	if err := json.Unmarshal(data, &fileCfg); err != nil {
+		return cfg, fmt.Errorf("parse rate limit config: %w", err)
+	}
+
+	if fileCfg.DefaultTier != "" {
+		cfg.DefaultTier = fileCfg.DefaultTier
+	}
+	for name, tier := range fileCfg.Tiers {
+		cfg.Tiers[name] = tier
+	}
+	for key, tier := range fileCfg.APIKeyTiers {
+		cfg.APIKeyTiers[key] = tier
+	}
+	if fileCfg.IPRate.RequestsPerMinute > 0 {
+		cfg.IPRate = fileCfg.IPRate
+	}
+
+	if _, ok := cfg.Tiers[cfg.DefaultTier]; !ok {
+		return cfg, fmt.Errorf("default tier %q is not defined", cfg.DefaultTier)
+	}
+	return cfg, nil
+}
+
+// TierFor resolves the tier for an API key, falling back to the default tier
+func (c Config) TierFor(apiKey string) (string, Tier) {
+	name := c.DefaultTier
+	if apiKey != "" {
+		if keyTier, ok := c.APIKeyTiers[apiKey]; ok {
+			name = keyTier
+		}
+	}
+	return c.Tier(name)
+}
+
+// Tier looks up a tier by name, falling back to the default tier
+func (c Config) Tier(name string) (string, Tier) {
+	if tier, ok := c.Tiers[name]; ok {
+		return name, tier
+	}
+	return c.DefaultTier, c.Tiers[c.DefaultTier]
+}
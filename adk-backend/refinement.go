@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"pen-shop/agents"
+	"pen-shop/models"
+)
+
+// recommendationDefaults reads RECOMMEND_AGGREGATION (default
+// highest_confidence) and RECOMMEND_CRITIQUE_ITERATIONS (default 3), which
+// recommendation agents use unless their config sets aggregation or
+// critique_iterations
+func recommendationDefaults(logger models.Logger) (string, int) {
+	aggregation := string(agents.HighestConfidence)
+	if value := os.Getenv("RECOMMEND_AGGREGATION"); value != "" {
+		if _, ok := agents.ParseAggregationStrategy(value); ok || strings.EqualFold(value, "off") {
+			aggregation = value
+		} else {
+			logger.Error("❌ Invalid RECOMMEND_AGGREGATION %q, using %s", value, aggregation)
+		}
+	}
+
+	iterations := 3
+	if value := os.Getenv("RECOMMEND_CRITIQUE_ITERATIONS"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			iterations = parsed
+		} else {
+			logger.Error("❌ Invalid RECOMMEND_CRITIQUE_ITERATIONS %q, using %d", value, iterations)
+		}
+	}
+	return aggregation, iterations
+}
+
+// parallelRecommendations runs the rule-based and model recommendations side
+// by side when the recommender has a model, aggregating them by strategy.
+// "off" keeps the single agent that falls back to the rules only when the
+// model fails.
+func parallelRecommendations(recommender *agents.RecommendationAgent, strategy string, hasModel bool, products map[string]agents.ProductInfo, logger models.Logger) (models.Agent, error) {
+	if strings.EqualFold(strategy, "off") {
+		return recommender, nil
+	}
+	parsed, ok := agents.ParseAggregationStrategy(strategy)
+	if !ok {
+		return nil, fmt.Errorf("unknown aggregation strategy %q", strategy)
+	}
+	if !hasModel {
+		return recommender, nil
+	}
+
+	logger.Info("🔀 Recommendations from rules and model, aggregated by %s", parsed)
+	parallel := agents.NewParallelAgent(recommender.GetName(), parsed, logger,
+		recommender.Variant(agents.RecommendRules), recommender.Variant(agents.RecommendModel))
+	parallel.SetVoteKey(agents.TopPickVote(products))
+	return parallel, nil
+}
+
+// refinedRecommendations puts recommendations through a critique loop that
+// checks budget, catalogue grounding and tone, for up to iterations drafts;
+// 1 turns the loop off
+func refinedRecommendations(recommender models.Agent, iterations int, products map[string]agents.ProductInfo, logger models.Logger) (models.Agent, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("critique_iterations must be at least 1")
+	}
+	if iterations == 1 {
+		return recommender, nil
+	}
+
+	logger.Info("🧐 Recommendations critiqued for up to %d drafts", iterations)
+	critic := agents.NewCritiqueAgent(products, logger)
+	return agents.NewLoopAgent(recommender.GetName(), recommender, critic, iterations, logger), nil
+}
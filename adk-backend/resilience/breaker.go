@@ -0,0 +1,132 @@
+package resilience
+
+import (
+	"sync"
+	"time"
+
+	"pen-shop/clock"
+)
+
+// State is a circuit breaker position
+type State string
+
+const (
+	// StateClosed lets every call through
+	StateClosed State = "closed"
+	// StateOpen rejects calls until the open timeout has passed
+	StateOpen State = "open"
+	// StateHalfOpen lets a few probe calls through to test recovery
+	StateHalfOpen State = "half_open"
+)
+
+// BreakerConfig controls when a breaker trips and how it recovers
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the breaker
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before probing
+	OpenTimeout time.Duration
+	// HalfOpenProbes is how many calls may test a half-open breaker at once
+	HalfOpenProbes int
+}
+
+// Breaker is a consecutive-failure circuit breaker
+type Breaker struct {
+	config BreakerConfig
+	clock  clock.Clock
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	probes   int
+	openedAt time.Time
+}
+
+func NewBreaker(config BreakerConfig, clock clock.Clock) *Breaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = 1
+	}
+	return &Breaker{config: config, clock: clock, state: StateClosed}
+}
+
+// Allow reports whether a call may proceed. Every allowed call must be
+// followed by exactly one Success or Failure.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.clock.Now().Sub(b.openedAt) < b.config.OpenTimeout {
+			return false
+		}
+		b.state, b.probes = StateHalfOpen, 0
+		fallthrough
+	case StateHalfOpen:
+		if b.probes >= b.config.HalfOpenProbes {
+			return false
+		}
+		b.probes++
+	}
+	return true
+}
+
+// Success records a healthy call; a successful probe closes the breaker
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	if b.state == StateHalfOpen {
+		b.state, b.probes = StateClosed, 0
+	}
+}
+
+// Failure records a failed call; a failed probe reopens the breaker
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	// Calls that started before the breaker opened must not extend the timeout
+	if b.state == StateOpen {
+		return
+	}
+	if b.state == StateHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.state, b.probes, b.openedAt = StateOpen, 0, b.clock.Now()
+	}
+}
+
+// Abandon releases a probe slot for a call that ended without a verdict,
+// such as one cancelled by its caller
+func (b *Breaker) Abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// State returns the breaker position, reporting an open breaker whose
+// timeout has passed as half-open
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && b.clock.Now().Sub(b.openedAt) >= b.config.OpenTimeout {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+func (b *Breaker) snapshot() (State, int, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state, b.failures, b.openedAt
+}
@@ -0,0 +1,87 @@
+package resilience_test
+
+import (
+	"testing"
+	"time"
+
+	"pen-shop/clock"
+	"pen-shop/resilience"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestBreakerTransitions(t *testing.T) {
+	clock := clock.NewFake(epoch)
+	breaker := resilience.NewBreaker(resilience.BreakerConfig{FailureThreshold: 3, OpenTimeout: 30 * time.Second}, clock)
+
+	for i := 0; i < 2; i++ {
+		if !breaker.Allow() {
+			t.Fatalf("call %d rejected while closed", i+1)
+		}
+		breaker.Failure()
+	}
+	if got := breaker.State(); got != resilience.StateClosed {
+		t.Fatalf("state after 2 failures = %s, want closed", got)
+	}
+
+	// A success resets the count of consecutive failures
+	breaker.Allow()
+	breaker.Success()
+	for i := 0; i < 3; i++ {
+		breaker.Allow()
+		breaker.Failure()
+	}
+	if got := breaker.State(); got != resilience.StateOpen {
+		t.Fatalf("state after 3 failures = %s, want open", got)
+	}
+	if breaker.Allow() {
+		t.Fatal("open breaker allowed a call")
+	}
+
+	clock.Advance(29 * time.Second)
+	if breaker.Allow() {
+		t.Fatal("breaker allowed a call before the open timeout")
+	}
+
+	clock.Advance(time.Second)
+	if got := breaker.State(); got != resilience.StateHalfOpen {
+		t.Fatalf("state after open timeout = %s, want half_open", got)
+	}
+	if !breaker.Allow() {
+		t.Fatal("half-open breaker rejected the probe")
+	}
+	if breaker.Allow() {
+		t.Fatal("half-open breaker allowed a second probe")
+	}
+
+	// A failed probe reopens the breaker for another full timeout
+	breaker.Failure()
+	if got := breaker.State(); got != resilience.StateOpen {
+		t.Fatalf("state after failed probe = %s, want open", got)
+	}
+	clock.Advance(30 * time.Second)
+	if !breaker.Allow() {
+		t.Fatal("breaker rejected the second probe")
+	}
+	breaker.Success()
+	if got := breaker.State(); got != resilience.StateClosed {
+		t.Fatalf("state after successful probe = %s, want closed", got)
+	}
+}
+
+func TestBreakerAbandonFreesProbe(t *testing.T) {
+	clock := clock.NewFake(epoch)
+	breaker := resilience.NewBreaker(resilience.BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second}, clock)
+
+	breaker.Allow()
+	breaker.Failure()
+	clock.Advance(time.Second)
+
+	if !breaker.Allow() {
+		t.Fatal("half-open breaker rejected the probe")
+	}
+	breaker.Abandon()
+	if !breaker.Allow() {
+		t.Fatal("abandoned probe was not released")
+	}
+}
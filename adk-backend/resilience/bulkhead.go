@@ -0,0 +1,61 @@
+package resilience
+
+import (
+	"context"
+	"time"
+
+	"pen-shop/clock"
+)
+
+// Bulkhead caps concurrent calls to one dependency so a slow dependency
+// cannot tie up every request goroutine
+type Bulkhead struct {
+	slots   chan struct{}
+	maxWait time.Duration
+	clock   clock.Clock
+}
+
+// NewBulkhead allows maxConcurrent calls at once; further calls wait up to
+// maxWait for a slot
+func NewBulkhead(maxConcurrent int, maxWait time.Duration, clock clock.Clock) *Bulkhead {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 16
+	}
+	return &Bulkhead{slots: make(chan struct{}, maxConcurrent), maxWait: maxWait, clock: clock}
+}
+
+// Acquire takes a slot, returning ErrBulkheadFull when none frees up in time
+func (bh *Bulkhead) Acquire(ctx context.Context) error {
+	select {
+	case bh.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if bh.maxWait <= 0 {
+		return ErrBulkheadFull
+	}
+
+	select {
+	case bh.slots <- struct{}{}:
+		return nil
+	case <-bh.clock.After(bh.maxWait):
+		return ErrBulkheadFull
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire
+func (bh *Bulkhead) Release() {
+	<-bh.slots
+}
+
+// InFlight is the number of calls holding a slot
+func (bh *Bulkhead) InFlight() int {
+	return len(bh.slots)
+}
+
+// Capacity is the maximum number of concurrent calls
+func (bh *Bulkhead) Capacity() int {
+	return cap(bh.slots)
+}
@@ -0,0 +1,75 @@
+package resilience_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"pen-shop/clock"
+	"pen-shop/resilience"
+)
+
+// waitForTimer blocks until a goroutine is waiting on the fake clock
+func waitForTimer(t *testing.T, clock *clock.Fake) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for clock.Waiters() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("nothing waited on the clock")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBulkheadRejectsAfterMaxWait(t *testing.T) {
+	clock := clock.NewFake(epoch)
+	bulkhead := resilience.NewBulkhead(1, 500*time.Millisecond, clock)
+	ctx := context.Background()
+
+	if err := bulkhead.Acquire(ctx); err != nil {
+		t.Fatalf("first Acquire: %v", err)
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- bulkhead.Acquire(ctx) }()
+	waitForTimer(t, clock)
+	clock.Advance(500 * time.Millisecond)
+
+	if err := <-result; !errors.Is(err, resilience.ErrBulkheadFull) {
+		t.Fatalf("second Acquire = %v, want ErrBulkheadFull", err)
+	}
+	if got := bulkhead.InFlight(); got != 1 {
+		t.Errorf("InFlight = %d, want 1", got)
+	}
+}
+
+func TestBulkheadWaiterGetsReleasedSlot(t *testing.T) {
+	clock := clock.NewFake(epoch)
+	bulkhead := resilience.NewBulkhead(1, time.Second, clock)
+	ctx := context.Background()
+
+	bulkhead.Acquire(ctx)
+	result := make(chan error, 1)
+	go func() { result <- bulkhead.Acquire(ctx) }()
+	waitForTimer(t, clock)
+	bulkhead.Release()
+
+	if err := <-result; err != nil {
+		t.Fatalf("waiting Acquire = %v, want a slot", err)
+	}
+}
+
+func TestBulkheadWithoutWaitRejectsAtOnce(t *testing.T) {
+	bulkhead := resilience.NewBulkhead(2, 0, clock.NewFake(epoch))
+	ctx := context.Background()
+
+	bulkhead.Acquire(ctx)
+	bulkhead.Acquire(ctx)
+	if err := bulkhead.Acquire(ctx); !errors.Is(err, resilience.ErrBulkheadFull) {
+		t.Fatalf("third Acquire = %v, want ErrBulkheadFull", err)
+	}
+	if got := bulkhead.Capacity(); got != 2 {
+		t.Errorf("Capacity = %d, want 2", got)
+	}
+}
@@ -0,0 +1,225 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pen-shop/clock"
+)
+
+var (
+	// ErrCircuitOpen is returned without calling a dependency whose breaker is open
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrBulkheadFull is returned when a dependency already has its maximum
+	// number of calls in flight
+	ErrBulkheadFull = errors.New("too many concurrent calls")
+)
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (pe *permanentError) Error() string { return pe.err.Error() }
+func (pe *permanentError) Unwrap() error { return pe.err }
+
+// Permanent marks err as not worth retrying and not a sign of an unhealthy
+// dependency, such as a 404 or a rejected request. Do returns the original err.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Policy is the protection applied to one dependency
+type Policy struct {
+	// Timeout bounds each attempt; the caller's deadline still applies
+	Timeout time.Duration
+	Retry   RetryConfig
+	Breaker BreakerConfig
+	// MaxConcurrent calls may be in flight; others wait up to MaxWait
+	MaxConcurrent int
+	MaxWait       time.Duration
+}
+
+// Status reports a dependency's breaker state and call counts since startup
+type Status struct {
+	State               State      `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	InFlight            int        `json:"in_flight"`
+	MaxConcurrent       int        `json:"max_concurrent"`
+	Calls               int64      `json:"calls"`
+	Failures            int64      `json:"failures"`
+	Retries             int64      `json:"retries"`
+	Rejected            int64      `json:"rejected"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+// Dependency guards calls to one downstream service with a bulkhead, a
+// circuit breaker, a per-attempt timeout and retries. A nil Dependency runs
+// calls unguarded.
+type Dependency struct {
+	name     string
+	policy   Policy
+	clock    clock.Clock
+	breaker  *Breaker
+	bulkhead *Bulkhead
+
+	calls, failures, retries, rejected atomic.Int64
+
+	mu        sync.Mutex
+	lastError string
+}
+
+func NewDependency(name string, policy Policy, clk clock.Clock) *Dependency {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	return &Dependency{
+		name:     name,
+		policy:   policy,
+		clock:    clk,
+		breaker:  NewBreaker(policy.Breaker, clk),
+		bulkhead: NewBulkhead(policy.MaxConcurrent, policy.MaxWait, clk),
+	}
+}
+
+func (d *Dependency) Name() string {
+	return d.name
+}
+
+// Do runs fn, retrying failures with backoff while attempts and the caller's
+// deadline allow. Use it for reads and other idempotent calls.
+func (d *Dependency) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if d == nil {
+		return unwrapPermanent(fn(ctx))
+	}
+	return d.run(ctx, d.policy.Retry.Attempts, fn)
+}
+
+// DoOnce runs fn behind the bulkhead and breaker without retrying, for calls
+// that must not be repeated such as placing an order
+func (d *Dependency) DoOnce(ctx context.Context, fn func(ctx context.Context) error) error {
+	if d == nil {
+		return unwrapPermanent(fn(ctx))
+	}
+	return d.run(ctx, 1, fn)
+}
+
+func (d *Dependency) run(ctx context.Context, attempts int, fn func(ctx context.Context) error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := d.attempt(ctx, fn)
+		var permanent *permanentError
+		if err == nil || errors.As(err, &permanent) {
+			return unwrapPermanent(err)
+		}
+		// Rejections are load shedding; retrying would only add load
+		if attempt >= attempts || ctx.Err() != nil || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrBulkheadFull) {
+			return err
+		}
+
+		wait := d.policy.Retry.Backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && d.clock.Now().Add(wait).After(deadline) {
+			return err
+		}
+		d.retries.Add(1)
+		select {
+		case <-d.clock.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (d *Dependency) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := d.bulkhead.Acquire(ctx); err != nil {
+		if errors.Is(err, ErrBulkheadFull) {
+			d.rejected.Add(1)
+			return fmt.Errorf("%s: %w", d.name, err)
+		}
+		return err
+	}
+	defer d.bulkhead.Release()
+
+	if !d.breaker.Allow() {
+		d.rejected.Add(1)
+		return fmt.Errorf("%s: %w", d.name, ErrCircuitOpen)
+	}
+	d.calls.Add(1)
+
+	callCtx, cancel := ctx, context.CancelFunc(func() {})
+	if d.policy.Timeout > 0 {
+		callCtx, cancel = context.WithTimeout(ctx, d.policy.Timeout)
+	}
+	err := fn(callCtx)
+	cancel()
+
+	var permanent *permanentError
+	switch {
+	case err == nil, errors.As(err, &permanent):
+		d.breaker.Success()
+	case ctx.Err() != nil:
+		// The caller gave up, which says nothing about the dependency
+		d.breaker.Abandon()
+	default:
+		d.breaker.Failure()
+		d.failures.Add(1)
+		d.mu.Lock()
+		d.lastError = err.Error()
+		d.mu.Unlock()
+	}
+	return err
+}
+
+// Status snapshots the breaker and counters
+func (d *Dependency) Status() Status {
+	_, failures, openedAt := d.breaker.snapshot()
+	status := Status{
+		State:               d.breaker.State(),
+		ConsecutiveFailures: failures,
+		InFlight:            d.bulkhead.InFlight(),
+		MaxConcurrent:       d.bulkhead.Capacity(),
+		Calls:               d.calls.Load(),
+		Failures:            d.failures.Load(),
+		Retries:             d.retries.Load(),
+		Rejected:            d.rejected.Load(),
+	}
+	if status.State != StateClosed {
+		status.OpenedAt = &openedAt
+	}
+	d.mu.Lock()
+	status.LastError = d.lastError
+	d.mu.Unlock()
+	return status
+}
+
+// Call runs fn through dep.Do and returns its result
+func Call[T any](ctx context.Context, dep *Dependency, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := dep.Do(ctx, func(ctx context.Context) error {
+		value, err := fn(ctx)
+		if err == nil {
+			result = value
+		}
+		return err
+	})
+	return result, err
+}
+
+func unwrapPermanent(err error) error {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return permanent.err
+	}
+	return err
+}
@@ -0,0 +1,203 @@
+package resilience_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"pen-shop/clock"
+	"pen-shop/resilience"
+)
+
+func testPolicy() resilience.Policy {
+	return resilience.Policy{
+		Retry:         resilience.RetryConfig{Attempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second},
+		Breaker:       resilience.BreakerConfig{FailureThreshold: 3, OpenTimeout: 30 * time.Second},
+		MaxConcurrent: 4,
+	}
+}
+
+// runWithClock runs fn and keeps advancing the fake clock past every backoff
+// until it returns
+func runWithClock(clock *clock.Fake, fn func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	for {
+		select {
+		case err := <-done:
+			return err
+		default:
+		}
+		if clock.Waiters() > 0 {
+			clock.Advance(time.Second)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDependencyRetriesUntilSuccess(t *testing.T) {
+	clock := clock.NewFake(epoch)
+	dep := resilience.NewDependency("catalogue", testPolicy(), clock)
+	faults := &resilience.Faults{FailFirst: 2}
+
+	err := runWithClock(clock, func() error {
+		return dep.Do(context.Background(), faults.Call)
+	})
+	if err != nil {
+		t.Fatalf("Do = %v, want success on the third attempt", err)
+	}
+	if got := faults.Calls(); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+	status := dep.Status()
+	if status.Retries != 2 || status.Failures != 2 || status.State != resilience.StateClosed {
+		t.Errorf("status = %+v, want 2 retries, 2 failures and a closed breaker", status)
+	}
+}
+
+func TestDependencyGivesUpAfterAttempts(t *testing.T) {
+	clock := clock.NewFake(epoch)
+	dep := resilience.NewDependency("catalogue", testPolicy(), clock)
+	faults := &resilience.Faults{}
+	faults.SetDown(true)
+
+	err := runWithClock(clock, func() error {
+		return dep.Do(context.Background(), faults.Call)
+	})
+	if !errors.Is(err, resilience.ErrInjected) {
+		t.Fatalf("Do = %v, want the injected fault", err)
+	}
+	if got := faults.Calls(); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+}
+
+func TestDependencySkipsRetryPastDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	// Any backoff from here would end after the caller's deadline
+	clock := clock.NewFake(deadline)
+	dep := resilience.NewDependency("catalogue", testPolicy(), clock)
+	faults := &resilience.Faults{FailFirst: 1}
+
+	if err := dep.Do(ctx, faults.Call); !errors.Is(err, resilience.ErrInjected) {
+		t.Fatalf("Do = %v, want the first failure", err)
+	}
+	if got := faults.Calls(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+	if got := dep.Status().Retries; got != 0 {
+		t.Errorf("retries = %d, want 0", got)
+	}
+}
+
+func TestDependencyBreakerOpensAndRecovers(t *testing.T) {
+	clock := clock.NewFake(epoch)
+	policy := testPolicy()
+	policy.Retry.Attempts = 1
+	dep := resilience.NewDependency("mongodb", policy, clock)
+	faults := &resilience.Faults{}
+	faults.SetDown(true)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := dep.Do(ctx, faults.Call); !errors.Is(err, resilience.ErrInjected) {
+			t.Fatalf("call %d = %v, want the injected fault", i+1, err)
+		}
+	}
+	if err := dep.Do(ctx, faults.Call); !errors.Is(err, resilience.ErrCircuitOpen) {
+		t.Fatalf("call with open breaker = %v, want ErrCircuitOpen", err)
+	}
+	if got := faults.Calls(); got != 3 {
+		t.Errorf("calls = %d, want the open breaker to shed the 4th", got)
+	}
+	if status := dep.Status(); status.State != resilience.StateOpen || status.Rejected != 1 || status.OpenedAt == nil {
+		t.Errorf("status = %+v, want open with 1 rejection", status)
+	}
+
+	faults.SetDown(false)
+	clock.Advance(30 * time.Second)
+	if err := dep.Do(ctx, faults.Call); err != nil {
+		t.Fatalf("probe = %v, want success", err)
+	}
+	if got := dep.Status().State; got != resilience.StateClosed {
+		t.Errorf("state after successful probe = %s, want closed", got)
+	}
+}
+
+func TestDependencyPermanentErrors(t *testing.T) {
+	clock := clock.NewFake(epoch)
+	dep := resilience.NewDependency("catalogue", testPolicy(), clock)
+	notFound := errors.New("unknown sku")
+	calls := 0
+
+	for i := 0; i < 5; i++ {
+		err := dep.Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			return resilience.Permanent(notFound)
+		})
+		if err != notFound {
+			t.Fatalf("Do = %v, want the unwrapped error", err)
+		}
+	}
+	if calls != 5 {
+		t.Errorf("calls = %d, want no retries", calls)
+	}
+	if status := dep.Status(); status.State != resilience.StateClosed || status.Failures != 0 {
+		t.Errorf("status = %+v, want a closed breaker with no failures", status)
+	}
+}
+
+func TestDependencyBulkheadSheds(t *testing.T) {
+	clock := clock.NewFake(epoch)
+	policy := testPolicy()
+	policy.MaxConcurrent = 1
+	dep := resilience.NewDependency("model_runner", policy, clock)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	go dep.Do(context.Background(), func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	faults := &resilience.Faults{}
+	if err := dep.Do(context.Background(), faults.Call); !errors.Is(err, resilience.ErrBulkheadFull) {
+		t.Fatalf("Do = %v, want ErrBulkheadFull", err)
+	}
+	close(release)
+
+	status := dep.Status()
+	if faults.Calls() != 0 || status.Rejected != 1 || status.Retries != 0 {
+		t.Errorf("calls = %d, status = %+v, want one rejection and no retries", faults.Calls(), status)
+	}
+}
+
+func TestDependencyAttemptTimeout(t *testing.T) {
+	policy := testPolicy()
+	policy.Retry.Attempts = 1
+	policy.Timeout = 10 * time.Millisecond
+	dep := resilience.NewDependency("openai", policy, clock.NewFake(epoch))
+	faults := &resilience.Faults{Latency: time.Second}
+
+	if err := dep.Do(context.Background(), faults.Call); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Do = %v, want the attempt to time out", err)
+	}
+	if got := dep.Status().Failures; got != 1 {
+		t.Errorf("failures = %d, want the timeout to count against the breaker", got)
+	}
+}
+
+func TestNilDependencyRunsUnguarded(t *testing.T) {
+	var dep *resilience.Dependency
+	value, err := resilience.Call(context.Background(), dep, func(ctx context.Context) (int, error) {
+		return 42, nil
+	})
+	if err != nil || value != 42 {
+		t.Fatalf("Call = %d, %v, want 42", value, err)
+	}
+}
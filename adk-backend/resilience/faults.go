@@ -0,0 +1,61 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrInjected is the failure returned by a Faults stub
+var ErrInjected = errors.New("injected fault")
+
+// Faults is a stub dependency for exercising guards in tests: it fails the
+// first FailFirst calls, fails every call while Down is set, and can delay
+// each call by Latency (honouring the caller's context)
+type Faults struct {
+	FailFirst int
+	Latency   time.Duration
+	Err       error
+
+	mu    sync.Mutex
+	down  bool
+	calls int
+}
+
+// SetDown makes every following call fail until it is cleared
+func (f *Faults) SetDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+// Calls reports how many calls reached the stub
+func (f *Faults) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// Call behaves like one call to the faulty dependency
+func (f *Faults) Call(ctx context.Context) error {
+	f.mu.Lock()
+	f.calls++
+	fail := f.down || f.calls <= f.FailFirst
+	f.mu.Unlock()
+
+	if f.Latency > 0 {
+		select {
+		case <-time.After(f.Latency):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if !fail {
+		return nil
+	}
+	if f.Err != nil {
+		return f.Err
+	}
+	return ErrInjected
+}
@@ -0,0 +1,190 @@
+package resilience
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"pen-shop/clock"
+)
+
+// Dependency names used across the backend
+const (
+	Catalogue   = "catalogue"
+	MongoDB     = "mongodb"
+	OpenAI      = "openai"
+	ModelRunner = "model_runner"
+	Embeddings  = "embeddings"
+)
+
+// DefaultPolicies keeps every attempt well inside the 10-15 second workflow
+// step timeouts, so a slow dependency fails fast instead of stalling a step
+func DefaultPolicies() map[string]Policy {
+	return map[string]Policy{
+		Catalogue: {
+			Timeout:       3 * time.Second,
+			Retry:         RetryConfig{Attempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second},
+			Breaker:       BreakerConfig{FailureThreshold: 5, OpenTimeout: 30 * time.Second},
+			MaxConcurrent: 32,
+			MaxWait:       500 * time.Millisecond,
+		},
+		MongoDB: {
+			Timeout:       2 * time.Second,
+			Retry:         RetryConfig{Attempts: 2, BaseDelay: 50 * time.Millisecond, MaxDelay: 500 * time.Millisecond},
+			Breaker:       BreakerConfig{FailureThreshold: 5, OpenTimeout: 15 * time.Second},
+			MaxConcurrent: 64,
+			MaxWait:       250 * time.Millisecond,
+		},
+		OpenAI: {
+			Timeout:       8 * time.Second,
+			Retry:         RetryConfig{Attempts: 2, BaseDelay: 250 * time.Millisecond, MaxDelay: 2 * time.Second},
+			Breaker:       BreakerConfig{FailureThreshold: 3, OpenTimeout: 60 * time.Second},
+			MaxConcurrent: 16,
+			MaxWait:       time.Second,
+		},
+		ModelRunner: {
+			Timeout:       8 * time.Second,
+			Retry:         RetryConfig{Attempts: 2, BaseDelay: 250 * time.Millisecond, MaxDelay: 2 * time.Second},
+			Breaker:       BreakerConfig{FailureThreshold: 3, OpenTimeout: 30 * time.Second},
+			MaxConcurrent: 4,
+			MaxWait:       2 * time.Second,
+		},
+		Embeddings: {
+			Timeout:       10 * time.Second,
+			Retry:         RetryConfig{Attempts: 2, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second},
+			Breaker:       BreakerConfig{FailureThreshold: 5, OpenTimeout: 30 * time.Second},
+			MaxConcurrent: 16,
+			MaxWait:       500 * time.Millisecond,
+		},
+	}
+}
+
+// policyFile is the JSON form of a Policy, with durations such as "3s"
+type policyFile struct {
+	Timeout          string `json:"timeout"`
+	Attempts         int    `json:"attempts"`
+	BaseDelay        string `json:"base_delay"`
+	MaxDelay         string `json:"max_delay"`
+	FailureThreshold int    `json:"failure_threshold"`
+	OpenTimeout      string `json:"open_timeout"`
+	HalfOpenProbes   int    `json:"half_open_probes"`
+	MaxConcurrent    int    `json:"max_concurrent"`
+	MaxWait          string `json:"max_wait"`
+}
+
+// LoadPolicies reads per-dependency overrides from a JSON file keyed by
+// dependency name, filling unset fields from DefaultPolicies
+func LoadPolicies(path string) (map[string]Policy, error) {
+	policies := DefaultPolicies()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policies, err
+	}
+
+	var overrides map[string]policyFile
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return policies, fmt.Errorf("parse resilience config: %w", err)
+	}
+
+	for name, override := range overrides {
+		policy := policies[name]
+		durations := []struct {
+			value  string
+			target *time.Duration
+		}{
+			{override.Timeout, &policy.Timeout},
+			{override.BaseDelay, &policy.Retry.BaseDelay},
+			{override.MaxDelay, &policy.Retry.MaxDelay},
+			{override.OpenTimeout, &policy.Breaker.OpenTimeout},
+			{override.MaxWait, &policy.MaxWait},
+		}
+		for _, d := range durations {
+			if d.value == "" {
+				continue
+			}
+			parsed, err := time.ParseDuration(d.value)
+			if err != nil {
+				return DefaultPolicies(), fmt.Errorf("%s: invalid duration %q", name, d.value)
+			}
+			*d.target = parsed
+		}
+		if override.Attempts > 0 {
+			policy.Retry.Attempts = override.Attempts
+		}
+		if override.FailureThreshold > 0 {
+			policy.Breaker.FailureThreshold = override.FailureThreshold
+		}
+		if override.HalfOpenProbes > 0 {
+			policy.Breaker.HalfOpenProbes = override.HalfOpenProbes
+		}
+		if override.MaxConcurrent > 0 {
+			policy.MaxConcurrent = override.MaxConcurrent
+		}
+		policies[name] = policy
+	}
+	return policies, nil
+}
+
+// Registry hands out one Dependency per downstream service
+type Registry struct {
+	policies map[string]Policy
+	clock    clock.Clock
+
+	mu           sync.Mutex
+	dependencies map[string]*Dependency
+}
+
+func NewRegistry(policies map[string]Policy, clk clock.Clock) *Registry {
+	if policies == nil {
+		policies = DefaultPolicies()
+	}
+	if clk == nil {
+		clk = clock.System{}
+	}
+	return &Registry{policies: policies, clock: clk, dependencies: make(map[string]*Dependency)}
+}
+
+// Dependency returns the guard for name, creating it from its policy on first
+// use. A nil Registry returns nil, which runs calls unguarded.
+func (r *Registry) Dependency(name string) *Dependency {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if dep, ok := r.dependencies[name]; ok {
+		return dep
+	}
+	dep := NewDependency(name, r.policies[name], r.clock)
+	r.dependencies[name] = dep
+	return dep
+}
+
+// Statuses reports every dependency that has been used
+func (r *Registry) Statuses() map[string]Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make(map[string]Status, len(r.dependencies))
+	for name, dep := range r.dependencies {
+		statuses[name] = dep.Status()
+	}
+	return statuses
+}
+
+// Degraded lists dependencies whose breaker is not closed
+func (r *Registry) Degraded() []string {
+	var degraded []string
+	for name, status := range r.Statuses() {
+		if status.State != StateClosed {
+			degraded = append(degraded, name)
+		}
+	}
+	sort.Strings(degraded)
+	return degraded
+}
@@ -0,0 +1,31 @@
+package resilience
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RetryConfig controls how often and how patiently a call is retried
+type RetryConfig struct {
+	// Attempts is the total number of tries, including the first
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Backoff returns the wait before retry number attempt (1 for the first
+// retry): exponential growth capped at MaxDelay, with full jitter so callers
+// that failed together do not retry together
+func (rc RetryConfig) Backoff(attempt int) time.Duration {
+	ceiling := rc.BaseDelay
+	for i := 1; i < attempt && (rc.MaxDelay <= 0 || ceiling < rc.MaxDelay); i++ {
+		ceiling *= 2
+	}
+	if rc.MaxDelay > 0 && ceiling > rc.MaxDelay {
+		ceiling = rc.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
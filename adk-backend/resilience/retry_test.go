@@ -0,0 +1,40 @@
+package resilience_test
+
+import (
+	"testing"
+	"time"
+
+	"pen-shop/resilience"
+)
+
+func TestBackoffFullJitter(t *testing.T) {
+	config := resilience.RetryConfig{Attempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	ceilings := map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	}
+
+	for attempt, ceiling := range ceilings {
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 200; i++ {
+			wait := config.Backoff(attempt)
+			if wait < 0 || wait > ceiling {
+				t.Fatalf("Backoff(%d) = %s, want within [0, %s]", attempt, wait, ceiling)
+			}
+			seen[wait] = true
+		}
+		if len(seen) < 10 {
+			t.Errorf("Backoff(%d) gave only %d distinct waits, want jitter", attempt, len(seen))
+		}
+	}
+}
+
+func TestBackoffWithoutBaseDelay(t *testing.T) {
+	if wait := (resilience.RetryConfig{Attempts: 3}).Backoff(2); wait != 0 {
+		t.Errorf("Backoff = %s, want 0", wait)
+	}
+}
@@ -0,0 +1,177 @@
+package respcache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"pen-shop/models"
+)
+
+// Entry is a cached workflow answer
+type Entry struct {
+	Key              string          `json:"key"`
+	Intent           string          `json:"intent"`
+	Query            string          `json:"query"`
+	CatalogueVersion string          `json:"catalogue_version"`
+	Response         models.Response `json:"response"`
+	CreatedAt        time.Time       `json:"created_at"`
+	ExpiresAt        time.Time       `json:"expires_at"`
+}
+
+// Store holds cached answers. Implementations drop expired entries on read.
+type Store interface {
+	Get(ctx context.Context, key string) (Entry, bool, error)
+	Set(ctx context.Context, entry Entry) error
+	// Purge removes every entry and reports how many were dropped
+	Purge(ctx context.Context) (int64, error)
+}
+
+// LRUStore keeps the most recently used entries in process
+type LRUStore struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func NewLRUStore(capacity int) *LRUStore {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &LRUStore{capacity: capacity, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (ls *LRUStore) Get(ctx context.Context, key string) (Entry, bool, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	element, ok := ls.entries[key]
+	if !ok {
+		return Entry{}, false, nil
+	}
+	entry := element.Value.(Entry)
+	if time.Now().After(entry.ExpiresAt) {
+		ls.order.Remove(element)
+		delete(ls.entries, key)
+		return Entry{}, false, nil
+	}
+	ls.order.MoveToFront(element)
+	return entry, true, nil
+}
+
+func (ls *LRUStore) Set(ctx context.Context, entry Entry) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if element, ok := ls.entries[entry.Key]; ok {
+		element.Value = entry
+		ls.order.MoveToFront(element)
+		return nil
+	}
+	ls.entries[entry.Key] = ls.order.PushFront(entry)
+	for ls.order.Len() > ls.capacity {
+		oldest := ls.order.Back()
+		ls.order.Remove(oldest)
+		delete(ls.entries, oldest.Value.(Entry).Key)
+	}
+	return nil
+}
+
+func (ls *LRUStore) Purge(ctx context.Context) (int64, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	dropped := int64(len(ls.entries))
+	ls.order.Init()
+	ls.entries = make(map[string]*list.Element)
+	return dropped, nil
+}
+
+// MongoStore keeps entries in the response_cache collection, shared by every
+// backend replica. Responses are stored as JSON so their metadata round-trips
+// unchanged.
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoStore(collection *mongo.Collection) *MongoStore {
+	return &MongoStore{collection: collection}
+}
+
+type mongoEntry struct {
+	Key              string    `bson:"_id"`
+	Intent           string    `bson:"intent"`
+	Query            string    `bson:"query"`
+	CatalogueVersion string    `bson:"catalogue_version"`
+	Response         string    `bson:"response"`
+	CreatedAt        time.Time `bson:"created_at"`
+	ExpiresAt        time.Time `bson:"expires_at"`
+}
+
+// EnsureIndexes lets MongoDB expire entries past their TTL
+func (ms *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := ms.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+func (ms *MongoStore) Get(ctx context.Context, key string) (Entry, bool, error) {
+	var doc mongoEntry
+	err := ms.collection.FindOne(ctx, bson.M{"_id": key, "expires_at": bson.M{"$gt": time.Now()}}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	entry := Entry{
+		Key:              doc.Key,
+		Intent:           doc.Intent,
+		Query:            doc.Query,
+		CatalogueVersion: doc.CatalogueVersion,
+		CreatedAt:        doc.CreatedAt,
+		ExpiresAt:        doc.ExpiresAt,
+	}
+	if err := json.Unmarshal([]byte(doc.Response), &entry.Response); err != nil {
+		return Entry{}, false, err
+	}
+	return entry, true, nil
+}
+
+func (ms *MongoStore) Set(ctx context.Context, entry Entry) error {
+	response, err := json.Marshal(entry.Response)
+	if err != nil {
+		return err
+	}
+	doc := mongoEntry{
+		Key:              entry.Key,
+		Intent:           entry.Intent,
+		Query:            entry.Query,
+		CatalogueVersion: entry.CatalogueVersion,
+		Response:         string(response),
+		CreatedAt:        entry.CreatedAt,
+		ExpiresAt:        entry.ExpiresAt,
+	}
+	_, err = ms.collection.ReplaceOne(ctx, bson.M{"_id": entry.Key}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (ms *MongoStore) Purge(ctx context.Context) (int64, error) {
+	result, err := ms.collection.DeleteMany(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
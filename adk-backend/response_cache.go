@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"pen-shop/agents"
+	"pen-shop/inventory"
+	"pen-shop/models"
+	"pen-shop/resilience"
+	"pen-shop/respcache"
+)
+
+// newResponseCache wraps the workflow agent in a response cache. RESPONSE_CACHE
+// selects "memory" (LRU of RESPONSE_CACHE_SIZE entries), "mongo" or "off";
+// MongoDB is used by default when connected so replicas share answers.
+// Entries live for RESPONSE_CACHE_TTL.
+func newResponseCache(agent models.Agent, mongoClient *mongo.Client, dependencies *resilience.Registry, poller *inventory.Poller, logger models.Logger) *agents.CachingAgent {
+	backend := os.Getenv("RESPONSE_CACHE")
+	if backend == "" {
+		backend = "memory"
+		if mongoClient != nil {
+			backend = "mongo"
+		}
+	}
+
+	ttl := 15 * time.Minute
+	if value := os.Getenv("RESPONSE_CACHE_TTL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			ttl = parsed
+		} else {
+			logger.Error("❌ Invalid RESPONSE_CACHE_TTL %q, using %s", value, ttl)
+		}
+	}
+
+	var store respcache.Store
+	switch backend {
+	case "off":
+		return nil
+	case "mongo":
+		if mongoClient == nil {
+			logger.Error("❌ RESPONSE_CACHE=mongo but MongoDB is not connected, using memory")
+			backend, store = "memory", newLRUResponseStore()
+			break
+		}
+		mongoStore := respcache.NewMongoStore(mongoClient.Database("penstore").Collection("response_cache"))
+		go func() {
+			indexCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := mongoStore.EnsureIndexes(indexCtx); err != nil {
+				logger.Error("❌ response_cache indexes: %v", err)
+			}
+		}()
+		store = guardedResponseStore{store: mongoStore, dep: dependencies.Dependency(resilience.MongoDB)}
+	case "memory":
+		store = newLRUResponseStore()
+	default:
+		logger.Error("❌ Unknown RESPONSE_CACHE %q, response cache disabled", backend)
+		return nil
+	}
+
+	logger.Info("⚡ Response cache enabled (%s, TTL %s)", backend, ttl)
+	return agents.NewCachingAgent(agent, store, ttl, poller.Version, logger)
+}
+
+func newLRUResponseStore() respcache.Store {
+	size := 1000
+	if n, err := strconv.Atoi(os.Getenv("RESPONSE_CACHE_SIZE")); err == nil && n > 0 {
+		size = n
+	}
+	return respcache.NewLRUStore(size)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"pen-shop/agents"
+	"pen-shop/llm"
+	"pen-shop/models"
+	"pen-shop/resilience"
+	"pen-shop/vectors"
+)
+
+// newEmbedder picks the embeddings backend from EMBEDDINGS_PROVIDER:
+// "openai", "local" (Docker Model Runner), "hash" (deterministic, offline)
+// or "none". Without a setting, OpenAI is used when a key is configured,
+// then the local runner, otherwise semantic search is disabled.
+func newEmbedder(openaiKey, baseURL string, dependencies *resilience.Registry, logger models.Logger) llm.Embedder {
+	provider := os.Getenv("EMBEDDINGS_PROVIDER")
+	localURL := os.Getenv("MODEL_RUNNER_URL")
+	if provider == "" {
+		switch {
+		case openaiKey != "":
+			provider = llm.ProviderOpenAI
+		case localURL != "":
+			provider = llm.ProviderLocal
+		default:
+			provider = "none"
+		}
+	}
+
+	model := os.Getenv("EMBEDDINGS_MODEL")
+	switch provider {
+	case llm.ProviderOpenAI:
+		if model == "" {
+			model = "text-embedding-3-small"
+		}
+		return llm.NewGuardedEmbedder(llm.NewOpenAIEmbedder(baseURL, openaiKey, model), dependencies.Dependency(resilience.Embeddings))
+	case llm.ProviderLocal:
+		if localURL == "" {
+			logger.Error("❌ EMBEDDINGS_PROVIDER=local but MODEL_RUNNER_URL is not set, semantic search disabled")
+			return nil
+		}
+		if model == "" {
+			model = "ai/mxbai-embed-large"
+		}
+		return llm.NewGuardedEmbedder(llm.NewLocalEmbedder(localURL, model), dependencies.Dependency(resilience.Embeddings))
+	case "hash":
+		dimensions := 256
+		if n, err := strconv.Atoi(os.Getenv("EMBEDDINGS_DIMENSIONS")); err == nil && n > 0 {
+			dimensions = n
+		}
+		return llm.NewHashEmbedder(dimensions)
+	case "none":
+		return nil
+	default:
+		logger.Error("❌ Unknown EMBEDDINGS_PROVIDER %q, semantic search disabled", provider)
+		return nil
+	}
+}
+
+// newVectorStore persists vectors in MongoDB when connected, or in the
+// JSON file at VECTOR_INDEX_PATH otherwise
+func newVectorStore(mongoClient *mongo.Client, logger models.Logger) vectors.Store {
+	if path := os.Getenv("VECTOR_INDEX_PATH"); path != "" || mongoClient == nil {
+		if path == "" {
+			path = "/tmp/pen-shop/vectors.json"
+		}
+		return vectors.NewFileStore(path)
+	}
+
+	store := vectors.NewMongoStore(mongoClient.Database("penstore").Collection("product_embeddings"))
+	go func() {
+		indexCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := store.EnsureIndexes(indexCtx); err != nil {
+			logger.Error("❌ product_embeddings indexes: %v", err)
+		}
+	}()
+	return store
+}
+
+// loadReviews reads review text from the penstore reviews collection
+func loadReviews(ctx context.Context, collection *mongo.Collection) ([]agents.ReviewText, error) {
+	cursor, err := collection.Find(ctx, bson.M{"review": bson.M{"$type": "string"}})
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []struct {
+		ID     primitive.ObjectID `bson:"_id"`
+		PenID  string             `bson:"pen_id"`
+		Review string             `bson:"review"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	reviews := make([]agents.ReviewText, 0, len(docs))
+	for _, doc := range docs {
+		reviews = append(reviews, agents.ReviewText{ID: doc.ID.Hex(), ProductID: doc.PenID, Text: doc.Review})
+	}
+	return reviews, nil
+}
+
+// buildSemanticIndex embeds the catalogue and reviews in the background so
+// startup is not blocked on the embeddings endpoint. Agents fall back to
+// keyword ranking until the first build completes.
+func buildSemanticIndex(semantic *agents.SemanticIndex, products map[string]agents.ProductInfo, mongoClient *mongo.Client, logger models.Logger) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		var reviews []agents.ReviewText
+		if mongoClient != nil {
+			loaded, err := loadReviews(ctx, mongoClient.Database("penstore").Collection("reviews"))
+			if err != nil {
+				logger.Error("❌ Loading reviews for semantic search: %v", err)
+			}
+			reviews = loaded
+		}
+
+		if err := semantic.Build(ctx, products, reviews); err != nil {
+			logger.Error("❌ Building vector index: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,193 @@
+package vectors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Item is one embedded document
+type Item struct {
+	ID       string            `json:"id"`
+	Kind     string            `json:"kind"`
+	Text     string            `json:"text"`
+	TextHash string            `json:"text_hash"`
+	Vector   []float32         `json:"vector"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// Match is a search hit with its cosine similarity
+type Match struct {
+	Item  Item
+	Score float64
+}
+
+// Index is an in-memory cosine-similarity index. Vectors are tied to the
+// embedder that produced them so a model change forces re-embedding.
+type Index struct {
+	mu       sync.RWMutex
+	embedder string
+	items    map[string]Item
+}
+
+func NewIndex(embedder string) *Index {
+	return &Index{embedder: embedder, items: make(map[string]Item)}
+}
+
+// Embedder returns the name of the embedder the index holds vectors for
+func (idx *Index) Embedder() string {
+	return idx.embedder
+}
+
+// HashText fingerprints document text so unchanged documents are not re-embedded
+func HashText(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Has reports whether id is indexed with exactly this text
+func (idx *Index) Has(id, text string) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	item, ok := idx.items[id]
+	return ok && item.TextHash == HashText(text)
+}
+
+// Upsert adds or replaces an item
+func (idx *Index) Upsert(item Item) {
+	if item.TextHash == "" {
+		item.TextHash = HashText(item.Text)
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.items[item.ID] = item
+}
+
+// Retain drops every item whose ID is not in keep and returns how many were dropped
+func (idx *Index) Retain(keep map[string]bool) int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	var dropped int
+	for id := range idx.items {
+		if !keep[id] {
+			delete(idx.items, id)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// Items returns a copy of every indexed item
+func (idx *Index) Items() []Item {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	items := make([]Item, 0, len(idx.items))
+	for _, item := range idx.items {
+		items = append(items, item)
+	}
+	return items
+}
+
+// Len returns the number of indexed items
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.items)
+}
+
+// Search returns the k items most similar to vector, optionally limited to
+// items accepted by filter. Ties are broken by ID for stable results.
+func (idx *Index) Search(vector []float32, k int, filter func(Item) bool) []Match {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var matches []Match
+	for _, item := range idx.items {
+		if filter != nil && !filter(item) {
+			continue
+		}
+		matches = append(matches, Match{Item: item, Score: Cosine(vector, item.Vector)})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Item.ID < matches[j].Item.ID
+	})
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// Cosine returns the cosine similarity of two vectors, or 0 if they differ in length
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+type snapshot struct {
+	Embedder string `json:"embedder"`
+	Items    []Item `json:"items"`
+}
+
+// Save writes the index to path atomically
+func (idx *Index) Save(path string) error {
+	snap := snapshot{Embedder: idx.embedder, Items: idx.Items()}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load reads items saved by Save. Items embedded by a different embedder are
+// discarded so they are rebuilt with the current model.
+func (idx *Index) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("parse vector index: %w", err)
+	}
+	if snap.Embedder != idx.embedder {
+		return nil
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, item := range snap.Items {
+		idx.items[item.ID] = item
+	}
+	return nil
+}
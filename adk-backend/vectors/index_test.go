@@ -0,0 +1,170 @@
+package vectors_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pen-shop/llm"
+	"pen-shop/vectors"
+)
+
+var documents = map[string]string{
+	"safari":  "Lamy Safari fountain pen with a steel nib, sturdy plastic body for students",
+	"capless": "Pilot Capless retractable fountain pen with a gold nib and click mechanism",
+	"jotter":  "Parker Jotter ballpoint pen in stainless steel, a classic office pen",
+	"kaweco":  "Kaweco Sport pocket fountain pen, compact octagonal cap for travel",
+}
+
+// embeddedIndex indexes documents with the hash embedder
+func embeddedIndex(t *testing.T, embedder *llm.HashEmbedder) *vectors.Index {
+	t.Helper()
+	idx := vectors.NewIndex(embedder.Name())
+	for id, text := range documents {
+		embedded, _ := embedder.Embed(context.Background(), []string{text})
+		kind := "fountain"
+		if id == "jotter" {
+			kind = "ballpoint"
+		}
+		idx.Upsert(vectors.Item{ID: id, Kind: kind, Text: text, Vector: embedded[0]})
+	}
+	return idx
+}
+
+func search(t *testing.T, embedder *llm.HashEmbedder, idx *vectors.Index, query string, k int, filter func(vectors.Item) bool) []string {
+	t.Helper()
+	embedded, _ := embedder.Embed(context.Background(), []string{query})
+	var ids []string
+	for _, match := range idx.Search(embedded[0], k, filter) {
+		ids = append(ids, match.Item.ID)
+	}
+	return ids
+}
+
+func TestSearchRanksBySimilarity(t *testing.T) {
+	embedder := llm.NewHashEmbedder(256)
+	idx := embeddedIndex(t, embedder)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"retractable pen with a click mechanism", "capless"},
+		{"stainless steel ballpoint for the office", "jotter"},
+		{"compact pocket pen for travel", "kaweco"},
+		{"sturdy fountain pen for students", "safari"},
+	}
+	for _, tt := range tests {
+		if got := search(t, embedder, idx, tt.query, 1, nil); len(got) != 1 || got[0] != tt.want {
+			t.Errorf("top match for %q = %v, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	if got := search(t, embedder, idx, "fountain pen", 0, nil); len(got) != len(documents) {
+		t.Errorf("k=0 returned %d matches, want all %d", len(got), len(documents))
+	}
+}
+
+func TestSearchFilterAndScores(t *testing.T) {
+	embedder := llm.NewHashEmbedder(256)
+	idx := embeddedIndex(t, embedder)
+
+	fountainOnly := func(item vectors.Item) bool { return item.Kind == "fountain" }
+	for _, id := range search(t, embedder, idx, "stainless steel ballpoint for the office", 0, fountainOnly) {
+		if id == "jotter" {
+			t.Fatal("filter let a ballpoint through")
+		}
+	}
+
+	embedded, _ := embedder.Embed(context.Background(), []string{documents["safari"]})
+	matches := idx.Search(embedded[0], 0, nil)
+	if matches[0].Item.ID != "safari" || matches[0].Score < 0.999 {
+		t.Errorf("searching a document's own text: top = %s (%.3f), want safari at 1", matches[0].Item.ID, matches[0].Score)
+	}
+	for i := 1; i < len(matches); i++ {
+		if matches[i].Score > matches[i-1].Score {
+			t.Errorf("matches out of order at %d: %.3f after %.3f", i, matches[i].Score, matches[i-1].Score)
+		}
+	}
+}
+
+func TestSearchBreaksTiesByID(t *testing.T) {
+	idx := vectors.NewIndex("test")
+	for _, id := range []string{"c", "a", "b"} {
+		idx.Upsert(vectors.Item{ID: id, Vector: []float32{1, 0}})
+	}
+	matches := idx.Search([]float32{1, 0}, 2, nil)
+	if len(matches) != 2 || matches[0].Item.ID != "a" || matches[1].Item.ID != "b" {
+		t.Errorf("tied matches = %+v, want a then b", matches)
+	}
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	embedder := llm.NewHashEmbedder(64)
+	idx := embeddedIndex(t, embedder)
+	store := vectors.NewFileStore(filepath.Join(t.TempDir(), "index", "vectors.json"))
+	ctx := context.Background()
+
+	if err := store.Save(ctx, idx); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(store.Path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	loaded := vectors.NewIndex(embedder.Name())
+	if err := store.Load(ctx, loaded); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.Len() != idx.Len() {
+		t.Fatalf("loaded %d items, want %d", loaded.Len(), idx.Len())
+	}
+	for id, text := range documents {
+		if !loaded.Has(id, text) {
+			t.Errorf("loaded index is missing %s", id)
+		}
+	}
+	if loaded.Has("safari", "edited description") {
+		t.Error("Has matched changed text")
+	}
+
+	// Loaded vectors rank the same as the originals
+	if got := search(t, embedder, loaded, "retractable pen with a click mechanism", 1, nil); len(got) != 1 || got[0] != "capless" {
+		t.Errorf("top match after reload = %v, want capless", got)
+	}
+}
+
+func TestLoadDiscardsOtherEmbedders(t *testing.T) {
+	store := vectors.NewFileStore(filepath.Join(t.TempDir(), "vectors.json"))
+	ctx := context.Background()
+	if err := store.Save(ctx, embeddedIndex(t, llm.NewHashEmbedder(64))); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	other := vectors.NewIndex(llm.NewHashEmbedder(128).Name())
+	if err := store.Load(ctx, other); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if other.Len() != 0 {
+		t.Errorf("loaded %d vectors from another embedder, want none", other.Len())
+	}
+}
+
+func TestFileStoreLoadWithoutSnapshot(t *testing.T) {
+	store := vectors.NewFileStore(filepath.Join(t.TempDir(), "missing.json"))
+	idx := vectors.NewIndex("test")
+	if err := store.Load(context.Background(), idx); err != nil || idx.Len() != 0 {
+		t.Errorf("Load = %v with %d items, want an empty index", err, idx.Len())
+	}
+}
+
+func TestRetain(t *testing.T) {
+	idx := embeddedIndex(t, llm.NewHashEmbedder(64))
+	if dropped := idx.Retain(map[string]bool{"safari": true, "jotter": true}); dropped != 2 {
+		t.Errorf("dropped %d, want 2", dropped)
+	}
+	if idx.Len() != 2 || !idx.Has("safari", documents["safari"]) {
+		t.Errorf("kept %d items, want safari and jotter", idx.Len())
+	}
+}
@@ -0,0 +1,121 @@
+package vectors
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Store persists an index between restarts
+type Store interface {
+	Load(ctx context.Context, idx *Index) error
+	Save(ctx context.Context, idx *Index) error
+}
+
+// FileStore keeps the index as a JSON snapshot on local disk
+type FileStore struct {
+	Path string
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+// Load is a no-op when no snapshot has been written yet
+func (fs *FileStore) Load(ctx context.Context, idx *Index) error {
+	err := idx.Load(fs.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (fs *FileStore) Save(ctx context.Context, idx *Index) error {
+	return idx.Save(fs.Path)
+}
+
+// MongoStore keeps one document per item in the product_embeddings collection
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoStore(collection *mongo.Collection) *MongoStore {
+	return &MongoStore{collection: collection}
+}
+
+type mongoItem struct {
+	ID       string            `bson:"_id"`
+	Embedder string            `bson:"embedder"`
+	Kind     string            `bson:"kind"`
+	Text     string            `bson:"text"`
+	TextHash string            `bson:"text_hash"`
+	Vector   []float32         `bson:"vector"`
+	Labels   map[string]string `bson:"labels,omitempty"`
+}
+
+// EnsureIndexes creates the embedder lookup index
+func (ms *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := ms.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "embedder", Value: 1}},
+	})
+	return err
+}
+
+// Load reads the items stored for the index's embedder
+func (ms *MongoStore) Load(ctx context.Context, idx *Index) error {
+	cursor, err := ms.collection.Find(ctx, bson.M{"embedder": idx.Embedder()})
+	if err != nil {
+		return err
+	}
+
+	var docs []mongoItem
+	if err := cursor.All(ctx, &docs); err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		idx.Upsert(Item{
+			ID:       doc.ID,
+			Kind:     doc.Kind,
+			Text:     doc.Text,
+			TextHash: doc.TextHash,
+			Vector:   doc.Vector,
+			Labels:   doc.Labels,
+		})
+	}
+	return nil
+}
+
+// Save upserts every item and removes documents the index no longer holds,
+// including those written by a previous embedder
+func (ms *MongoStore) Save(ctx context.Context, idx *Index) error {
+	items := idx.Items()
+	ids := make([]string, 0, len(items))
+	writes := make([]mongo.WriteModel, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": item.ID}).
+			SetReplacement(mongoItem{
+				ID:       item.ID,
+				Embedder: idx.Embedder(),
+				Kind:     item.Kind,
+				Text:     item.Text,
+				TextHash: item.TextHash,
+				Vector:   item.Vector,
+				Labels:   item.Labels,
+			}).
+			SetUpsert(true))
+	}
+
+	if len(writes) > 0 {
+		if _, err := ms.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+	_, err := ms.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$nin": ids}})
+	return err
+}
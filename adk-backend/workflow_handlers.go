@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"pen-shop/agents"
+	"pen-shop/auth"
+	"pen-shop/checkpoint"
+	"pen-shop/llm"
+	"pen-shop/models"
+	"pen-shop/resilience"
+)
+
+// newCheckpointStore picks where workflow executions are checkpointed from
+// WORKFLOW_CHECKPOINTS: "mongo" (the default when connected), "memory" or
+// "off". Executions are kept for WORKFLOW_RETENTION.
+func newCheckpointStore(mongoClient *mongo.Client, dependencies *resilience.Registry, logger models.Logger) checkpoint.Store {
+	backend := os.Getenv("WORKFLOW_CHECKPOINTS")
+	if backend == "" {
+		backend = "memory"
+		if mongoClient != nil {
+			backend = "mongo"
+		}
+	}
+
+	retention := 24 * time.Hour
+	if value := os.Getenv("WORKFLOW_RETENTION"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			retention = parsed
+		} else {
+			logger.Error("❌ Invalid WORKFLOW_RETENTION %q, using %s", value, retention)
+		}
+	}
+
+	var store checkpoint.Store
+	switch backend {
+	case "off":
+		return nil
+	case "mongo":
+		if mongoClient == nil {
+			logger.Error("❌ WORKFLOW_CHECKPOINTS=mongo but MongoDB is not connected, using memory")
+			backend, store = "memory", checkpoint.NewMemoryStore(retention)
+			break
+		}
+		mongoStore := checkpoint.NewMongoStore(mongoClient.Database("penstore").Collection("workflow_executions"), retention)
+		go func() {
+			indexCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := mongoStore.EnsureIndexes(indexCtx); err != nil {
+				logger.Error("❌ workflow_executions indexes: %v", err)
+			}
+		}()
+		store = guardedCheckpoints{store: mongoStore, dep: dependencies.Dependency(resilience.MongoDB)}
+	case "memory":
+		store = checkpoint.NewMemoryStore(retention)
+	default:
+		logger.Error("❌ Unknown WORKFLOW_CHECKPOINTS %q, checkpoints disabled", backend)
+		return nil
+	}
+
+	logger.Info("💾 Workflow checkpoints enabled (%s, kept %s)", backend, retention)
+	return store
+}
+
+// workflowExecution loads the {id} execution and checks the caller may see
+// it, writing an error response and returning false otherwise. Executions
+// started anonymously are open to whoever holds the ID; admins see all.
+func (psma *PenShopMultiAgent) workflowExecution(w http.ResponseWriter, r *http.Request) (checkpoint.Execution, bool) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	exec, err := psma.workflows.Execution(ctx, mux.Vars(r)["id"])
+	switch {
+	case errors.Is(err, agents.ErrCheckpointsDisabled):
+		http.Error(w, "Workflow checkpoints are disabled", http.StatusServiceUnavailable)
+		return checkpoint.Execution{}, false
+	case errors.Is(err, checkpoint.ErrNotFound):
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return checkpoint.Execution{}, false
+	case err != nil:
+		psma.logger.Error("Failed to load workflow: %v", err)
+		http.Error(w, "Failed to load workflow", http.StatusInternalServerError)
+		return checkpoint.Execution{}, false
+	}
+
+	// Report other users' executions as missing rather than forbidden
+	if !visibleTo(r, exec.Owner) {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return checkpoint.Execution{}, false
+	}
+	return exec, true
+}
+
+// visibleTo reports whether the caller may see something owned by owner:
+// their own, anything anonymous, or anything at all with the admin scope
+func visibleTo(r *http.Request, owner string) bool {
+	principal := auth.PrincipalFrom(r.Context())
+	var subject string
+	if principal != nil {
+		subject = principal.Subject
+	}
+	return owner == "" || owner == subject || principal.HasScope("admin")
+}
+
+// handleGetWorkflow serves GET /api/workflows/{id}
+func (psma *PenShopMultiAgent) handleGetWorkflow(w http.ResponseWriter, r *http.Request) {
+	exec, ok := psma.workflowExecution(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exec)
+}
+
+// handleResumeWorkflow serves POST /api/workflows/{id}/resume, continuing a
+// failed or interrupted execution from its last completed step
+func (psma *PenShopMultiAgent) handleResumeWorkflow(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	exec, ok := psma.workflowExecution(w, r)
+	if !ok {
+		return
+	}
+
+	principal := auth.PrincipalFrom(r.Context())
+	caller, allowed := psma.enforceRateLimits(w, r, principal)
+	if !allowed {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), workflowTimeout)
+	defer cancel()
+
+	usageTracker := &llm.UsageTracker{}
+	ctx = llm.WithUsageTracker(ctx, usageTracker)
+
+	query := exec.Query
+	query.Principal = principal
+	record := newConversationRecord(query, query.SessionID, clientIP(r, psma.trustProxy))
+
+	response, err := psma.workflows.Resume(ctx, exec.ID, principal)
+	if errors.Is(err, agents.ErrExecutionActive) {
+		http.Error(w, "Workflow is still running", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		psma.logger.Error("Failed to resume workflow %s: %v", exec.ID, err)
+		psma.recordUsage(caller, usageTracker)
+		psma.failConversation(record, err, usageTracker)
+		http.Error(w, "Failed to resume workflow", http.StatusInternalServerError)
+		return
+	}
+
+	psma.recordUsage(caller, usageTracker)
+	tokensUsed, costUSD := usageTracker.Totals()
+
+	completeConversationRecord(&record, response, usageTracker)
+	psma.logConversation(record)
+
+	processingTime := time.Since(startTime)
+	chatResponse := ChatResponse{
+		Response:       response.Content,
+		SessionID:      query.SessionID,
+		AgentsUsed:     record.AgentsUsed,
+		ProcessingTime: processingTime,
+		Metadata: map[string]interface{}{
+			"agent_mode":      "sequential",
+			"query_id":        query.ID,
+			"conversation_id": record.ID,
+			"processing_time": processingTime.Milliseconds(),
+			"agents_executed": response.Metadata["steps_executed"],
+			"rate_limit_tier": caller.tierName,
+			"tokens_used":     tokensUsed,
+			"cost_usd":        costUSD,
+			"currency":        query.Currency,
+		},
+	}
+	if steps, ok := response.Metadata["steps"].([]agents.StepResult); ok {
+		chatResponse.Metadata["steps"] = stepSummaries(steps)
+	}
+	for _, key := range []string{"execution_id", "resumed", "comparison", "currency", "degraded", "degraded_steps", "intent", "route"} {
+		if value, ok := response.Metadata[key]; ok {
+			chatResponse.Metadata[key] = value
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chatResponse)
+}
@@ -0,0 +1,34 @@
+// Initialize promotion rules used by the price research agent
+
+db = db.getSiblingDB('penstore');
+
+// Limited-time promotions run for 90 days from when the database is seeded
+const seededAt = new Date();
+const limitedUntil = new Date(seededAt.getTime() + 90 * 24 * 60 * 60 * 1000);
+
+db.promotions.insertMany([
+  {
+    _id: "parker-10",
+    name: "10% off the Parker collection",
+    kind: "percent_off",
+    percent: 10,
+    brands: ["Parker"],
+    starts_at: seededAt,
+    ends_at: limitedUntil
+  },
+  {
+    _id: "free-shipping-75",
+    name: "Free shipping",
+    kind: "free_shipping",
+    min_order_usd: 75
+  },
+  {
+    _id: "student-10",
+    name: "Student discount: 10% off",
+    kind: "percent_off",
+    percent: 10,
+    code: "STUDENT10"
+  }
+]);
+
+print("Pen store promotions initialized successfully!");